@@ -4,20 +4,37 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
-	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/cli/go-gh/v2/pkg/auth"
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/spf13/cobra"
 )
 
-var gistIDFlag string
+var (
+	gistIDFlag     string
+	addAsFlag      string
+	addDirFlag     string
+	addIncludeFlag []string
+	addExcludeFlag []string
+	addHostFlag    string
+	addAccountFlag string
+)
 
 var addCmd = &cobra.Command{
 	Use:   "add [path]",
-	Short: "Add a file to be monitored",
-	Args:  cobra.ExactArgs(1),
+	Short: "Add a file, or with --dir a whole directory, to be monitored",
+	Args: func(cmd *cobra.Command, args []string) error {
+		if addDirFlag != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if addDirFlag != "" {
+			return addDir(addDirFlag)
+		}
 		path := args[0]
 		absPath, err := filepath.Abs(path)
 		if err != nil {
@@ -37,16 +54,31 @@ var addCmd = &cobra.Command{
 			return err
 		}
 
-		gistClient := gist.NewClient()
+		remoteName := filepath.Base(absPath)
+		if addAsFlag != "" {
+			if strings.ContainsAny(addAsFlag, `/\`) {
+				return fmt.Errorf("invalid --as %q: Gist filenames cannot contain path separators", addAsFlag)
+			}
+			remoteName = addAsFlag
+		}
+		if gistIDFlag != "" {
+			if other, ok := sm.FindRemote(gistIDFlag, remoteName); ok && other != absPath {
+				return fmt.Errorf("%s already syncs to %q in Gist %s; choose another name with --as", other, remoteName, gistIDFlag)
+			}
+		}
+
+		host := auth.NormalizeHostname(addHostFlag)
+		gistClient := newGistClients().For(host, addAccountFlag)
 		var finalGistID string
+		var remoteUpdatedAt int64
+		content, err := os.ReadFile(absPath)
+		if err != nil {
+			return fmt.Errorf("failed to read file: %w", err)
+		}
 
 		if gistIDFlag != "" {
 			fmt.Printf("Linking %s to Gist %s...\n", path, gistIDFlag)
-			content, err := os.ReadFile(absPath)
-			if err != nil {
-				return fmt.Errorf("failed to read file: %w", err)
-			}
-			err = gistClient.UpdateFile(gistIDFlag, absPath, content)
+			remoteUpdatedAt, err = gistClient.UpdateFile(gistIDFlag, remoteName, content)
 			if err != nil {
 				fmt.Println("Failed to link file to Gist. Please check the ID and permissions.")
 				return err
@@ -55,21 +87,43 @@ var addCmd = &cobra.Command{
 		} else {
 			fmt.Printf("Creating Gist for %s...\n", path)
 			desc := fmt.Sprintf("Automagist: %s", filepath.Base(absPath))
-			id, err := gistClient.CreateGist(absPath, desc, false)
+			id, createdAt, err := gistClient.CreateGistFiles(map[string][]byte{remoteName: content}, desc, false)
 			if err != nil {
 				fmt.Println("Failed to create Gist.")
 				return err
 			}
-			finalGistID = id
+			finalGistID, remoteUpdatedAt = id, createdAt
 		}
 
-		sm.AddTrackedFile(absPath, finalGistID, time.Now().Unix())
-		if err := sm.Save(); err != nil {
+		if err := rememberBase(sm, content); err != nil {
+			fmt.Printf("Warning: failed to store base copy: %v\n", err)
+		}
+
+		err = sm.Update(func(files map[string]state.FileState) error {
+			fs := state.NewFileState(finalGistID, time.Now().Unix())
+			if addAsFlag != "" {
+				fs.RemoteFilename = remoteName
+			}
+			fs.Host, fs.Account = host, addAccountFlag
+			// Baseline for the monitor's --on-conflict check and for status:
+			// the Gist now holds exactly this content.
+			fs.RemoteUpdatedAt = remoteUpdatedAt
+			fs.ContentSHA = sha256Hex(content)
+			files[absPath] = fs
+			return nil
+		})
+		if err != nil {
 			return fmt.Errorf("failed to save state: %w", err)
 		}
 
-		fmt.Printf("Added %s to monitor (Gist ID: %s)\n", absPath, finalGistID)
-		fmt.Println("Note: If 'gh-automagist monitor' is running, run 'gh automagist restart' to pick up the new file.")
+		if remoteName != filepath.Base(absPath) {
+			fmt.Printf("Added %s to monitor (Gist ID: %s, as %s)\n", absPath, finalGistID, remoteName)
+		} else {
+			fmt.Printf("Added %s to monitor (Gist ID: %s)\n", absPath, finalGistID)
+		}
+		if isMonitorRunning() {
+			fmt.Println("The running monitor will pick up the new file automatically.")
+		}
 
 		return nil
 	},
@@ -77,5 +131,11 @@ var addCmd = &cobra.Command{
 
 func init() {
 	addCmd.Flags().StringVar(&gistIDFlag, "gist-id", "", "Existing Gist ID to link to")
+	addCmd.Flags().StringVar(&addAsFlag, "as", "", "Filename to use inside the Gist (default: the local basename)")
+	addCmd.Flags().StringVar(&addDirFlag, "dir", "", "Track every matching file in this directory (not recursive) in one Gist")
+	addCmd.Flags().StringArrayVar(&addIncludeFlag, "include", nil, "With --dir: only track files whose name matches this glob (repeatable)")
+	addCmd.Flags().StringArrayVar(&addExcludeFlag, "exclude", nil, "With --dir: skip files whose name matches this glob (repeatable)")
+	addCmd.Flags().StringVar(&addHostFlag, "hostname", "", "GitHub host the Gist lives on, e.g. a GitHub Enterprise Server (default: gh's default host)")
+	addCmd.Flags().StringVar(&addAccountFlag, "account", "", "Account to use when several are logged in to the host with 'gh auth login' (default: the active one)")
 	rootCmd.AddCommand(addCmd)
 }
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+)
+
+// addDir implements `add --dir`: record a directory rule and upload every
+// file it matches today in one request. Files created later are picked up by
+// the monitor.
+func addDir(path string) error {
+	dir, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve absolute path: %w", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("directory not found: %s", path)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory; drop --dir to add a single file", path)
+	}
+	if addAsFlag != "" {
+		return fmt.Errorf("--as cannot be combined with --dir; files keep their own names")
+	}
+	if err := state.ValidatePatterns(addIncludeFlag); err != nil {
+		return fmt.Errorf("--include: %w", err)
+	}
+	if err := state.ValidatePatterns(addExcludeFlag); err != nil {
+		return fmt.Errorf("--exclude: %w", err)
+	}
+
+	sm, err := state.NewManager()
+	if err != nil {
+		return err
+	}
+	if err := sm.Load(); err != nil {
+		return err
+	}
+	if _, exists := sm.Dirs[dir]; exists {
+		return fmt.Errorf("%s is already tracked as a directory; remove it first to change its rule", dir)
+	}
+
+	rule := state.DirRule{
+		Dir:     dir,
+		GistID:  gistIDFlag,
+		Include: addIncludeFlag,
+		Exclude: addExcludeFlag,
+		AddedAt: time.Now().Unix(),
+		Host:    auth.NormalizeHostname(addHostFlag),
+		Account: addAccountFlag,
+	}
+	paths, files, err := collectDirFiles(sm, rule)
+	if err != nil {
+		return err
+	}
+
+	gistClient := newGistClients().For(rule.Host, rule.Account)
+	var remoteUpdatedAt int64
+	if rule.GistID != "" {
+		fmt.Printf("Linking %s to Gist %s (%d file(s))...\n", path, rule.GistID, len(paths))
+		if len(files) > 0 {
+			remoteUpdatedAt, err = gistClient.UpdateFiles(rule.GistID, files)
+			if err != nil {
+				fmt.Println("Failed to link directory to Gist. Please check the ID and permissions.")
+				return err
+			}
+		}
+	} else {
+		if len(files) == 0 {
+			return fmt.Errorf("no files in %s match; a new Gist needs at least one file (or pass --gist-id)", path)
+		}
+		fmt.Printf("Creating Gist for %s (%d file(s))...\n", path, len(paths))
+		desc := fmt.Sprintf("Automagist: %s/", filepath.Base(dir))
+		rule.GistID, remoteUpdatedAt, err = gistClient.CreateGistFiles(files, desc, false)
+		if err != nil {
+			fmt.Println("Failed to create Gist.")
+			return err
+		}
+	}
+
+	for _, p := range paths {
+		if err := rememberBase(sm, files[filepath.Base(p)]); err != nil {
+			fmt.Printf("Warning: failed to store base copy of %s: %v\n", p, err)
+		}
+	}
+	now := time.Now().Unix()
+	err = sm.Update(func(tracked map[string]state.FileState) error {
+		sm.AddDirRule(rule)
+		for _, p := range paths {
+			fs := state.NewFileState(rule.GistID, now)
+			fs.Dir = dir
+			fs.Host, fs.Account = rule.Host, rule.Account
+			fs.RemoteUpdatedAt = remoteUpdatedAt
+			fs.ContentSHA = sha256Hex(files[filepath.Base(p)])
+			tracked[p] = fs
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+
+	fmt.Printf("Added %s to monitor (Gist ID: %s)\n", dir, rule.GistID)
+	for _, p := range paths {
+		fmt.Printf("  %s\n", filepath.Base(p))
+	}
+	if isMonitorRunning() {
+		fmt.Println("The running monitor will pick up the directory, and new matching files in it, automatically.")
+	}
+	return nil
+}
+
+// collectDirFiles reads every regular file in rule.Dir the rule matches,
+// keyed by Gist filename. Files already tracked individually are left to
+// their existing entry.
+func collectDirFiles(sm *state.Manager, rule state.DirRule) ([]string, map[string][]byte, error) {
+	entries, err := os.ReadDir(rule.Dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read directory: %w", err)
+	}
+	var paths []string
+	files := make(map[string][]byte)
+	for _, e := range entries {
+		p := filepath.Join(rule.Dir, e.Name())
+		if !e.Type().IsRegular() || !rule.Matches(p) {
+			continue
+		}
+		if _, tracked := sm.Files[p]; tracked {
+			fmt.Printf("Skipping %s: already tracked on its own\n", e.Name())
+			continue
+		}
+		if rule.GistID != "" {
+			if other, taken := sm.FindRemote(rule.GistID, e.Name()); taken {
+				return nil, nil, fmt.Errorf("%s already syncs to %q in Gist %s; exclude it with --exclude", other, e.Name(), rule.GistID)
+			}
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		paths = append(paths, p)
+		files[e.Name()] = content
+	}
+	sort.Strings(paths)
+	return paths, files, nil
+}
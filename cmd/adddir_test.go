@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectDirFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.md":                     "a",
+		"b.md":                     "b",
+		"own.md":                   "tracked separately",
+		"c.txt":                    "not included",
+		"a.md.bak.20260101-000000": "backup",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub.md"), 0755))
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	sm.AddTrackedFile(filepath.Join(dir, "own.md"), "g_other", 1)
+
+	rule := state.DirRule{Dir: dir, Include: []string{"*.md"}}
+	paths, files, err := collectDirFiles(sm, rule)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")}, paths)
+	assert.Equal(t, map[string][]byte{"a.md": []byte("a"), "b.md": []byte("b")}, files)
+}
+
+func TestCollectDirFiles_RejectsNameTakenInGist(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("x"), 0644))
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	sm.AddTrackedFile("/elsewhere/config.yaml", "g1", 1)
+
+	_, _, err = collectDirFiles(sm, state.DirRule{Dir: dir, GistID: "g1"})
+	assert.ErrorContains(t, err, "already syncs")
+}
+
+func TestRemove_ExcludesFileFromDirRule(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	dir := t.TempDir()
+	target := filepath.Join(dir, "a.md")
+	require.NoError(t, os.WriteFile(target, []byte("a"), 0644))
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	sm.AddDirRule(state.DirRule{Dir: dir, GistID: "g1", Include: []string{"*.md"}})
+	fs := state.NewFileState("g1", 1)
+	fs.Dir = dir
+	sm.Files[target] = fs
+	require.NoError(t, sm.Save())
+
+	require.NoError(t, removeCmd.RunE(removeCmd, []string{target}))
+
+	require.NoError(t, sm.Load())
+	assert.NotContains(t, sm.Files, target)
+	_, matches := sm.RuleFor(target)
+	assert.False(t, matches, "the monitor must not adopt the file again")
+	_, matches = sm.RuleFor(filepath.Join(dir, "b.md"))
+	assert.True(t, matches, "the rest of the directory still matches")
+}
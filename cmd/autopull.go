@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/monitor"
+	"github.com/noriyo_tcp/gh-automagist/pkg/outbox"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+)
+
+var pullInterval time.Duration
+
+// puller applies newer Gist content to local files from inside the monitor
+// (--pull-interval). It only ever takes the remote when the local copy has
+// nothing unsynced; anything else is left for an interactive `pull`. sm is
+// the poller's own Manager, not the one the watcher and pusher share.
+type puller struct {
+	sm       *state.Manager
+	clients  *gist.Clients
+	watcher  *monitor.Watcher
+	outbox   *outbox.Outbox
+	debounce time.Duration
+}
+
+// run polls every interval until stop is closed.
+func (p *puller) run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.poll()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// poll checks every tracked Gist's commit timestamp (one API call per Gist)
+// and pulls the files whose remote moved ahead.
+func (p *puller) poll() {
+	if err := p.sm.Load(); err != nil {
+		log.Printf("Warning: failed to reload state.json before pull check: %v", err)
+		return
+	}
+	statuses, err := detect(context.Background(), p.sm, p.clients)
+	if err != nil {
+		return
+	}
+	for _, s := range statuses {
+		if s.Err != nil {
+			log.Printf("[AutoPull] Warning: could not check %s: %v", filepath.Base(s.Path), s.Err)
+			continue
+		}
+		if s.RemoteNewer {
+			p.pullOne(s.Path)
+		}
+	}
+}
+
+func (p *puller) pullOne(absPath string) {
+	fs, ok := p.sm.Files[absPath]
+	if !ok || fs.Status == state.StatusMissing || !fs.Pulls() {
+		return
+	}
+	name := filepath.Base(absPath)
+
+	// The same "unsynced local edit" test pullFile uses, plus the daemon's
+	// own view: an armed debounce timer or a queued retry means the last
+	// local save has not reached the Gist yet, even though the event loop
+	// already bumped UpdatedAt.
+	localInfo, err := os.Stat(absPath)
+	if err != nil {
+		log.Printf("[AutoPull] Error stat'ing %s: %v", absPath, err)
+		return
+	}
+	_, queued := p.outbox.Entries()[absPath]
+	if localInfo.ModTime().Unix() > fs.UpdatedAt || p.watcher.HasPendingSync(absPath) || queued {
+		log.Printf("[AutoPull] Warning: %s has newer remote content but unsynced local edits; run 'gh automagist pull' to resolve", name)
+		return
+	}
+
+	remoteContent, remoteUpdatedAt, err := clientFor(p.clients, fs).FetchFile(fs.GistID, fs.RemoteName(absPath))
+	if err != nil {
+		log.Printf("[AutoPull] Error fetching %s: %v", name, err)
+		return
+	}
+	localContent, err := os.ReadFile(absPath)
+	if err != nil {
+		log.Printf("[AutoPull] Error reading %s: %v", absPath, err)
+		return
+	}
+	if sha256Hex(remoteContent) == sha256Hex(localContent) {
+		if err := rememberBase(p.sm, remoteContent); err != nil {
+			log.Printf("[AutoPull] Warning: failed to store base copy: %v", err)
+		}
+		_, err := p.sm.UpdateFile(absPath, func(fs *state.FileState) {
+			fs.RemoteUpdatedAt = remoteUpdatedAt
+			fs.ContentSHA = sha256Hex(remoteContent)
+			fs.ClearConflict()
+		})
+		if err != nil {
+			log.Printf("[AutoPull] Warning: failed to save state: %v", err)
+		}
+		return
+	}
+	// A conflict holds back the local edit on purpose (--on-conflict skip or
+	// merge), with or without markers in the file; taking the remote here
+	// would throw that edit away.
+	if fs.ConflictAt != 0 {
+		log.Printf("[AutoPull] Warning: %s has newer remote content but an unresolved conflict; run 'gh automagist pull' to resolve", name)
+		return
+	}
+
+	backupID, _, err := applyRemote(p.sm, absPath, localContent, remoteContent,
+		remoteUpdatedAt, localInfo.Mode().Perm(), "autopull", p.debounce)
+	if err != nil {
+		log.Printf("[AutoPull] Error applying remote %s: %v", name, err)
+		return
+	}
+	log.Printf("[AutoPull] Pulled %s from Gist %s (backup: %s)", name, fs.GistID, backupID)
+}
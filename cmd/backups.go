@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/backup"
+	"github.com/noriyo_tcp/gh-automagist/pkg/pager"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupsListOutput jsonOutput
+	backupsShowRaw    bool
+	backupsNoPager    bool
+	backupsKeep       int
+	backupsMaxAge     time.Duration
+	backupsMaxSizeMB  int64
+)
+
+func backupStore(sm *state.Manager) *backup.Store {
+	return backup.New(sm.BackupsDir())
+}
+
+// takeBackup stores content as a backup of absPath taken for reason, then
+// applies backup.DefaultPolicy so the store does not grow without bound.
+func takeBackup(sm *state.Manager, absPath string, content []byte, reason string) (backup.Entry, error) {
+	store := backupStore(sm)
+	fs, _ := sm.File(absPath)
+	e, err := store.Save(absPath, fs.GistID, reason, content, time.Now())
+	if err != nil {
+		return e, err
+	}
+	if _, err := store.Prune(backup.DefaultPolicy, time.Now()); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return e, nil
+}
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "List, show and prune the copies taken before pull, merge and restore overwrite a file",
+}
+
+var backupsListCmd = &cobra.Command{
+	Use:   "list [path]",
+	Short: "List backups, newest first, optionally of one file",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		entries, err := backupStore(sm).List()
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			absPath, err := filepath.Abs(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to resolve path: %w", err)
+			}
+			entries = backupsOf(entries, absPath)
+		}
+
+		if backupsListOutput.enabled() {
+			out := make([]backupJSON, 0, len(entries))
+			for _, e := range entries {
+				out = append(out, newBackupJSON(e))
+			}
+			return backupsListOutput.write(cmd.OutOrStdout(), out)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No backups.")
+			return nil
+		}
+		printBackups(os.Stdout, entries)
+		return nil
+	},
+}
+
+var backupsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a backup's details and content",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		store := backupStore(sm)
+		e, err := store.Get(args[0])
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		content, err := store.Content(e)
+		if err != nil {
+			return err
+		}
+		if backupsShowRaw {
+			_, err := os.Stdout.Write(content)
+			return err
+		}
+		return pager.Run(backupsNoPager, func(w io.Writer) error {
+			fmt.Fprintf(w, "Backup:  %s\n", e.ID)
+			fmt.Fprintf(w, "File:    %s\n", displayPath(e.Path))
+			if e.GistID != "" {
+				fmt.Fprintf(w, "Gist:    %s\n", e.GistID)
+			}
+			fmt.Fprintf(w, "Reason:  %s\n", e.Reason)
+			fmt.Fprintf(w, "Taken:   %s\n", time.Unix(e.CreatedAt, 0).Format(time.RFC3339))
+			fmt.Fprintf(w, "Size:    %d bytes\n\n", e.Size)
+			_, err := w.Write(content)
+			return err
+		})
+	},
+}
+
+var backupsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete backups beyond a retention policy",
+	Long: `Delete backups beyond the given limits; a limit of 0 is none. Without flags
+this applies the policy used after every backup: the newest 10 per file, none
+older than 90 days, 100 MB in total (oldest go first).`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		removed, err := backupStore(sm).Prune(backup.Policy{
+			KeepPerFile: backupsKeep,
+			MaxAge:      backupsMaxAge,
+			MaxSize:     backupsMaxSizeMB << 20,
+		}, time.Now())
+		for _, e := range removed {
+			fmt.Printf("Removed %s (%s)\n", e.ID, displayPath(e.Path))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Pruned %d backup(s).\n", len(removed))
+		return nil
+	},
+}
+
+// backupsOf filters entries down to the backups of absPath.
+func backupsOf(entries []backup.Entry, absPath string) []backup.Entry {
+	var out []backup.Entry
+	for _, e := range entries {
+		if e.Path == absPath {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func printBackups(w io.Writer, entries []backup.Entry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTAKEN\tREASON\tSIZE\tFILE")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", e.ID,
+			time.Unix(e.CreatedAt, 0).Format(time.RFC3339), e.Reason, e.Size, displayPath(e.Path))
+	}
+	tw.Flush()
+}
+
+// backupJSON is a backup.Entry as `backups list --json` reports it.
+type backupJSON struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	GistID    string `json:"gist_id,omitempty"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"created_at"`
+	Size      int64  `json:"size"`
+	SHA       string `json:"sha"`
+}
+
+func newBackupJSON(e backup.Entry) backupJSON {
+	return backupJSON{
+		ID:        e.ID,
+		Path:      e.Path,
+		GistID:    e.GistID,
+		Reason:    e.Reason,
+		CreatedAt: jsonTime(e.CreatedAt),
+		Size:      e.Size,
+		SHA:       e.SHA,
+	}
+}
+
+func init() {
+	addJSONFlags(backupsListCmd, &backupsListOutput)
+	backupsShowCmd.Flags().BoolVar(&backupsShowRaw, "raw", false, "Print only the content, e.g. to redirect it to a file")
+	backupsShowCmd.Flags().BoolVar(&backupsNoPager, "no-pager", false, "Skip the pager even when stdout is a terminal")
+	backupsPruneCmd.Flags().IntVar(&backupsKeep, "keep", backup.DefaultPolicy.KeepPerFile, "Newest backups to keep per file")
+	backupsPruneCmd.Flags().DurationVar(&backupsMaxAge, "max-age", backup.DefaultPolicy.MaxAge, "Delete backups older than this")
+	backupsPruneCmd.Flags().Int64Var(&backupsMaxSizeMB, "max-size-mb", backup.DefaultPolicy.MaxSize>>20, "Keep at most this many megabytes of backups")
+	backupsCmd.AddCommand(backupsListCmd, backupsShowCmd, backupsPruneCmd)
+	rootCmd.AddCommand(backupsCmd)
+}
@@ -0,0 +1,348 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/manifest"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneManifest string
+	cloneDir      string
+	clonePaths    []string
+	cloneForce    bool
+	cloneHost     string
+	cloneAccount  string
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone [gist-id]",
+	Short: "Download existing Gists to this machine and track their files",
+	Long: `Set up a machine from Gists you already sync: every file of the Gist is
+written to --dir (default: the current directory), or where --path maps it,
+and tracked as in sync. With --manifest, the files listed in a manifest are
+cloned instead, each to its own path ("~/" and relative paths are under your
+home directory) and with the sync settings it lists; 'gh automagist export'
+writes one.
+
+Nothing is uploaded. A file that already exists with other content is left
+alone unless --force is given, in which case it is backed up first.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cloneManifest != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		if err := sm.Load(); err != nil {
+			return err
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+
+		var targets []cloneTarget
+		var expand func(head cloneTarget, files map[string][]byte) ([]cloneTarget, error)
+		if cloneManifest != "" {
+			m, err := manifest.Read(cloneManifest)
+			if err != nil {
+				return err
+			}
+			targets = manifestTargets(m, home)
+		} else {
+			targets = []cloneTarget{{gistID: args[0], host: auth.NormalizeHostname(cloneHost), account: cloneAccount}}
+			expand = func(head cloneTarget, files map[string][]byte) ([]cloneTarget, error) {
+				return gistCloneTargets(head, files, home)
+			}
+		}
+
+		cloned, skipped, errored, err := cloneAll(sm, newGistClients(), targets, expand, cloneForce)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nClone complete: %d cloned, %d skipped, %d error(s)\n", cloned, skipped, errored)
+		if cloned > 0 && isMonitorRunning() {
+			fmt.Println("The running monitor will pick up the new files automatically.")
+		}
+		return nil
+	},
+}
+
+// cloneAll fetches each Gist targets name once and clones its files. expand,
+// when set, replaces a Gist's targets once its files are known.
+func cloneAll(sm *state.Manager, clients *gist.Clients, targets []cloneTarget, expand func(cloneTarget, map[string][]byte) ([]cloneTarget, error), force bool) (cloned, skipped, errored int, err error) {
+	for _, group := range groupCloneTargets(targets) {
+		head := group[0]
+		files, updatedAt, err := clients.For(head.host, head.account).FetchAllFiles(head.gistID)
+		if err != nil {
+			fmt.Printf("\nGist %s: Error: %v\n", truncateGistID(head.gistID), err)
+			errored += len(group)
+			continue
+		}
+		if expand != nil {
+			if group, err = expand(head, files); err != nil {
+				return cloned, skipped, errored, err
+			}
+		}
+		for _, t := range group {
+			content, ok := files[t.filename]
+			if !ok {
+				fmt.Printf("\n-> %s\n  Error: file %q not found in gist %s\n", displayPath(t.absPath), t.filename, truncateGistID(t.gistID))
+				errored++
+				continue
+			}
+			switch cloneFile(sm, t, content, updatedAt, force) {
+			case pullStatusPulled:
+				cloned++
+			case pullStatusError:
+				errored++
+			default:
+				skipped++
+			}
+		}
+	}
+	return cloned, skipped, errored, nil
+}
+
+// cloneTarget is one Gist file and the local path it is cloned to, with the
+// sync settings the tracked entry starts with.
+type cloneTarget struct {
+	absPath  string
+	gistID   string
+	filename string
+	host     string
+	account  string
+	settings manifest.Entry
+}
+
+// manifestTargets resolves m's entries against home.
+func manifestTargets(m *manifest.Manifest, home string) []cloneTarget {
+	targets := make([]cloneTarget, 0, len(m.Files))
+	for _, e := range m.Files {
+		targets = append(targets, cloneTarget{
+			absPath:  manifest.ExpandPath(e.Path, home),
+			gistID:   e.GistID,
+			filename: e.RemoteName(),
+			host:     auth.NormalizeHostname(e.Host),
+			account:  e.Account,
+			settings: e,
+		})
+	}
+	return targets
+}
+
+// applyManifestSettings sets fs's sync settings to e's. Reports whether
+// anything changed.
+func applyManifestSettings(fs *state.FileState, e manifest.Entry) bool {
+	direction := e.Direction
+	if direction == state.DirectionBoth {
+		direction = ""
+	}
+	var debounce string
+	if d, err := time.ParseDuration(e.Debounce); err == nil && e.Debounce != "" {
+		debounce = d.String()
+	}
+	changed := fs.Debounce != debounce || fs.Direction != direction || fs.Paused != e.Paused
+	fs.Debounce, fs.Direction, fs.Paused = debounce, direction, e.Paused
+	return changed
+}
+
+// groupCloneTargets buckets targets per Gist, in a stable order, so each
+// Gist is fetched once.
+func groupCloneTargets(targets []cloneTarget) [][]cloneTarget {
+	type gistKey struct{ host, account, gistID string }
+	byGist := make(map[gistKey][]cloneTarget)
+	var keys []gistKey
+	for _, t := range targets {
+		key := gistKey{t.host, t.account, t.gistID}
+		if _, ok := byGist[key]; !ok {
+			keys = append(keys, key)
+		}
+		byGist[key] = append(byGist[key], t)
+	}
+	out := make([][]cloneTarget, 0, len(keys))
+	for _, key := range keys {
+		out = append(out, byGist[key])
+	}
+	return out
+}
+
+// gistCloneTargets places every file of a Gist cloned by ID: under --dir,
+// or where a --path filename=path flag maps it.
+func gistCloneTargets(head cloneTarget, files map[string][]byte, home string) ([]cloneTarget, error) {
+	dir, err := expandLocalPath(cloneDir, home)
+	if err != nil {
+		return nil, err
+	}
+	mapped := make(map[string]string, len(clonePaths))
+	for _, raw := range clonePaths {
+		name, path, ok := strings.Cut(raw, "=")
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("invalid --path %q: want filename=path", raw)
+		}
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("invalid --path %q: gist %s has no file %q", raw, truncateGistID(head.gistID), name)
+		}
+		if mapped[name], err = expandLocalPath(path, home); err != nil {
+			return nil, err
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]cloneTarget, 0, len(names))
+	for _, name := range names {
+		t := head
+		t.filename = name
+		t.absPath = filepath.Join(dir, name)
+		if p, ok := mapped[name]; ok {
+			t.absPath = p
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// expandLocalPath resolves a path given on the command line: "~/" under
+// home (for when the shell did not expand it), anything else as usual.
+func expandLocalPath(p, home string) (string, error) {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		return manifest.ExpandPath(p, home), nil
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	return abs, nil
+}
+
+// errAlreadyTracked aborts registering a file another process tracked first.
+var errAlreadyTracked = errors.New("already tracked")
+
+// cloneFile writes content to t.absPath and tracks it as synced with the
+// Gist revision at remoteUpdatedAt. The entry is registered before the
+// write, which goes through applyRemote so a running monitor treats it as a
+// pull and never uploads it. An existing file with other content is only
+// replaced, after a backup, with force. Reports the outcome as pullFile does.
+func cloneFile(sm *state.Manager, t cloneTarget, content []byte, remoteUpdatedAt int64, force bool) pullStatus {
+	fmt.Printf("\n-> %s\n", displayPath(t.absPath))
+	if _, ok := sm.Files[t.absPath]; ok {
+		fmt.Println("  Skipped: already tracked")
+		return pullStatusSkipped
+	}
+	if other, ok := sm.FindRemote(t.gistID, t.filename); ok {
+		fmt.Printf("  Skipped: %s already syncs to %q in this Gist\n", displayPath(other), t.filename)
+		return pullStatusSkipped
+	}
+
+	perm := os.FileMode(0644)
+	var localContent []byte
+	info, err := os.Lstat(t.absPath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		fmt.Printf("  Error: %v\n", err)
+		return pullStatusError
+	case !info.Mode().IsRegular():
+		fmt.Println("  Skipped: exists and is not a regular file (a symlink or directory); clone to its target instead")
+		return pullStatusSkipped
+	default:
+		if localContent, err = os.ReadFile(t.absPath); err != nil {
+			fmt.Printf("  Error: %v\n", err)
+			return pullStatusError
+		}
+		perm = info.Mode().Perm()
+		if !bytes.Equal(localContent, content) && !force {
+			fmt.Println("  Skipped: exists with other content — use --force to replace it (it is backed up first)")
+			return pullStatusSkipped
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(t.absPath), 0755); err != nil {
+		fmt.Printf("  Error creating directory: %v\n", err)
+		return pullStatusError
+	}
+
+	err = sm.Update(func(files map[string]state.FileState) error {
+		if _, ok := files[t.absPath]; ok {
+			return errAlreadyTracked
+		}
+		fs := state.NewFileState(t.gistID, time.Now().Unix())
+		if t.filename != filepath.Base(t.absPath) {
+			fs.RemoteFilename = t.filename
+		}
+		fs.Host, fs.Account = t.host, t.account
+		fs.RemoteUpdatedAt = remoteUpdatedAt
+		fs.ContentSHA = sha256Hex(content)
+		applyManifestSettings(&fs, t.settings)
+		files[t.absPath] = fs
+		return nil
+	})
+	if errors.Is(err, errAlreadyTracked) {
+		fmt.Println("  Skipped: already tracked")
+		return pullStatusSkipped
+	}
+	if err != nil {
+		fmt.Printf("  Error saving state: %v\n", err)
+		return pullStatusError
+	}
+
+	if localContent != nil && bytes.Equal(localContent, content) {
+		if err := rememberBase(sm, content); err != nil {
+			fmt.Printf("  Warning: failed to save base copy: %v\n", err)
+		}
+		fmt.Println("  [Track] already matches the Gist")
+		return pullStatusPulled
+	}
+	backupReason := ""
+	if localContent != nil {
+		backupReason = "clone"
+	}
+	effective := monitorDebounce(sm)
+	backupID, _, err := applyRemote(sm, t.absPath, localContent, content, remoteUpdatedAt, perm, backupReason, effective)
+	if backupID != "" {
+		fmt.Printf("  [Backup] %s\n", backupID)
+	}
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		if rmErr := sm.Update(func(files map[string]state.FileState) error {
+			delete(files, t.absPath)
+			return nil
+		}); rmErr != nil {
+			fmt.Printf("  Warning: failed to untrack %s: %v\n", displayPath(t.absPath), rmErr)
+		}
+		return pullStatusError
+	}
+	fmt.Printf("  [Write] %d bytes from %s in Gist %s\n", len(content), t.filename, truncateGistID(t.gistID))
+	return pullStatusPulled
+}
+
+func init() {
+	cloneCmd.Flags().StringVar(&cloneManifest, "manifest", "", "Clone the files listed in this manifest instead of one Gist")
+	cloneCmd.Flags().StringVar(&cloneDir, "dir", ".", "Directory to write the Gist's files to")
+	cloneCmd.Flags().StringArrayVar(&clonePaths, "path", nil, "Write one Gist file elsewhere, as filename=path (repeatable)")
+	cloneCmd.Flags().BoolVar(&cloneForce, "force", false, "Replace existing files with other content, after backing them up")
+	cloneCmd.Flags().StringVar(&cloneHost, "hostname", "", "GitHub host the Gist lives on (default: gh's default host)")
+	cloneCmd.Flags().StringVar(&cloneAccount, "account", "", "Account to use when several are logged in to the host (default: the active one)")
+	cloneCmd.MarkFlagsMutuallyExclusive("manifest", "dir")
+	cloneCmd.MarkFlagsMutuallyExclusive("manifest", "path")
+	rootCmd.AddCommand(cloneCmd)
+}
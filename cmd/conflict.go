@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+)
+
+// conflictPolicy is what the monitor does when a local edit is ready to push
+// but the Gist's copy of the file changed since the last sync.
+type conflictPolicy string
+
+const (
+	// conflictOverwrite pushes without checking (Last Write Wins). Default,
+	// and the only policy that costs no extra API call per sync.
+	conflictOverwrite conflictPolicy = "overwrite"
+	// conflictSkip leaves the Gist alone and records the conflict.
+	conflictSkip conflictPolicy = "skip"
+	// conflictBackupRemote saves the remote version beside the file, then pushes.
+	conflictBackupRemote conflictPolicy = "backup-remote"
+	// conflictMerge saves the remote version beside the file and does not
+	// push, leaving both sides for the user to merge.
+	conflictMerge conflictPolicy = "merge"
+)
+
+var onConflictFlag string
+
+func parseConflictPolicy(raw string) (conflictPolicy, error) {
+	switch p := conflictPolicy(raw); p {
+	case conflictOverwrite, conflictSkip, conflictBackupRemote, conflictMerge:
+		return p, nil
+	case "":
+		return conflictOverwrite, nil
+	}
+	return "", fmt.Errorf("invalid --on-conflict %q: want overwrite, skip, backup-remote or merge", raw)
+}
+
+// checkConflict asks the Gist whether this file changed there since the last
+// sync and applies the policy. proceed reports whether the caller should
+// still PATCH; conflicted reports whether a conflict was recorded in state.
+//
+// A Gist-level timestamp bump is not enough on its own — another file in the
+// same Gist may have changed — so the remote content is compared against
+// ContentSHA (the last-synced content) before declaring a conflict.
+func (p *pusher) checkConflict(absPath, gistID string, fs state.FileState, currentSHA string) (proceed, conflicted bool, err error) {
+	remoteAt, err := clientFor(p.clients, fs).FetchGistMeta(gistID)
+	if err != nil {
+		return false, false, fmt.Errorf("conflict check failed: %w", err)
+	}
+	if remoteAt <= fs.RemoteUpdatedAt {
+		return true, false, nil
+	}
+
+	remoteContent, _, err := clientFor(p.clients, fs).FetchFile(gistID, fs.RemoteName(absPath))
+	if err != nil {
+		return false, false, fmt.Errorf("conflict check failed: %w", err)
+	}
+	remoteSHA := sha256Hex(remoteContent)
+	switch {
+	case remoteSHA == currentSHA:
+		log.Printf("  [Sync] %s already matches the Gist; nothing to push", filepath.Base(absPath))
+		p.recordSynced(absPath, remoteContent, remoteAt, true)
+		return false, false, nil
+	case fs.ContentSHA != "" && remoteSHA == fs.ContentSHA:
+		return true, false, nil
+	case p.onConflict == conflictMerge && savedCopyMatches(fs.ConflictCopy, remoteSHA):
+		// The remote has not moved since we set it aside, so this save is
+		// the user's merge result.
+		log.Printf("  [Conflict] Treating this save of %s as the merge resolution", filepath.Base(absPath))
+		return true, false, nil
+	}
+
+	log.Printf("  [Conflict] %s was edited on the Gist at %s, after the last sync",
+		filepath.Base(absPath), time.Unix(remoteAt, 0).Format(time.RFC3339))
+
+	var copyPath string
+	if p.onConflict == conflictBackupRemote || p.onConflict == conflictMerge {
+		copyPath = fmt.Sprintf("%s.remote.%s", absPath, time.Now().Format("20060102-150405"))
+		if err := os.WriteFile(copyPath, remoteContent, 0644); err != nil {
+			return false, false, fmt.Errorf("failed to save remote copy: %w", err)
+		}
+		log.Printf("  [Conflict] Remote version saved to %s", copyPath)
+	}
+	p.recordConflict(absPath, copyPath)
+
+	switch p.onConflict {
+	case conflictBackupRemote:
+		return true, true, nil
+	case conflictMerge:
+		log.Printf("  [Conflict] Not pushing; merge the remote copy into %s and save to sync", filepath.Base(absPath))
+	default:
+		log.Printf("  [Conflict] Not pushing (--on-conflict=%s); run 'gh automagist pull' to review", p.onConflict)
+	}
+	return false, true, nil
+}
+
+func savedCopyMatches(copyPath, sha string) bool {
+	if copyPath == "" {
+		return false
+	}
+	content, err := os.ReadFile(copyPath)
+	return err == nil && sha256Hex(content) == sha
+}
+
+// recordConflict marks absPath as conflicted in state.json. The first
+// detection time is kept across repeated edits while unresolved.
+func (p *pusher) recordConflict(absPath, copyPath string) {
+	_, err := p.sm.UpdateFile(absPath, func(fs *state.FileState) {
+		if fs.ConflictAt == 0 {
+			fs.ConflictAt = time.Now().Unix()
+		}
+		if copyPath != "" {
+			fs.ConflictCopy = copyPath
+		}
+	})
+	if err != nil {
+		log.Printf("  Warning: failed to record conflict: %v", err)
+	}
+}
+
+// recordSynced stores the content and remote revision time local and remote
+// now agree on, so the next conflict check — and the next `pull --merge` —
+// has an accurate baseline.
+func (p *pusher) recordSynced(absPath string, content []byte, remoteAt int64, clearConflict bool) {
+	if err := rememberBase(p.sm, content); err != nil {
+		log.Printf("  Warning: failed to store base copy: %v", err)
+	}
+	_, err := p.sm.UpdateFile(absPath, func(fs *state.FileState) {
+		fs.ContentSHA = sha256Hex(content)
+		fs.RemoteUpdatedAt = remoteAt
+		if clearConflict {
+			fs.ClearConflict()
+		}
+	})
+	if err != nil {
+		log.Printf("  Warning: failed to record sync baseline: %v", err)
+	}
+}
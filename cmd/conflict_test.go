@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConflictPolicy_Valid(t *testing.T) {
+	for _, raw := range []string{"overwrite", "skip", "backup-remote", "merge"} {
+		p, err := parseConflictPolicy(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, conflictPolicy(raw), p)
+	}
+}
+
+func TestParseConflictPolicy_EmptyMeansOverwrite(t *testing.T) {
+	p, err := parseConflictPolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, conflictOverwrite, p)
+}
+
+func TestParseConflictPolicy_Invalid(t *testing.T) {
+	_, err := parseConflictPolicy("theirs")
+	assert.Error(t, err)
+}
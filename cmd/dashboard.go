@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,7 +12,6 @@ import (
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
-	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
 	"github.com/noriyo_tcp/gh-automagist/pkg/notify"
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/spf13/cobra"
@@ -117,7 +117,11 @@ func fetchDashboardStatuses() []notify.FileStatus {
 	if len(sm.Files) == 0 {
 		return nil
 	}
-	return notify.Detect(sm, gist.NewClient())
+	statuses, err := detect(context.Background(), sm, newGistClients())
+	if err != nil {
+		return nil
+	}
+	return statuses
 }
 
 // renderDashboardNotice prints the "N files have remote changes" summary
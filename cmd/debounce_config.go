@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"os"
 	"time"
 
 	"github.com/noriyo_tcp/gh-automagist/pkg/monitor"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 )
 
 // resolveDebounce picks the effective debounce interval from a triaged set of
@@ -23,3 +25,17 @@ func resolveDebounce(flagChanged bool, flagValue time.Duration, envRaw string) (
 	}
 	return monitor.DefaultDebounceInterval, nil
 }
+
+// monitorDebounce is the debounce interval the monitor applies to files
+// without their own: what the running monitor recorded in monitor.json, else
+// what it would resolve with no --debounce flag. It sizes the suppression
+// window for writes the monitor must not upload.
+func monitorDebounce(sm *state.Manager) time.Duration {
+	if info, err := sm.ReadMonitorInfo(); err == nil && info != nil && info.Debounce != "" {
+		if d, err := time.ParseDuration(info.Debounce); err == nil {
+			return d
+		}
+	}
+	effective, _ := resolveDebounce(false, 0, os.Getenv(debounceEnvVar))
+	return effective
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -19,6 +20,7 @@ import (
 var (
 	fetchDiff    bool
 	fetchNoPager bool
+	fetchOutput  jsonOutput
 )
 
 var fetchCmd = &cobra.Command{
@@ -39,22 +41,39 @@ diff a single tracked file instead of all newer ones.`,
 		if err := sm.Load(); err != nil {
 			return err
 		}
+		if fetchOutput.enabled() {
+			if fetchDiff || len(args) == 1 {
+				return fmt.Errorf("--json, --jq and --template report every tracked file; they cannot be combined with --diff or a path")
+			}
+			statuses, err := detect(cmd.Context(), sm, newGistClients())
+			if err != nil {
+				return err
+			}
+			if err := fetchOutput.write(cmd.OutOrStdout(), newCheckJSONs(sm, statuses)); err != nil {
+				return err
+			}
+			return checkExitStatus(cmd, statuses)
+		}
 		if len(sm.Files) == 0 {
 			fmt.Println("No files are currently tracked.")
 			return nil
 		}
 
-		client := gist.NewClient()
+		clients := newGistClients()
 
 		switch {
 		case !fetchDiff && len(args) == 0:
-			statuses := notify.Detect(sm, client)
+			statuses, err := detect(cmd.Context(), sm, clients)
+			if err != nil {
+				return err
+			}
 			printFetchResult(os.Stdout, statuses)
+			printConflicts(os.Stdout, sm)
 			return nil
 		case !fetchDiff && len(args) == 1:
 			return fmt.Errorf("--diff is required to inspect a specific file")
 		case fetchDiff && len(args) == 0:
-			return runFetchDiffAll(sm, client, fetchNoPager)
+			return runFetchDiffAll(cmd.Context(), sm, clients, fetchNoPager)
 		default: // fetchDiff && len(args) == 1
 			absPath, err := filepath.Abs(args[0])
 			if err != nil {
@@ -64,48 +83,60 @@ diff a single tracked file instead of all newer ones.`,
 			if !ok {
 				return fmt.Errorf("file not tracked: %s", absPath)
 			}
-			return runFetchDiffSingle(absPath, fs.GistID, client, fetchNoPager)
+			return runFetchDiffSingle(absPath, fs.GistID, fs.RemoteName(absPath), clientFor(clients, fs), fetchNoPager)
 		}
 	},
 }
 
 // printFetchResult renders the fetch command's summary from a notify.Detect
-// result. Groups per-Gist for the "N Gist(s)" summary and lists per-file
+// result. Groups per-Gist for the "N Gist(s)" summary — under one heading per
+// host when more than gh's default host is involved — and lists per-file
 // entries whose Gist has newer content.
 func printFetchResult(w io.Writer, statuses []notify.FileStatus) {
-	byGist := groupByGist(statuses)
-	gistIDs := make([]string, 0, len(byGist))
-	for id := range byGist {
-		gistIDs = append(gistIDs, id)
+	hosts, byHost := groupByHost(statuses)
+	gistCount := 0
+	for _, host := range hosts {
+		gistCount += len(groupByGist(byHost[host]))
 	}
-	sort.Strings(gistIDs)
 
-	fmt.Fprintf(w, "Checking %d Gist(s) for remote changes...\n\n", len(gistIDs))
+	fmt.Fprintf(w, "Checking %d Gist(s) for remote changes...\n\n", gistCount)
 
 	var newerFiles []string
-	for _, gistID := range gistIDs {
-		group := byGist[gistID]
-		head := group[0]
-		if head.Err != nil {
-			fmt.Fprintf(w, "  %s: error — %v\n", truncateGistID(gistID), head.Err)
-			continue
+	for _, host := range hosts {
+		if showHosts(hosts) {
+			fmt.Fprintf(w, "%s:\n", host)
 		}
+		byGist := groupByGist(byHost[host])
+		gistIDs := make([]string, 0, len(byGist))
+		for id := range byGist {
+			gistIDs = append(gistIDs, id)
+		}
+		sort.Strings(gistIDs)
 
-		var groupNewer []string
-		for _, s := range group {
-			if s.RemoteNewer {
-				groupNewer = append(groupNewer, s.Path)
+		for _, gistID := range gistIDs {
+			group := byGist[gistID]
+			head := group[0]
+			if head.Err != nil {
+				fmt.Fprintf(w, "  %s: error — %v\n", truncateGistID(gistID), head.Err)
+				continue
 			}
-		}
-		if len(groupNewer) == 0 {
-			fmt.Fprintf(w, "  %s: in sync\n", truncateGistID(gistID))
-			continue
-		}
 
-		fmt.Fprintf(w, "  %s: newer available (remote updated %s)\n",
-			truncateGistID(gistID),
-			time.Unix(head.RemoteUpdatedAt, 0).Format(time.RFC3339))
-		newerFiles = append(newerFiles, groupNewer...)
+			var groupNewer []string
+			for _, s := range group {
+				if s.RemoteNewer {
+					groupNewer = append(groupNewer, s.Path)
+				}
+			}
+			if len(groupNewer) == 0 {
+				fmt.Fprintf(w, "  %s: in sync\n", truncateGistID(gistID))
+				continue
+			}
+
+			fmt.Fprintf(w, "  %s: newer available (remote updated %s)\n",
+				truncateGistID(gistID),
+				time.Unix(head.RemoteUpdatedAt, 0).Format(time.RFC3339))
+			newerFiles = append(newerFiles, groupNewer...)
+		}
 	}
 
 	fmt.Fprintln(w)
@@ -126,13 +157,18 @@ func printFetchResult(w io.Writer, statuses []notify.FileStatus) {
 // runFetchDiffAll prints the meta summary followed by per-file unified diffs
 // for every file whose Gist has newer content. Buckets per Gist so each Gist
 // is fetched exactly once.
-func runFetchDiffAll(sm *state.Manager, client *gist.Client, noPagerFlag bool) error {
-	statuses := notify.Detect(sm, client)
+func runFetchDiffAll(ctx context.Context, sm *state.Manager, clients *gist.Clients, noPagerFlag bool) error {
+	statuses, err := detect(ctx, sm, clients)
+	if err != nil {
+		return err
+	}
 
-	perGist := make(map[string][]notify.FileStatus)
+	type gistKey struct{ host, gistID string }
+	perGist := make(map[gistKey][]notify.FileStatus)
 	for _, s := range statuses {
 		if s.RemoteNewer && s.Err == nil {
-			perGist[s.GistID] = append(perGist[s.GistID], s)
+			key := gistKey{s.Host, s.GistID}
+			perGist[key] = append(perGist[key], s)
 		}
 	}
 
@@ -144,25 +180,31 @@ func runFetchDiffAll(sm *state.Manager, client *gist.Client, noPagerFlag bool) e
 			return nil
 		}
 
-		gistIDs := make([]string, 0, len(perGist))
-		for id := range perGist {
-			gistIDs = append(gistIDs, id)
+		keys := make([]gistKey, 0, len(perGist))
+		for key := range perGist {
+			keys = append(keys, key)
 		}
-		sort.Strings(gistIDs)
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].host != keys[j].host {
+				return keys[i].host < keys[j].host
+			}
+			return keys[i].gistID < keys[j].gistID
+		})
 
-		for _, gistID := range gistIDs {
-			group := perGist[gistID]
+		for _, key := range keys {
+			group := perGist[key]
 			sort.Slice(group, func(i, j int) bool { return group[i].Path < group[j].Path })
 
-			allFiles, _, err := client.FetchAllFiles(gistID)
+			client := clientFor(clients, sm.Files[group[0].Path])
+			allFiles, _, err := client.FetchAllFiles(key.gistID)
 			if err != nil {
-				fmt.Fprintf(w, "=== Gist %s ===\n", truncateGistID(gistID))
+				fmt.Fprintf(w, "=== Gist %s ===\n", truncateGistID(key.gistID))
 				fmt.Fprintf(w, "Error fetching content: %v\n\n", err)
 				continue
 			}
 
 			for _, f := range group {
-				filename := filepath.Base(f.Path)
+				filename := sm.Files[f.Path].RemoteName(f.Path)
 				remoteContent, ok := allFiles[filename]
 				if !ok {
 					fmt.Fprintf(w, "=== %s ===\n", displayPath(f.Path))
@@ -179,12 +221,11 @@ func runFetchDiffAll(sm *state.Manager, client *gist.Client, noPagerFlag bool) e
 }
 
 // runFetchDiffSingle prints the unified diff for one tracked file.
-func runFetchDiffSingle(absPath, gistID string, client *gist.Client, noPagerFlag bool) error {
+func runFetchDiffSingle(absPath, gistID, filename string, client *gist.Client, noPagerFlag bool) error {
 	allFiles, _, err := client.FetchAllFiles(gistID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch gist %s: %w", truncateGistID(gistID), err)
 	}
-	filename := filepath.Base(absPath)
 	remoteContent, ok := allFiles[filename]
 	if !ok {
 		return fmt.Errorf("file %q not found in gist %s", filename, truncateGistID(gistID))
@@ -207,21 +248,7 @@ func writeFileDiff(w io.Writer, localPath string, remoteContent []byte, mode dif
 		return fmt.Errorf("read local %s: %w", localPath, err)
 	}
 
-	// Write to a shared tmp dir with short relative names so git's diff
-	// headers read as `a/local` vs `b/remote` instead of full tmp paths.
-	tmpDir, err := os.MkdirTemp("", "gh-automagist-diff-*")
-	if err != nil {
-		return err
-	}
-	defer os.RemoveAll(tmpDir)
-	if err := os.WriteFile(filepath.Join(tmpDir, "local"), localContent, 0644); err != nil {
-		return err
-	}
-	if err := os.WriteFile(filepath.Join(tmpDir, "remote"), remoteContent, 0644); err != nil {
-		return err
-	}
-
-	out, err := diff.Unified(tmpDir, "local", "remote", mode)
+	out, err := diffContents("local", localContent, "remote", remoteContent, mode)
 	if err != nil {
 		return err
 	}
@@ -234,6 +261,24 @@ func writeFileDiff(w io.Writer, localPath string, remoteContent []byte, mode dif
 	return nil
 }
 
+// diffContents returns the unified diff of a vs b, labelled aName and bName.
+// Writes both to a shared tmp dir with short relative names so git's diff
+// headers read as `a/local` vs `b/remote` instead of full tmp paths.
+func diffContents(aName string, a []byte, bName string, b []byte, mode diff.ColorMode) ([]byte, error) {
+	tmpDir, err := os.MkdirTemp("", "gh-automagist-diff-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	if err := os.WriteFile(filepath.Join(tmpDir, aName), a, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, bName), b, 0644); err != nil {
+		return nil, err
+	}
+	return diff.Unified(tmpDir, aName, bName, mode)
+}
+
 // colorForOutput picks the diff color mode. When output actually goes to a
 // terminal via the pager, force color-always so ANSI escapes survive the pipe
 // (less -R passes them through). Otherwise plain output.
@@ -254,6 +299,32 @@ func groupByGist(statuses []notify.FileStatus) map[string][]notify.FileStatus {
 	return out
 }
 
+// printConflicts lists files the monitor flagged under --on-conflict, with
+// where the remote copy was saved. Silent when there are none.
+func printConflicts(w io.Writer, sm *state.Manager) {
+	var paths []string
+	for path, fs := range sm.Files {
+		if fs.ConflictAt != 0 {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	sort.Strings(paths)
+
+	fmt.Fprintf(w, "\n%d file(s) with unresolved conflicts:\n", len(paths))
+	for _, path := range paths {
+		fs := sm.Files[path]
+		fmt.Fprintf(w, "  %s (since %s)\n", displayPath(path), time.Unix(fs.ConflictAt, 0).Format(time.RFC3339))
+		if fs.ConflictCopy != "" {
+			fmt.Fprintf(w, "    remote copy: %s\n", displayPath(fs.ConflictCopy))
+		}
+	}
+	fmt.Fprintln(w, "Run `gh automagist pull --force <path>` to take the remote version, or (with --on-conflict=merge)")
+	fmt.Fprintln(w, "merge the remote copy into the file and save it.")
+}
+
 func truncateGistID(id string) string {
 	if len(id) > 8 {
 		return id[:8] + "..."
@@ -264,5 +335,7 @@ func truncateGistID(id string) string {
 func init() {
 	fetchCmd.Flags().BoolVar(&fetchDiff, "diff", false, "Fetch content and show a unified diff (local vs remote)")
 	fetchCmd.Flags().BoolVar(&fetchNoPager, "no-pager", false, "Skip the pager even when stdout is a terminal")
+	addDetectFlags(fetchCmd)
+	addJSONFlags(fetchCmd, &fetchOutput)
 	rootCmd.AddCommand(fetchCmd)
 }
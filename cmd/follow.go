@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+)
+
+var (
+	propagateRenames bool
+	propagateDeletes bool
+)
+
+// propagator mirrors local renames and deletions, which the watcher has
+// already recorded in state.json, onto the Gist. Without
+// --propagate-renames / --propagate-deletes the Gist is left untouched: a
+// renamed file keeps syncing to its old Gist filename and a deleted one stays
+// tracked as missing.
+type propagator struct {
+	sm      *state.Manager
+	clients *gist.Clients
+	renames bool
+	deletes bool
+}
+
+// renamed is the watcher's OnRename.
+func (p *propagator) renamed(oldPath, newPath string) {
+	if !p.renames {
+		return
+	}
+	fs, ok := p.sm.File(newPath)
+	if !ok {
+		return
+	}
+	oldName, newName := fs.RemoteName(newPath), filepath.Base(newPath)
+	if oldName == newName {
+		return
+	}
+	if other, taken := p.sm.Snapshot().FindRemote(fs.GistID, newName); taken && other != newPath {
+		log.Printf("  [Rename] Gist %s already has %s (for %s); keeping %s", fs.GistID, newName, other, oldName)
+		return
+	}
+
+	updatedAt, err := clientFor(p.clients, fs).RenameFile(fs.GistID, oldName, newName)
+	if err != nil {
+		log.Printf("  [Error] Failed to rename %s to %s in Gist %s: %v", oldName, newName, fs.GistID, err)
+		return
+	}
+	_, err = p.sm.UpdateFile(newPath, func(fs *state.FileState) {
+		fs.RemoteFilename = ""
+		fs.RemoteUpdatedAt = updatedAt
+	})
+	if err != nil {
+		log.Printf("  Warning: failed to record Gist rename: %v", err)
+		return
+	}
+	log.Printf("  [Rename] Gist %s: %s -> %s", fs.GistID, oldName, newName)
+}
+
+// missing is the watcher's OnMissing.
+func (p *propagator) missing(absPath string) {
+	if !p.deletes {
+		return
+	}
+	fs, ok := p.sm.File(absPath)
+	if !ok {
+		return
+	}
+	name := fs.RemoteName(absPath)
+	if _, err := clientFor(p.clients, fs).DeleteFile(fs.GistID, name); err != nil {
+		log.Printf("  [Error] Failed to delete %s from Gist %s: %v", name, fs.GistID, err)
+		return
+	}
+	err := p.sm.Update(func(files map[string]state.FileState) error {
+		delete(files, absPath)
+		return nil
+	})
+	if err != nil {
+		log.Printf("  Warning: failed to untrack %s: %v", absPath, err)
+		return
+	}
+	log.Printf("  [Delete] Removed %s from Gist %s and stopped tracking %s", name, fs.GistID, absPath)
+}
@@ -0,0 +1,592 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/diff"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist/gisttest"
+	"github.com/noriyo_tcp/gh-automagist/pkg/manifest"
+	"github.com/noriyo_tcp/gh-automagist/pkg/monitor"
+	"github.com/noriyo_tcp/gh-automagist/pkg/outbox"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeGist points newGistClients at a fresh gisttest server under a
+// temporary HOME for the duration of the test.
+func newFakeGist(t *testing.T) (*gisttest.Server, *state.Manager, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	srv := gisttest.NewServer(t)
+	prev := newGistClients
+	cache := gist.OpenETagCache(filepath.Join(home, "etags.json"))
+	newGistClients = func() *gist.Clients { return srv.Clients(gist.WithETagCache(cache)) }
+	t.Cleanup(func() { newGistClients = prev })
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	return srv, sm, home
+}
+
+// trackSynced writes content to name under dir and records it as synced with
+// gistID as of the Gist's current revision.
+func trackSynced(t *testing.T, srv *gisttest.Server, sm *state.Manager, dir, name, gistID, content string) string {
+	t.Helper()
+	absPath := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(absPath, []byte(content), 0o644))
+	info, err := os.Stat(absPath)
+	require.NoError(t, err)
+
+	g, ok := srv.Gist(gistID)
+	require.True(t, ok)
+	sm.AddTrackedFile(absPath, gistID, info.ModTime().Unix())
+	fs := sm.Files[absPath]
+	fs.RemoteUpdatedAt = g.UpdatedAt().Unix()
+	fs.ContentSHA = sha256Hex([]byte(content))
+	sm.Files[absPath] = fs
+	require.NoError(t, rememberBase(sm, []byte(content)))
+	return absPath
+}
+
+func TestGistFlow_FetchReportsRemoteEdit(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+
+	statuses, err := detect(context.Background(), sm, newGistClients())
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.False(t, statuses[0].RemoteNewer)
+
+	srv.SetFile(id, "notes.md", "two\n")
+	statuses, err = detect(context.Background(), sm, newGistClients())
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.True(t, statuses[0].RemoteNewer)
+
+	var out bytes.Buffer
+	printFetchResult(&out, statuses)
+	assert.Contains(t, out.String(), "1 file(s) with newer remote content")
+	assert.Contains(t, out.String(), displayPath(absPath))
+}
+
+func TestGistFlow_PullAppliesRemoteEdit(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"remote.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "local.md", id, "one\n")
+	fs := sm.Files[absPath]
+	fs.RemoteFilename = "remote.md"
+	sm.Files[absPath] = fs
+	require.NoError(t, sm.Save())
+
+	srv.SetFile(id, "remote.md", "two\n")
+	pullYes, pullNoBackup = true, true
+	t.Cleanup(func() { pullYes, pullNoBackup = false, false })
+
+	assert.Equal(t, pullStatusPulled, pullFile(sm, newGistClients(), absPath))
+	got, err := os.ReadFile(absPath)
+	require.NoError(t, err)
+	assert.Equal(t, "two\n", string(got))
+
+	g, _ := srv.Gist(id)
+	assert.Equal(t, g.UpdatedAt().Unix(), sm.Files[absPath].RemoteUpdatedAt)
+
+	// A second pull sees nothing new.
+	assert.Equal(t, pullStatusSkipped, pullFile(sm, newGistClients(), absPath))
+}
+
+func TestGistFlow_PullBlocksUnsyncedLocalEdit(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+	fs := sm.Files[absPath]
+	fs.UpdatedAt--
+	sm.Files[absPath] = fs
+	require.NoError(t, os.WriteFile(absPath, []byte("local\n"), 0o644))
+
+	srv.SetFile(id, "notes.md", "two\n")
+	assert.Equal(t, pullStatusBlocked, pullFile(sm, newGistClients(), absPath))
+	got, _ := os.ReadFile(absPath)
+	assert.Equal(t, "local\n", string(got))
+}
+
+func newTestPusher(t *testing.T, sm *state.Manager, clients *gist.Clients, policy conflictPolicy) *pusher {
+	t.Helper()
+	require.NoError(t, sm.Save())
+	box, err := outbox.Open(sm.OutboxPath())
+	require.NoError(t, err)
+	return &pusher{sm: sm, clients: clients, outbox: box, onConflict: policy}
+}
+
+func TestGistFlow_PushBatchIsOneRevision(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"a.md": "a\n", "b.md": "b\n"})
+	a := trackSynced(t, srv, sm, home, "a.md", id, "a\n")
+	b := trackSynced(t, srv, sm, home, "b.md", id, "b\n")
+	require.NoError(t, os.WriteFile(a, []byte("A\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("B\n"), 0o644))
+
+	p := newTestPusher(t, sm, newGistClients(), conflictOverwrite)
+	p.syncBatch(id, []string{a, b})
+
+	g, _ := srv.Gist(id)
+	assert.Equal(t, map[string]string{"a.md": "A\n", "b.md": "B\n"}, g.Files)
+	assert.Len(t, g.History, 2, "both files land in a single revision")
+	assert.Equal(t, 1, srv.CountRequests(http.MethodPatch))
+	assert.Equal(t, g.UpdatedAt().Unix(), sm.Files[a].RemoteUpdatedAt)
+	assert.Empty(t, p.outbox.Entries())
+}
+
+func TestGistFlow_PushSkipPolicyHoldsBackOnRemoteEdit(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+	require.NoError(t, os.WriteFile(absPath, []byte("local\n"), 0o644))
+	srv.SetFile(id, "notes.md", "remote\n")
+
+	p := newTestPusher(t, sm, newGistClients(), conflictSkip)
+	p.syncBatch(id, []string{absPath})
+
+	g, _ := srv.Gist(id)
+	assert.Equal(t, "remote\n", g.Files["notes.md"], "the remote edit is not overwritten")
+	assert.Zero(t, srv.CountRequests(http.MethodPatch))
+	assert.NotZero(t, sm.Files[absPath].ConflictAt)
+}
+
+func TestGistFlow_AddRecordsSyncedBaseline(t *testing.T) {
+	srv, _, home := newFakeGist(t)
+	created := filepath.Join(home, "created.md")
+	linked := filepath.Join(home, "linked.md")
+	require.NoError(t, os.WriteFile(created, []byte("new\n"), 0o644))
+	require.NoError(t, os.WriteFile(linked, []byte("mine\n"), 0o644))
+	existing := srv.AddGist(map[string]string{"linked.md": "theirs\n"})
+
+	require.NoError(t, addCmd.RunE(addCmd, []string{created}))
+	gistIDFlag = existing
+	t.Cleanup(func() { gistIDFlag = "" })
+	require.NoError(t, addCmd.RunE(addCmd, []string{linked}))
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, sm.Load())
+	for path, content := range map[string]string{created: "new\n", linked: "mine\n"} {
+		fs := sm.Files[path]
+		g, _ := srv.Gist(fs.GistID)
+		assert.Equal(t, g.UpdatedAt().Unix(), fs.RemoteUpdatedAt, path)
+		assert.Equal(t, sha256Hex([]byte(content)), fs.ContentSHA, path)
+		base, err := baseStore(sm).Get(fs.ContentSHA)
+		require.NoError(t, err, "add stores the merge base")
+		assert.Equal(t, content, string(base))
+	}
+
+	// The next local edit is an ordinary push, not a conflict with the
+	// content add itself uploaded.
+	require.NoError(t, os.WriteFile(linked, []byte("edited\n"), 0o644))
+	p := newTestPusher(t, sm, newGistClients(), conflictSkip)
+	p.syncBatch(existing, []string{linked})
+	g, _ := srv.Gist(existing)
+	assert.Equal(t, "edited\n", g.Files["linked.md"])
+	assert.Zero(t, sm.Files[linked].ConflictAt)
+}
+
+func TestGistFlow_AutoPullKeepsEditHeldBackByConflict(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+	require.NoError(t, os.WriteFile(absPath, []byte("local\n"), 0o644))
+	srv.SetFile(id, "notes.md", "remote\n")
+
+	// The event loop records the save, then the skip policy holds it back.
+	p := newTestPusher(t, sm, newGistClients(), conflictSkip)
+	_, err := sm.UpdateFile(absPath, func(fs *state.FileState) { fs.UpdatedAt = time.Now().Unix() + 1 })
+	require.NoError(t, err)
+	p.syncBatch(id, []string{absPath})
+	require.NotZero(t, sm.Files[absPath].ConflictAt)
+
+	w, err := monitor.NewWatcher(sm)
+	require.NoError(t, err)
+	t.Cleanup(w.Stop)
+	pl := &puller{sm: sm, clients: newGistClients(), watcher: w, outbox: p.outbox}
+	pl.pullOne(absPath)
+
+	got, err := os.ReadFile(absPath)
+	require.NoError(t, err)
+	assert.Equal(t, "local\n", string(got), "the held-back edit is not overwritten")
+}
+
+func TestGistFlow_PullBlocksConflictedFile(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+	require.NoError(t, os.WriteFile(absPath, []byte("local\n"), 0o644))
+	srv.SetFile(id, "notes.md", "remote\n")
+	p := newTestPusher(t, sm, newGistClients(), conflictSkip)
+	_, err := sm.UpdateFile(absPath, func(fs *state.FileState) { fs.UpdatedAt = time.Now().Unix() + 1 })
+	require.NoError(t, err)
+	p.syncBatch(id, []string{absPath})
+	require.NotZero(t, sm.Files[absPath].ConflictAt)
+
+	pullYes = true
+	t.Cleanup(func() { pullYes, pullForce = false, false })
+	assert.Equal(t, pullStatusBlocked, pullFile(sm, newGistClients(), absPath))
+	got, err := os.ReadFile(absPath)
+	require.NoError(t, err)
+	assert.Equal(t, "local\n", string(got), "no markers, mtime not ahead, still kept")
+
+	pullForce = true
+	assert.Equal(t, pullStatusPulled, pullFile(sm, newGistClients(), absPath))
+	got, err = os.ReadFile(absPath)
+	require.NoError(t, err)
+	assert.Equal(t, "remote\n", string(got))
+	assert.Zero(t, sm.Files[absPath].ConflictAt)
+}
+
+func TestGistFlow_PushFailureIsQueued(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+	require.NoError(t, os.WriteFile(absPath, []byte("local\n"), 0o644))
+
+	clients := gist.NewClients(gist.WithBaseURL(srv.URL), gist.WithAuthToken("revoked"))
+	p := newTestPusher(t, sm, clients, conflictOverwrite)
+	p.syncBatch(id, []string{absPath})
+
+	_, queued := p.outbox.Entries()[absPath]
+	assert.True(t, queued)
+}
+
+func TestGistFlow_FetchGroupsByHost(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"a.md": "a\n"})
+	trackSynced(t, srv, sm, home, "a.md", id, "a\n")
+	b := trackSynced(t, srv, sm, home, "b.md", id, "b\n")
+	fs := sm.Files[b]
+	fs.Host = "ghe.example.com"
+	sm.Files[b] = fs
+
+	statuses, err := detect(context.Background(), sm, newGistClients())
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.Equal(t, "ghe.example.com", statuses[1].Host)
+	assert.Equal(t, 2, srv.CountRequests(http.MethodGet), "same Gist ID on two hosts is two Gists")
+
+	var out bytes.Buffer
+	printFetchResult(&out, statuses)
+	assert.Contains(t, out.String(), "Checking 2 Gist(s)")
+	assert.Contains(t, out.String(), hostLabel("")+":\n")
+	assert.Contains(t, out.String(), "ghe.example.com:\n")
+}
+
+func TestGistFlow_StatusReportsQuotaAndCachesChecks(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+
+	_, err := detect(context.Background(), sm, newGistClients())
+	require.NoError(t, err)
+	spent := gisttest.RateLimit - srv.RateLimitRemaining()
+
+	// A later invocation re-checks the unchanged Gist for free.
+	clients := newGistClients()
+	_, err = detect(context.Background(), sm, clients)
+	require.NoError(t, err)
+	assert.Equal(t, spent, gisttest.RateLimit-srv.RateLimitRemaining())
+
+	var out bytes.Buffer
+	printRateLimits(&out, clients.RateLimits())
+	assert.Contains(t, out.String(), fmt.Sprintf("%d/%d remaining", srv.RateLimitRemaining(), gisttest.RateLimit))
+}
+
+func TestGistFlow_PullSkipsPausedAndPushOnly(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"a.md": "a\n", "b.md": "b\n"})
+	a := trackSynced(t, srv, sm, home, "a.md", id, "a\n")
+	b := trackSynced(t, srv, sm, home, "b.md", id, "b\n")
+	fs := sm.Files[a]
+	fs.Paused = true
+	sm.Files[a] = fs
+	fs = sm.Files[b]
+	fs.Direction = state.DirectionPush
+	sm.Files[b] = fs
+	require.NoError(t, sm.Save())
+	srv.SetFile(id, "a.md", "A\n")
+	srv.SetFile(id, "b.md", "B\n")
+	pullYes = true
+	t.Cleanup(func() { pullYes = false })
+
+	assert.Equal(t, pullStatusSkipped, pullFile(sm, newGistClients(), a))
+	assert.Equal(t, pullStatusSkipped, pullFile(sm, newGistClients(), b))
+	got, _ := os.ReadFile(a)
+	assert.Equal(t, "a\n", string(got))
+	assert.Zero(t, srv.CountRequests(http.MethodGet), "held files are not even fetched")
+	assert.Contains(t, settingsBadge(sm.Files[a]), "paused")
+	assert.Contains(t, settingsBadge(sm.Files[b]), "push only")
+}
+
+func TestGistFlow_HistoryShowsRevisionDiffs(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	srv.SetFile(id, "other.md", "unrelated\n")
+	srv.SetFile(id, "notes.md", "one\ntwo\n")
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\ntwo\n")
+	client := clientFor(newGistClients(), sm.Files[absPath])
+
+	rev, parent, err := findRevision(client, id, "v3")
+	require.NoError(t, err)
+	require.NotNil(t, parent)
+	assert.Equal(t, "v2", parent.Version)
+
+	var out bytes.Buffer
+	require.NoError(t, writeRevisionDiff(&out, client, id, "notes.md", *rev, parent, diff.ColorNever))
+	assert.Contains(t, out.String(), "+two")
+
+	out.Reset()
+	rev, parent, err = findRevision(client, id, "v2")
+	require.NoError(t, err)
+	require.NoError(t, writeRevisionDiff(&out, client, id, "notes.md", *rev, parent, diff.ColorNever))
+	assert.Contains(t, out.String(), "No change to notes.md")
+
+	out.Reset()
+	rev, parent, err = findRevision(client, id, "v1")
+	require.NoError(t, err)
+	assert.Nil(t, parent, "the first revision has no parent")
+	require.NoError(t, writeRevisionDiff(&out, client, id, "notes.md", *rev, parent, diff.ColorNever))
+	assert.Contains(t, out.String(), "+one")
+
+	_, _, err = findRevision(client, id, "v9")
+	assert.Error(t, err)
+	_, _, err = findRevision(client, id, "v")
+	assert.ErrorContains(t, err, "ambiguous (3 matches)", "a shared prefix is not resolved to the newest revision")
+
+	revs, more, err := client.Revisions(id, 1, 30)
+	require.NoError(t, err)
+	out.Reset()
+	printHistory(&out, absPath, sm.Files[absPath], revs, more)
+	assert.Contains(t, out.String(), "v3  "+gisttest.Epoch.Add(2*time.Minute).Local().Format(time.RFC3339)+"  +1 -0  ← last synced")
+	assert.NotContains(t, out.String(), "More revisions")
+}
+
+func TestGistFlow_RestoreRevisionAndPush(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	srv.SetFile(id, "notes.md", "two\n")
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "two\n")
+	require.NoError(t, sm.Save())
+	client := clientFor(newGistClients(), sm.Files[absPath])
+
+	restoreYes, restorePush = true, true
+	t.Cleanup(func() { restoreYes, restorePush = false, false })
+
+	content, source, err := revisionContent(client, id, "notes.md", "v1")
+	require.NoError(t, err)
+	assert.Contains(t, source, "revision v1")
+	require.NoError(t, restoreFile(sm, client, absPath, content, source))
+
+	got, err := os.ReadFile(absPath)
+	require.NoError(t, err)
+	assert.Equal(t, "one\n", string(got))
+	g, _ := srv.Gist(id)
+	assert.Equal(t, "one\n", g.Files["notes.md"], "pushed as a new revision")
+	assert.Len(t, g.History, 3)
+
+	fs := sm.Files[absPath]
+	assert.Equal(t, g.UpdatedAt().Unix(), fs.RemoteUpdatedAt)
+	assert.Equal(t, sha256Hex([]byte("one\n")), fs.ContentSHA)
+	assert.NotZero(t, fs.PullSuppressUntil, "the monitor must not upload the restored file again")
+
+	entries, err := backupStore(sm).List()
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "restore", entries[0].Reason)
+	content, _, err = backupContent(sm, absPath, entries[0].ID[:15])
+	require.NoError(t, err)
+	assert.Equal(t, "two\n", string(content))
+	_, _, err = backupContent(sm, absPath, "20000101-000000")
+	assert.ErrorContains(t, err, entries[0].ID)
+
+	// Backups left next to the file by earlier releases still restore.
+	require.NoError(t, os.WriteFile(absPath+".bak.20250101-120000", []byte("legacy\n"), 0o644))
+	content, _, err = backupContent(sm, absPath, "20250101-120000")
+	require.NoError(t, err)
+	assert.Equal(t, "legacy\n", string(content))
+}
+
+func TestGistFlow_RestoreWithoutPushStaysUnsynced(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	srv.SetFile(id, "notes.md", "two\n")
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "two\n")
+	require.NoError(t, sm.Save())
+	before := sm.Files[absPath]
+	client := clientFor(newGistClients(), before)
+
+	restoreYes = true
+	t.Cleanup(func() { restoreYes = false })
+	content, source, err := revisionContent(client, id, "notes.md", "v1")
+	require.NoError(t, err)
+	require.NoError(t, restoreFile(sm, client, absPath, content, source))
+
+	g, _ := srv.Gist(id)
+	assert.Equal(t, "two\n", g.Files["notes.md"], "nothing is pushed")
+	fs := sm.Files[absPath]
+	assert.Equal(t, before.ContentSHA, fs.ContentSHA, "the last synced remote stays the baseline")
+	assert.Equal(t, before.RemoteUpdatedAt, fs.RemoteUpdatedAt)
+	assert.Equal(t, sha256Hex([]byte("one\n")), fs.PullSuppressSHA)
+
+	// The monitor does not upload the write itself; the next save does.
+	p := newTestPusher(t, sm, newGistClients(), conflictSkip)
+	p.syncBatch(id, []string{absPath})
+	assert.Zero(t, srv.CountRequests(http.MethodPatch))
+	p.syncBatch(id, []string{absPath})
+	g, _ = srv.Gist(id)
+	assert.Equal(t, "one\n", g.Files["notes.md"])
+}
+
+func TestGistFlow_CloneByIDAndManifest(t *testing.T) {
+	srv, _, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"a.txt": "a\n", "b.conf": "b\n"})
+	other := srv.AddGist(map[string]string{".zshrc": "zsh\n", ".vimrc": "vim\n"})
+
+	cloneDir, clonePaths = filepath.Join(home, "sub"), []string{"b.conf=~/nested/bee.conf"}
+	t.Cleanup(func() { cloneDir, clonePaths, cloneManifest = ".", nil, "" })
+	require.NoError(t, cloneCmd.RunE(cloneCmd, []string{id}))
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, sm.Load())
+	g, _ := srv.Gist(id)
+	for path, want := range map[string]string{
+		filepath.Join(home, "sub", "a.txt"):       "a\n",
+		filepath.Join(home, "nested", "bee.conf"): "b\n",
+	} {
+		got, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got))
+		fs, ok := sm.Files[path]
+		require.True(t, ok, path)
+		assert.Equal(t, g.UpdatedAt().Unix(), fs.RemoteUpdatedAt)
+		assert.Equal(t, sha256Hex([]byte(want)), fs.ContentSHA)
+	}
+	assert.Equal(t, "b.conf", sm.Files[filepath.Join(home, "nested", "bee.conf")].RemoteFilename)
+
+	// An identical file is just tracked; one with other content is left
+	// alone without --force.
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".zshrc"), []byte("zsh\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".vimrc"), []byte("mine\n"), 0o644))
+	manifestPath := filepath.Join(home, "manifest.yml")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(fmt.Sprintf(
+		"version: 1\nfiles:\n  - path: ~/.zshrc\n    gist_id: %s\n  - path: .vimrc\n    gist_id: %s\n", other, other)), 0o644))
+	cloneDir, clonePaths, cloneManifest = ".", nil, manifestPath
+	require.NoError(t, cloneCmd.RunE(cloneCmd, nil))
+
+	require.NoError(t, sm.Load())
+	assert.Contains(t, sm.Files, filepath.Join(home, ".zshrc"))
+	assert.NotContains(t, sm.Files, filepath.Join(home, ".vimrc"))
+	got, err := os.ReadFile(filepath.Join(home, ".vimrc"))
+	require.NoError(t, err)
+	assert.Equal(t, "mine\n", string(got))
+
+	assert.Zero(t, srv.CountRequests(http.MethodPatch), "clone never pushes")
+}
+
+func TestGistFlow_CloneOutlastsDebounceWithoutPushing(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"slow.md": "slow\n", "plain.md": "plain\n"})
+	require.NoError(t, sm.WriteMonitorInfo(state.MonitorInfo{PID: os.Getpid(), Debounce: "3m"}))
+	manifestPath := filepath.Join(home, "manifest.yml")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(fmt.Sprintf(
+		"files:\n  - path: slow.md\n    gist_id: %s\n    debounce: 10m\n  - path: plain.md\n    gist_id: %s\n", id, id)), 0o644))
+	cloneManifest = manifestPath
+	t.Cleanup(func() { cloneManifest = "" })
+
+	before := time.Now()
+	require.NoError(t, cloneCmd.RunE(cloneCmd, nil))
+	require.NoError(t, sm.Load())
+	slow := sm.Files[filepath.Join(home, "slow.md")]
+	assert.GreaterOrEqual(t, slow.PullSuppressUntil, before.Add(10*time.Minute).Unix(),
+		"the manifest's own debounce sizes the window")
+	plain := sm.Files[filepath.Join(home, "plain.md")]
+	assert.GreaterOrEqual(t, plain.PullSuppressUntil, before.Add(3*time.Minute).Unix(),
+		"the running monitor's --debounce sizes the rest")
+
+	// The monitor firing at the end of either debounce still sees an echo.
+	p := newTestPusher(t, sm, newGistClients(), conflictOverwrite)
+	p.syncBatch(id, []string{filepath.Join(home, "slow.md"), filepath.Join(home, "plain.md")})
+	assert.Zero(t, srv.CountRequests(http.MethodPatch), "clone never pushes")
+}
+
+func TestGistFlow_ExportImportThroughManifestGist(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n", "init.lua": "lua\n"})
+	notes := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, ".config", "nvim"), 0o755))
+	trackSynced(t, srv, sm, filepath.Join(home, ".config", "nvim"), "init.lua", id, "lua\n")
+	outside := srv.AddGist(map[string]string{"hosts": "127.0.0.1\n"})
+	trackSynced(t, srv, sm, t.TempDir(), "hosts", outside, "127.0.0.1\n")
+	fs := sm.Files[notes]
+	fs.Debounce, fs.Direction = "2s", state.DirectionPush
+	sm.Files[notes] = fs
+	require.NoError(t, sm.Save())
+
+	exportGist = true
+	t.Cleanup(func() { exportGist, importGist, importGistID, importPrune = false, false, "", false })
+	require.NoError(t, exportCmd.RunE(exportCmd, nil))
+	r, ok, err := manifest.ReadRemote(sm.ManifestGistPath())
+	require.NoError(t, err)
+	require.True(t, ok)
+	mg, _ := srv.Gist(r.GistID)
+	assert.Contains(t, mg.Files["automagist-manifest.yml"], "path: ~/.config/nvim/init.lua")
+	assert.NotContains(t, mg.Files["automagist-manifest.yml"], home, "paths are home-relative")
+	assert.NotContains(t, mg.Files["automagist-manifest.yml"], outside, "files outside home are left out")
+
+	// Another machine: a fresh home and config directory.
+	other := t.TempDir()
+	t.Setenv("HOME", other)
+	importGist, importGistID = true, r.GistID
+	require.NoError(t, importCmd.RunE(importCmd, nil))
+
+	sm2, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, sm2.Load())
+	require.Len(t, sm2.Files, 2)
+	got, err := os.ReadFile(filepath.Join(other, ".config", "nvim", "init.lua"))
+	require.NoError(t, err)
+	assert.Equal(t, "lua\n", string(got))
+	fs2 := sm2.Files[filepath.Join(other, "notes.md")]
+	assert.Equal(t, "2s", fs2.Debounce)
+	assert.Equal(t, state.DirectionPush, fs2.Direction)
+
+	// Later imports converge: settings follow the manifest and, with
+	// --prune, files it does not list stop being tracked.
+	require.NoError(t, os.WriteFile(filepath.Join(other, "extra.txt"), []byte("x\n"), 0o644))
+	_, err = sm2.UpdateFile(filepath.Join(other, "notes.md"), func(fs *state.FileState) { fs.Paused = true })
+	require.NoError(t, err)
+	require.NoError(t, sm2.Update(func(files map[string]state.FileState) error {
+		sm2.AddDirRule(state.DirRule{Dir: other, GistID: id, Include: []string{"*.txt"}})
+		sm2.AddTrackedFile(filepath.Join(other, "extra.txt"), id, 1)
+		return nil
+	}))
+	importGistID, importPrune = "", true
+	require.NoError(t, importCmd.RunE(importCmd, nil))
+	require.NoError(t, sm2.Load())
+	assert.False(t, sm2.Files[filepath.Join(other, "notes.md")].Paused)
+	assert.NotContains(t, sm2.Files, filepath.Join(other, "extra.txt"))
+	_, matches := sm2.RuleFor(filepath.Join(other, "extra.txt"))
+	assert.False(t, matches, "a pruned file is excluded from its directory rule")
+	assert.FileExists(t, filepath.Join(other, "extra.txt"))
+
+	assert.Equal(t, 1, srv.CountRequests(http.MethodPost), "only the manifest Gist is created")
+	assert.Zero(t, srv.CountRequests(http.MethodPatch), "import never pushes")
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/diff"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/pager"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyPage    int
+	historyPerPage int
+	historyDiff    bool
+	historyNoPager bool
+	historyOutput  jsonOutput
+)
+
+// revisionScanPage is how many revisions findRevision asks for at a time;
+// the most the API returns per page.
+const revisionScanPage = 100
+
+var historyCmd = &cobra.Command{
+	Use:   "history [path] [revision]",
+	Short: "List the revisions of a tracked file's Gist, or show what one changed",
+	Long: `Lists the revisions of the Gist a tracked file lives in, newest first:
+version SHA, commit time and the lines added and deleted across the Gist.
+
+With --diff, each listed revision is followed by the diff it made to this
+file. Pass a revision (a version SHA, or a prefix of one) to show just that
+revision's diff. Revisions that did not touch this file, or predate it under
+another name, say so instead.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		if err := sm.Load(); err != nil {
+			return err
+		}
+		absPath, err := filepath.Abs(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		fs, ok := sm.Files[absPath]
+		if !ok {
+			return fmt.Errorf("file not tracked: %s", absPath)
+		}
+		if historyPage < 1 || historyPerPage < 1 || historyPerPage > revisionScanPage {
+			return fmt.Errorf("--page must be at least 1 and --per-page between 1 and %d", revisionScanPage)
+		}
+		client := clientFor(newGistClients(), fs)
+		filename := fs.RemoteName(absPath)
+
+		if len(args) == 2 {
+			if historyOutput.enabled() || historyDiff {
+				return fmt.Errorf("a revision argument shows one diff; it cannot be combined with --diff, --json, --jq or --template")
+			}
+			rev, parent, err := findRevision(client, fs.GistID, args[1])
+			if err != nil {
+				return err
+			}
+			colorMode := colorForOutput(historyNoPager)
+			return pager.Run(historyNoPager, func(w io.Writer) error {
+				return writeRevisionDiff(w, client, fs.GistID, filename, *rev, parent, colorMode)
+			})
+		}
+
+		revs, more, err := client.Revisions(fs.GistID, historyPage, historyPerPage)
+		if err != nil {
+			return err
+		}
+		if historyOutput.enabled() {
+			if historyDiff {
+				return fmt.Errorf("--json, --jq and --template list revisions; they cannot be combined with --diff")
+			}
+			return historyOutput.write(cmd.OutOrStdout(), newHistoryJSON(absPath, fs, revs, more))
+		}
+		if !historyDiff {
+			printHistory(os.Stdout, absPath, fs, revs, more)
+			return nil
+		}
+
+		// The page's oldest revision is diffed against the first one of the
+		// next page.
+		var beyond *gist.Revision
+		if more {
+			next, _, err := client.Revisions(fs.GistID, historyPage*historyPerPage+1, 1)
+			if err != nil {
+				return err
+			}
+			if len(next) > 0 {
+				beyond = &next[0]
+			}
+		}
+		colorMode := colorForOutput(historyNoPager)
+		return pager.Run(historyNoPager, func(w io.Writer) error {
+			printHistory(w, absPath, fs, revs, more)
+			fmt.Fprintln(w)
+			for i, rev := range revs {
+				parent := beyond
+				if i+1 < len(revs) {
+					parent = &revs[i+1]
+				}
+				if err := writeRevisionDiff(w, client, fs.GistID, filename, rev, parent, colorMode); err != nil {
+					fmt.Fprintf(w, "Error: %v\n\n", err)
+				}
+			}
+			return nil
+		})
+	},
+}
+
+// printHistory lists one page of revisions, marking the one the file was
+// last synced with.
+func printHistory(w io.Writer, absPath string, fs state.FileState, revs []gist.Revision, more bool) {
+	fmt.Fprintf(w, "Revisions of %s (Gist %s, file %s), page %d:\n",
+		displayPath(absPath), truncateGistID(fs.GistID), fs.RemoteName(absPath), historyPage)
+	if len(revs) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, rev := range revs {
+		line := fmt.Sprintf("  %s  %s  +%d -%d", shortVersion(rev.Version),
+			time.Unix(rev.CommittedAt, 0).Format(time.RFC3339), rev.Additions, rev.Deletions)
+		if rev.CommittedAt == fs.RemoteUpdatedAt {
+			line += "  " + inSyncStyle.Render("← last synced")
+		}
+		fmt.Fprintln(w, line)
+	}
+	if more {
+		fmt.Fprintf(w, "More revisions: gh automagist history %s --page %d\n", displayPath(absPath), historyPage+1)
+	}
+}
+
+// writeRevisionDiff writes a header for rev and the diff it made to filename,
+// relative to parent (nil for the Gist's first revision).
+func writeRevisionDiff(w io.Writer, client *gist.Client, gistID, filename string, rev gist.Revision, parent *gist.Revision, mode diff.ColorMode) error {
+	fmt.Fprintf(w, "=== %s %s (+%d -%d) ===\n", shortVersion(rev.Version),
+		time.Unix(rev.CommittedAt, 0).Format(time.RFC3339), rev.Additions, rev.Deletions)
+
+	after, inRev, err := client.FetchFileAt(gistID, rev.Version, filename)
+	if err != nil {
+		return err
+	}
+	var before []byte
+	inParent := false
+	beforeName := "empty"
+	if parent != nil {
+		if before, inParent, err = client.FetchFileAt(gistID, parent.Version, filename); err != nil {
+			return err
+		}
+		beforeName = shortVersion(parent.Version)
+	}
+
+	switch {
+	case !inRev && !inParent:
+		fmt.Fprintf(w, "%s is not in this revision (added later, or under another name).\n\n", filename)
+		return nil
+	case inRev == inParent && bytes.Equal(before, after):
+		fmt.Fprintf(w, "No change to %s.\n\n", filename)
+		return nil
+	}
+	out, err := diffContents(beforeName, before, shortVersion(rev.Version), after, mode)
+	if err != nil {
+		return err
+	}
+	w.Write(out)
+	fmt.Fprintln(w)
+	return nil
+}
+
+// findRevision looks ref — a version SHA or a prefix of one — up in the
+// Gist's history and returns it along with the revision before it, nil for
+// the Gist's first. A prefix shared by several revisions is an error, as for
+// backup IDs, rather than a guess at which one was meant.
+func findRevision(client *gist.Client, gistID, ref string) (rev, parent *gist.Revision, err error) {
+	matches := 0
+	lastMatched := false // whether the revision just seen matched
+	for page := 1; ; page++ {
+		revs, more, err := client.Revisions(gistID, page, revisionScanPage)
+		if err != nil {
+			return nil, nil, err
+		}
+		for i := range revs {
+			if lastMatched {
+				parent, lastMatched = &revs[i], false
+				if rev.Version == ref {
+					return rev, parent, nil // a full SHA cannot be ambiguous
+				}
+			}
+			if strings.HasPrefix(revs[i].Version, ref) {
+				matches++
+				rev, parent, lastMatched = &revs[i], nil, true
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	switch matches {
+	case 0:
+		return nil, nil, fmt.Errorf("no revision %q in gist %s", ref, truncateGistID(gistID))
+	case 1:
+		return rev, parent, nil
+	default:
+		return nil, nil, fmt.Errorf("revision %q is ambiguous (%d matches)", ref, matches)
+	}
+}
+
+// shortVersion abbreviates a version SHA the way git abbreviates commits.
+func shortVersion(version string) string {
+	if len(version) > 7 {
+		return version[:7]
+	}
+	return version
+}
+
+// historyJSON is `history --json`.
+type historyJSON struct {
+	Path           string         `json:"path"`
+	GistID         string         `json:"gist_id"`
+	RemoteFilename string         `json:"remote_filename"`
+	Page           int            `json:"page"`
+	More           bool           `json:"more"`
+	Revisions      []revisionJSON `json:"revisions"`
+}
+
+// revisionJSON is a gist.Revision.
+type revisionJSON struct {
+	Version     string `json:"version"`
+	CommittedAt string `json:"committed_at"`
+	Additions   int    `json:"additions"`
+	Deletions   int    `json:"deletions"`
+	Total       int    `json:"total"`
+	LastSynced  bool   `json:"last_synced"`
+}
+
+func newHistoryJSON(absPath string, fs state.FileState, revs []gist.Revision, more bool) historyJSON {
+	out := historyJSON{
+		Path:           absPath,
+		GistID:         fs.GistID,
+		RemoteFilename: fs.RemoteName(absPath),
+		Page:           historyPage,
+		More:           more,
+		Revisions:      make([]revisionJSON, 0, len(revs)),
+	}
+	for _, rev := range revs {
+		out.Revisions = append(out.Revisions, revisionJSON{
+			Version:     rev.Version,
+			CommittedAt: jsonTime(rev.CommittedAt),
+			Additions:   rev.Additions,
+			Deletions:   rev.Deletions,
+			Total:       rev.Total,
+			LastSynced:  rev.CommittedAt == fs.RemoteUpdatedAt,
+		})
+	}
+	return out
+}
+
+func init() {
+	historyCmd.Flags().IntVar(&historyPage, "page", 1, "Page of revisions to list, newest first")
+	historyCmd.Flags().IntVar(&historyPerPage, "per-page", 30, "Revisions per page")
+	historyCmd.Flags().BoolVar(&historyDiff, "diff", false, "Show the diff each listed revision made to the file")
+	historyCmd.Flags().BoolVar(&historyNoPager, "no-pager", false, "Skip the pager even when stdout is a terminal")
+	addJSONFlags(historyCmd, &historyOutput)
+	rootCmd.AddCommand(historyCmd)
+}
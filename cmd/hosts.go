@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/notify"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// clientFor returns the client for fs's host and account.
+func clientFor(clients *gist.Clients, fs state.FileState) *gist.Client {
+	return clients.For(fs.Host, fs.Account)
+}
+
+// Set by --concurrency and --request-timeout on the commands that check
+// remotes; zero means notify's defaults.
+var (
+	detectWorkers int
+	detectTimeout time.Duration
+)
+
+// errInterrupted is returned by detect when Ctrl+C cut the checks short.
+var errInterrupted = errors.New("interrupted")
+
+// detect runs notify.DetectHosts with each file's own client. Ctrl+C while it
+// runs abandons the checks still in flight and returns errInterrupted along
+// with the partial result; once detect returns, Ctrl+C behaves as usual again.
+func detect(ctx context.Context, sm *state.Manager, clients *gist.Clients) ([]notify.FileStatus, error) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	statuses := notify.DetectHosts(ctx, sm, func(host, account string) notify.Fetcher {
+		return clients.For(host, account)
+	}, notify.Options{Workers: detectWorkers, Timeout: detectTimeout})
+	if ctx.Err() != nil {
+		return statuses, errInterrupted
+	}
+	return statuses, nil
+}
+
+// addDetectFlags registers --concurrency and --request-timeout on cmd.
+func addDetectFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(&detectWorkers, "concurrency", notify.DefaultWorkers,
+		"How many Gists to check for remote changes at once")
+	cmd.Flags().DurationVar(&detectTimeout, "request-timeout", notify.DefaultTimeout,
+		"Give up on a Gist's remote check after this long")
+}
+
+// hostLabel names host for output; empty means gh's default host.
+func hostLabel(host string) string {
+	if host == "" {
+		host, _ = auth.DefaultHost()
+	}
+	return host
+}
+
+// groupByHost buckets statuses by host label, keeping each bucket in input
+// order, and returns the labels sorted.
+func groupByHost(statuses []notify.FileStatus) (hosts []string, byHost map[string][]notify.FileStatus) {
+	byHost = make(map[string][]notify.FileStatus)
+	for _, s := range statuses {
+		label := hostLabel(s.Host)
+		if _, seen := byHost[label]; !seen {
+			hosts = append(hosts, label)
+		}
+		byHost[label] = append(byHost[label], s)
+	}
+	sort.Strings(hosts)
+	return hosts, byHost
+}
+
+// showHosts reports whether output should be split under host headings:
+// only when something is tracked outside gh's default host, so github.com-only
+// setups read as before.
+func showHosts(hosts []string) bool {
+	return len(hosts) > 1 || (len(hosts) == 1 && hosts[0] != hostLabel(""))
+}
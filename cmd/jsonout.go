@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/jq"
+	"github.com/cli/go-gh/v2/pkg/template"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/notify"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// jsonOutput holds the --json, --jq and --template flags the commands that
+// report state share. As with gh, --jq and --template filter the JSON; unlike gh,
+// they imply --json, which takes no field list.
+type jsonOutput struct {
+	json     bool
+	jq       string
+	template string
+}
+
+func addJSONFlags(cmd *cobra.Command, o *jsonOutput) {
+	cmd.Flags().BoolVar(&o.json, "json", false, "Output JSON")
+	cmd.Flags().StringVarP(&o.jq, "jq", "q", "", "Filter JSON output using a jq expression")
+	cmd.Flags().StringVarP(&o.template, "template", "t", "", "Format JSON output using a Go template; see \"gh help formatting\"")
+	cmd.MarkFlagsMutuallyExclusive("jq", "template")
+}
+
+func (o *jsonOutput) enabled() bool {
+	return o.json || o.jq != "" || o.template != ""
+}
+
+// write renders v as indented JSON, or through the jq expression or
+// template.
+func (o *jsonOutput) write(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode json: %w", err)
+	}
+	switch {
+	case o.jq != "":
+		return jq.Evaluate(bytes.NewReader(data), w, o.jq)
+	case o.template != "":
+		width := 80
+		if f, ok := w.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+			if cols, _, err := term.GetSize(int(f.Fd())); err == nil {
+				width = cols
+			}
+		}
+		t := template.New(w, width, false)
+		if err := t.Parse(o.template); err != nil {
+			return err
+		}
+		if err := t.Execute(bytes.NewReader(data)); err != nil {
+			return err
+		}
+		return t.Flush()
+	default:
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+}
+
+// exitStatus ends a command with a non-zero status and no error message.
+// JSON-mode status and fetch use it so scripts can branch without parsing:
+//
+//	0  every Gist checked, nothing newer remotely
+//	1  the command failed
+//	2  at least one file has newer remote content
+//	3  at least one Gist could not be checked
+type exitStatus int
+
+const (
+	exitRemoteNewer exitStatus = 2
+	exitCheckFailed exitStatus = 3
+)
+
+func (e exitStatus) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+// checkExitStatus is the exitStatus statuses call for, or nil. A failed
+// check wins over newer content: the answer for that Gist is unknown.
+func checkExitStatus(cmd *cobra.Command, statuses []notify.FileStatus) error {
+	code := exitStatus(0)
+	for _, s := range statuses {
+		switch {
+		case s.Err != nil:
+			code = exitCheckFailed
+		case s.RemoteNewer && code == 0:
+			code = exitRemoteNewer
+		}
+	}
+	if code == 0 {
+		return nil
+	}
+	cmd.SilenceErrors, cmd.SilenceUsage = true, true
+	return code
+}
+
+// exitCode maps an Execute error to the process exit status.
+func exitCode(err error) int {
+	var status exitStatus
+	if errors.As(err, &status) {
+		return int(status)
+	}
+	return 1
+}
+
+// jsonTime renders a unix timestamp as RFC 3339, or "" for unset.
+func jsonTime(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// fileJSON is a tracked file as list, status and fetch report it.
+type fileJSON struct {
+	Path            string `json:"path"`
+	GistID          string `json:"gist_id"`
+	RemoteFilename  string `json:"remote_filename"`
+	Host            string `json:"host,omitempty"`
+	Account         string `json:"account,omitempty"`
+	Dir             string `json:"dir,omitempty"`
+	Status          string `json:"status"`
+	UpdatedAt       string `json:"updated_at,omitempty"`
+	RemoteUpdatedAt string `json:"remote_updated_at,omitempty"`
+	ConflictAt      string `json:"conflict_at,omitempty"`
+	ConflictCopy    string `json:"conflict_copy,omitempty"`
+	Debounce        string `json:"debounce,omitempty"`
+	Direction       string `json:"direction"`
+	Paused          bool   `json:"paused"`
+}
+
+func newFileJSON(absPath string, fs state.FileState) fileJSON {
+	direction := fs.Direction
+	if direction == "" {
+		direction = state.DirectionBoth
+	}
+	return fileJSON{
+		Path:            absPath,
+		GistID:          fs.GistID,
+		RemoteFilename:  fs.RemoteName(absPath),
+		Host:            fs.Host,
+		Account:         fs.Account,
+		Dir:             fs.Dir,
+		Status:          fs.Status,
+		UpdatedAt:       jsonTime(fs.UpdatedAt),
+		RemoteUpdatedAt: jsonTime(fs.RemoteUpdatedAt),
+		ConflictAt:      jsonTime(fs.ConflictAt),
+		ConflictCopy:    fs.ConflictCopy,
+		Debounce:        fs.Debounce,
+		Direction:       direction,
+		Paused:          fs.Paused,
+	}
+}
+
+// checkJSON adds a remote check's result (notify.FileStatus) to fileJSON.
+// GistUpdatedAt is the Gist's latest commit; RemoteUpdatedAt is the one
+// last synced.
+type checkJSON struct {
+	fileJSON
+	RemoteNewer   bool   `json:"remote_newer"`
+	GistUpdatedAt string `json:"gist_updated_at,omitempty"`
+	Conflict      bool   `json:"conflict"`
+	Missing       bool   `json:"missing"`
+	Error         string `json:"error,omitempty"`
+}
+
+func newCheckJSONs(sm *state.Manager, statuses []notify.FileStatus) []checkJSON {
+	out := make([]checkJSON, 0, len(statuses))
+	for _, s := range statuses {
+		c := checkJSON{
+			fileJSON:      newFileJSON(s.Path, sm.Files[s.Path]),
+			RemoteNewer:   s.RemoteNewer,
+			GistUpdatedAt: jsonTime(s.RemoteUpdatedAt),
+			Conflict:      s.Conflict,
+			Missing:       s.Missing,
+		}
+		if s.Err != nil {
+			c.Error = s.Err.Error()
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// monitorJSON is the daemon's state plus its MonitorInfo.
+type monitorJSON struct {
+	State     string `json:"state"` // "running", "suspended" or "stopped"
+	PID       int    `json:"pid,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	StartedAt string `json:"started_at,omitempty"`
+}
+
+// rateLimitJSON is a gist.RateLimit.
+type rateLimitJSON struct {
+	Host      string `json:"host"`
+	Account   string `json:"account,omitempty"`
+	Limit     int    `json:"limit"`
+	Remaining int    `json:"remaining"`
+	Reset     string `json:"reset"`
+}
+
+func newRateLimitJSONs(limits []gist.RateLimit) []rateLimitJSON {
+	out := make([]rateLimitJSON, 0, len(limits))
+	for _, rl := range limits {
+		out = append(out, rateLimitJSON{
+			Host:      rl.Host,
+			Account:   rl.Account,
+			Limit:     rl.Limit,
+			Remaining: rl.Remaining,
+			Reset:     rl.Reset.UTC().Format(time.RFC3339),
+		})
+	}
+	return out
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/notify"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONOutput_Write(t *testing.T) {
+	v := []map[string]string{{"path": "/a"}, {"path": "/b"}}
+
+	var out bytes.Buffer
+	require.NoError(t, (&jsonOutput{json: true}).write(&out, v))
+	assert.Equal(t, "[\n  {\n    \"path\": \"/a\"\n  },\n  {\n    \"path\": \"/b\"\n  }\n]\n", out.String())
+
+	out.Reset()
+	require.NoError(t, (&jsonOutput{jq: ".[].path"}).write(&out, v))
+	assert.Equal(t, "/a\n/b\n", out.String())
+
+	out.Reset()
+	require.NoError(t, (&jsonOutput{template: `{{range .}}{{.path}};{{end}}`}).write(&out, v))
+	assert.Equal(t, "/a;/b;", out.String())
+
+	assert.Error(t, (&jsonOutput{jq: ".["}).write(&out, v))
+}
+
+func TestCheckExitStatus(t *testing.T) {
+	cmd := &cobra.Command{}
+	assert.NoError(t, checkExitStatus(cmd, []notify.FileStatus{{Path: "/a"}}))
+
+	err := checkExitStatus(cmd, []notify.FileStatus{{Path: "/a"}, {Path: "/b", RemoteNewer: true}})
+	assert.Equal(t, 2, exitCode(err))
+	assert.True(t, cmd.SilenceErrors, "the status is the message")
+
+	err = checkExitStatus(cmd, []notify.FileStatus{{Path: "/a", RemoteNewer: true}, {Path: "/b", Err: errors.New("boom")}})
+	assert.Equal(t, 3, exitCode(err), "an unchecked Gist outranks a newer one")
+
+	assert.Equal(t, 1, exitCode(errors.New("failed")))
+}
+
+func TestNewFileJSON(t *testing.T) {
+	f := newFileJSON("/p/notes.md", state.FileState{GistID: "g1", UpdatedAt: 86400, Status: state.StatusActive})
+	assert.Equal(t, "notes.md", f.RemoteFilename)
+	assert.Equal(t, "1970-01-02T00:00:00Z", f.UpdatedAt)
+	assert.Empty(t, f.RemoteUpdatedAt)
+	assert.Equal(t, state.DirectionBoth, f.Direction)
+}
+
+func TestGistFlow_FetchJSONReportsRemoteEdit(t *testing.T) {
+	srv, sm, home := newFakeGist(t)
+	id := srv.AddGist(map[string]string{"notes.md": "one\n"})
+	absPath := trackSynced(t, srv, sm, home, "notes.md", id, "one\n")
+	srv.SetFile(id, "notes.md", "two\n")
+
+	statuses, err := detect(context.Background(), sm, newGistClients())
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, (&jsonOutput{json: true}).write(&out, newCheckJSONs(sm, statuses)))
+
+	var got []map[string]any
+	require.NoError(t, json.Unmarshal(out.Bytes(), &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, absPath, got[0]["path"])
+	assert.Equal(t, id, got[0]["gist_id"])
+	assert.Equal(t, true, got[0]["remote_newer"])
+	assert.NotEmpty(t, got[0]["gist_updated_at"])
+	assert.Equal(t, 2, exitCode(checkExitStatus(&cobra.Command{}, statuses)))
+}
@@ -2,25 +2,64 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/charmbracelet/huh"
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
+var listOutput jsonOutput
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List currently monitored files",
+	Long: `Pick a tracked file to edit or view its Gist. When stdout is not a terminal,
+or with --json, --jq or --template, prints the tracked files instead.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if listOutput.enabled() || !term.IsTerminal(int(os.Stdout.Fd())) {
+			return runListPlain(cmd.OutOrStdout())
+		}
 		_, err := runListInteractive()
 		return err
 	},
 }
 
+// runListPlain prints every tracked file, sorted by path: as JSON when
+// asked, otherwise one "path<TAB>gist ID" line each.
+func runListPlain(w io.Writer) error {
+	sm, err := state.NewManager()
+	if err != nil {
+		return err
+	}
+	if err := sm.Load(); err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(sm.Files))
+	for path := range sm.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	if listOutput.enabled() {
+		files := make([]fileJSON, 0, len(paths))
+		for _, path := range paths {
+			files = append(files, newFileJSON(path, sm.Files[path]))
+		}
+		return listOutput.write(w, files)
+	}
+	for _, path := range paths {
+		fmt.Fprintf(w, "%s\t%s\n", path, sm.Files[path].GistID)
+	}
+	return nil
+}
+
 // runListInteractive shows the file list; returns backed=true if the user chose "← Back".
 func runListInteractive() (bool, error) {
 	sm, err := state.NewManager()
@@ -111,6 +150,9 @@ func runListInteractive() (bool, error) {
 				continue
 			case "view":
 				cm := exec.Command("gh", "gist", "view", gistID)
+				if host := sm.Files[path].Host; host != "" {
+					cm.Env = append(os.Environ(), "GH_HOST="+host)
+				}
 				cm.Stdin = os.Stdin
 				cm.Stdout = os.Stdout
 				cm.Stderr = os.Stderr
@@ -125,5 +167,6 @@ func runListInteractive() (bool, error) {
 }
 
 func init() {
+	addJSONFlags(listCmd, &listOutput)
 	rootCmd.AddCommand(listCmd)
 }
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/logfile"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsFollow bool
+	logsSince  string
+	logsLevel  string
+)
+
+// logsPollInterval is how often --follow checks the log for new lines.
+const logsPollInterval = 500 * time.Millisecond
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Show the monitor daemon's log",
+	Long: `Print the monitor's log file (including rotated files), oldest first.
+
+--since accepts a duration relative to now (e.g. 30m, 24h), an RFC3339
+timestamp, or a date (2006-01-02). --level hides lines below the given
+severity (info, warn, error). --follow keeps printing new lines as the
+daemon writes them; press Ctrl+C to stop.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseSince(logsSince, time.Now())
+		if err != nil {
+			return err
+		}
+		level, err := logfile.ParseLevel(logsLevel)
+		if err != nil {
+			return err
+		}
+
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+
+		filter := &logfile.Filter{Since: since, MinLevel: level}
+		files := logfile.Files(sm.LogPath())
+		if len(files) == 0 && !logsFollow {
+			fmt.Printf("No log file yet at %s. Start the monitor to create it.\n", displayPath(sm.LogPath()))
+			return nil
+		}
+		for _, path := range files {
+			f, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("failed to open log file: %w", err)
+			}
+			err = printFilteredLines(os.Stdout, f, filter)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+		if !logsFollow {
+			return nil
+		}
+		return followLog(os.Stdout, sm.LogPath(), filter)
+	},
+}
+
+// parseSince turns --since into an absolute cutoff. Empty means no cutoff.
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", raw, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: want a duration (30m), RFC3339 timestamp or date (2006-01-02)", raw)
+}
+
+func printFilteredLines(w io.Writer, r io.Reader, filter *logfile.Filter) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if filter.Match(line) {
+			fmt.Fprintln(w, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read log file: %w", err)
+	}
+	return nil
+}
+
+// followLog tails path from its current end, reopening it from the start
+// when the daemon rotates it (the file at path is replaced or shrinks).
+func followLog(w io.Writer, path string, filter *logfile.Filter) error {
+	var f *os.File
+	var reader *bufio.Reader
+	var offset int64
+	var partial strings.Builder
+	skipExisting := true
+	defer func() {
+		if f != nil {
+			f.Close()
+		}
+	}()
+
+	for {
+		if f == nil {
+			var err error
+			f, err = os.Open(path)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					return fmt.Errorf("failed to open log file: %w", err)
+				}
+				skipExisting = false // whatever appears later is all new
+				time.Sleep(logsPollInterval)
+				continue
+			}
+			offset = 0
+			if skipExisting {
+				// Everything up to now was printed before following began.
+				offset, _ = f.Seek(0, io.SeekEnd)
+				skipExisting = false
+			}
+			reader = bufio.NewReader(f)
+		}
+
+		chunk, err := reader.ReadString('\n')
+		offset += int64(len(chunk))
+		partial.WriteString(chunk)
+		if err == nil {
+			line := strings.TrimSuffix(partial.String(), "\n")
+			partial.Reset()
+			if filter.Match(line) {
+				fmt.Fprintln(w, line)
+			}
+			continue
+		}
+		if err != io.EOF {
+			return fmt.Errorf("failed to read log file: %w", err)
+		}
+
+		time.Sleep(logsPollInterval)
+		if rotated(f, path, offset) {
+			f.Close()
+			f = nil
+		}
+	}
+}
+
+// rotated reports whether the open file no longer backs path, or has been
+// truncated below what we have already read.
+func rotated(f *os.File, path string, offset int64) bool {
+	openInfo, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false // mid-rotation; try again next poll
+	}
+	return !os.SameFile(openInfo, pathInfo) || pathInfo.Size() < offset
+}
+
+func init() {
+	logsCmd.Flags().BoolVarP(&logsFollow, "follow", "f", false, "Keep printing new log lines as they are written")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Only show entries newer than a duration (e.g. 1h) or timestamp")
+	logsCmd.Flags().StringVar(&logsLevel, "level", "info", "Minimum severity to show: info, warn or error")
+	rootCmd.AddCommand(logsCmd)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSince_Duration(t *testing.T) {
+	now := time.Date(2026, 7, 10, 12, 0, 0, 0, time.UTC)
+	got, err := parseSince("90m", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-90*time.Minute), got)
+}
+
+func TestParseSince_RFC3339AndDate(t *testing.T) {
+	got, err := parseSince("2026-07-10T09:00:00Z", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 7, 10, 9, 0, 0, 0, time.UTC), got.UTC())
+
+	got, err = parseSince("2026-07-10", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 7, 10, 0, 0, 0, 0, time.Local), got)
+}
+
+func TestParseSince_EmptyMeansNoCutoff(t *testing.T) {
+	got, err := parseSince("", time.Now())
+	require.NoError(t, err)
+	assert.True(t, got.IsZero())
+}
+
+func TestParseSince_Invalid(t *testing.T) {
+	_, err := parseSince("yesterday", time.Now())
+	assert.Error(t, err)
+}
@@ -0,0 +1,390 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/cli/go-gh/v2/pkg/auth"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/manifest"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportOutput  string
+	exportFormat  string
+	exportGist    bool
+	exportGistID  string
+	exportHost    string
+	exportAccount string
+
+	importGist    bool
+	importGistID  string
+	importHost    string
+	importAccount string
+	importForce   bool
+	importPrune   bool
+)
+
+// manifestGistFilename is the name `export --gist` gives the manifest in a
+// new manifest Gist.
+const manifestGistFilename = "automagist-manifest"
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the tracked set as a portable manifest",
+	Long: `Print every tracked file as a manifest: its path relative to your home
+directory, Gist ID and filename, host and account, and its own debounce,
+direction and paused settings. Files from 'add --dir' are listed one by one.
+
+-o writes the manifest to a file (JSON for a .json name, YAML otherwise).
+--gist uploads it to a manifest Gist instead: the one given with --gist-id,
+else the one used last, else a new secret Gist. Teammates and other machines
+then follow it with 'gh automagist import --gist'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		if err := sm.Load(); err != nil {
+			return err
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		if exportGistID != "" && !exportGist {
+			return fmt.Errorf("--gist-id needs --gist")
+		}
+		m := buildManifest(sm, home, cmd.ErrOrStderr())
+
+		format := exportFormat
+		if !exportGist {
+			if format == "" {
+				format = manifest.FormatFor(exportOutput)
+			}
+			data, err := manifest.Encode(m, format)
+			if err != nil {
+				return err
+			}
+			if exportOutput == "" {
+				_, err = cmd.OutOrStdout().Write(data)
+				return err
+			}
+			if err := os.WriteFile(exportOutput, data, 0644); err != nil {
+				return fmt.Errorf("failed to write manifest: %w", err)
+			}
+			fmt.Printf("Exported %d file(s) to %s\n", len(m.Files), exportOutput)
+			return nil
+		}
+
+		r, known, err := manifestRemote(sm, exportGistID, exportHost, exportAccount)
+		if err != nil {
+			return err
+		}
+		client := newGistClients().For(r.Host, r.Account)
+		if known && r.Filename == "" {
+			files, _, err := client.FetchAllFiles(r.GistID)
+			if err != nil {
+				return err
+			}
+			r.Filename, _ = findManifestFile(files)
+		}
+		if r.Filename == "" {
+			if format == "" {
+				format = manifest.FormatYAML
+			}
+			r.Filename = manifestGistFilename + ".yml"
+			if format == manifest.FormatJSON {
+				r.Filename = manifestGistFilename + ".json"
+			}
+		} else if format == "" {
+			format = manifest.FormatFor(r.Filename)
+		}
+		data, err := manifest.Encode(m, format)
+		if err != nil {
+			return err
+		}
+
+		if known {
+			if _, err := client.UpdateFile(r.GistID, r.Filename, data); err != nil {
+				return fmt.Errorf("failed to update manifest Gist: %w", err)
+			}
+			fmt.Printf("Exported %d file(s) to manifest Gist %s (%s)\n", len(m.Files), r.GistID, r.Filename)
+		} else {
+			id, _, err := client.CreateGistFiles(map[string][]byte{r.Filename: data}, "Automagist manifest", false)
+			if err != nil {
+				return fmt.Errorf("failed to create manifest Gist: %w", err)
+			}
+			r.GistID = id
+			fmt.Printf("Exported %d file(s) to new manifest Gist %s\n", len(m.Files), id)
+			fmt.Printf("On other machines: gh automagist import --gist --gist-id %s\n", id)
+		}
+		return manifest.WriteRemote(sm.ManifestGistPath(), r)
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [manifest]",
+	Short: "Track the files a manifest lists, cloning the ones missing here",
+	Long: `Bring this machine in line with a manifest written by 'gh automagist
+export', read from a file or, with --gist, from the manifest Gist (--gist-id,
+or the one used last).
+
+Files not tracked yet are cloned as 'gh automagist clone --manifest' does:
+nothing is uploaded, and existing files with other content are skipped unless
+--force backs them up and replaces them. Files already tracked take the
+manifest's debounce, direction and paused settings. --prune stops tracking
+files the manifest does not list; their local copies and Gists are kept.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if importGist {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		if err := sm.Load(); err != nil {
+			return err
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to resolve home directory: %w", err)
+		}
+		if importGistID != "" && !importGist {
+			return fmt.Errorf("--gist-id needs --gist")
+		}
+		clients := newGistClients()
+
+		var m *manifest.Manifest
+		if importGist {
+			r, known, err := manifestRemote(sm, importGistID, importHost, importAccount)
+			if err != nil {
+				return err
+			}
+			if !known {
+				return fmt.Errorf("no manifest Gist yet; pass --gist-id, or create one with 'gh automagist export --gist'")
+			}
+			if m, r.Filename, err = fetchManifest(clients.For(r.Host, r.Account), r); err != nil {
+				return err
+			}
+			if err := manifest.WriteRemote(sm.ManifestGistPath(), r); err != nil {
+				return err
+			}
+		} else if m, err = manifest.Read(args[0]); err != nil {
+			return err
+		}
+
+		var toClone []cloneTarget
+		listed := make(map[string]bool, len(m.Files))
+		var updated, skipped int
+		for _, t := range manifestTargets(m, home) {
+			listed[t.absPath] = true
+			fs, ok := sm.Files[t.absPath]
+			if !ok {
+				toClone = append(toClone, t)
+				continue
+			}
+			if fs.GistID != t.gistID || fs.RemoteName(t.absPath) != t.filename {
+				fmt.Printf("\n-> %s\n  Skipped: tracked with %q in Gist %s; remove it first to follow the manifest\n",
+					displayPath(t.absPath), fs.RemoteName(t.absPath), truncateGistID(fs.GistID))
+				skipped++
+				continue
+			}
+			changed := false
+			if _, err := sm.UpdateFile(t.absPath, func(fs *state.FileState) {
+				changed = applyManifestSettings(fs, t.settings)
+			}); err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+			if changed {
+				fmt.Printf("\n-> %s\n  [Settings] %s\n", displayPath(t.absPath), settingsSummary(sm.Files[t.absPath]))
+				updated++
+			}
+		}
+
+		cloned, cloneSkipped, errored, err := cloneAll(sm, clients, toClone, nil, importForce)
+		if err != nil {
+			return err
+		}
+		skipped += cloneSkipped
+
+		var untracked int
+		if importPrune {
+			if untracked, err = pruneUnlisted(sm, listed); err != nil {
+				return err
+			}
+		}
+
+		fmt.Printf("\nImport complete: %d cloned, %d updated, %d untracked, %d skipped, %d error(s)\n",
+			cloned, updated, untracked, skipped, errored)
+		if cloned+updated+untracked > 0 && isMonitorRunning() {
+			fmt.Println("The running monitor picks up the changes automatically.")
+		}
+		return nil
+	},
+}
+
+// buildManifest lists every tracked file under home, sorted by path and made
+// home-relative. Files elsewhere cannot go into a manifest; each is named in
+// a warning on warn instead.
+func buildManifest(sm *state.Manager, home string, warn io.Writer) *manifest.Manifest {
+	paths := make([]string, 0, len(sm.Files))
+	for path := range sm.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	m := &manifest.Manifest{Version: manifest.CurrentVersion}
+	for _, path := range paths {
+		rel, ok := manifest.HomeRelative(path, home)
+		if !ok {
+			fmt.Fprintf(warn, "Warning: not exporting %s: it is outside your home directory\n", path)
+			continue
+		}
+		fs := sm.Files[path]
+		m.Files = append(m.Files, manifest.Entry{
+			Path:      rel,
+			GistID:    fs.GistID,
+			Filename:  fs.RemoteFilename,
+			Host:      fs.Host,
+			Account:   fs.Account,
+			Debounce:  fs.Debounce,
+			Direction: fs.Direction,
+			Paused:    fs.Paused,
+		})
+	}
+	return m
+}
+
+// manifestRemote is the manifest Gist to use: gistID on host/account when
+// given, else the remembered one. known is false when there is neither.
+func manifestRemote(sm *state.Manager, gistID, host, account string) (r manifest.Remote, known bool, err error) {
+	saved, ok, err := manifest.ReadRemote(sm.ManifestGistPath())
+	if err != nil {
+		return manifest.Remote{}, false, err
+	}
+	if gistID == "" {
+		if ok {
+			return saved, true, nil
+		}
+		return manifest.Remote{Host: auth.NormalizeHostname(host), Account: account}, false, nil
+	}
+	r = manifest.Remote{GistID: gistID, Host: auth.NormalizeHostname(host), Account: account}
+	if ok && saved.GistID == gistID && saved.Host == r.Host {
+		r.Filename = saved.Filename
+	}
+	return r, true, nil
+}
+
+// fetchManifest downloads and parses the manifest in r's Gist, returning the
+// filename it was found under.
+func fetchManifest(client *gist.Client, r manifest.Remote) (*manifest.Manifest, string, error) {
+	files, _, err := client.FetchAllFiles(r.GistID)
+	if err != nil {
+		return nil, "", err
+	}
+	name := r.Filename
+	if _, ok := files[name]; !ok {
+		if name, ok = findManifestFile(files); !ok {
+			return nil, "", fmt.Errorf("gist %s has no %s.yml or %s.json manifest", truncateGistID(r.GistID), manifestGistFilename, manifestGistFilename)
+		}
+	}
+	m, err := manifest.Parse(files[name])
+	if err != nil {
+		return nil, "", fmt.Errorf("%s in gist %s: %w", name, truncateGistID(r.GistID), err)
+	}
+	return m, name, nil
+}
+
+// findManifestFile picks the manifest among a Gist's files: one named
+// manifestGistFilename with a YAML or JSON extension, or the only file.
+func findManifestFile(files map[string][]byte) (string, bool) {
+	for _, ext := range []string{".yml", ".yaml", ".json"} {
+		if _, ok := files[manifestGistFilename+ext]; ok {
+			return manifestGistFilename + ext, true
+		}
+	}
+	if len(files) == 1 {
+		for name := range files {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// pruneUnlisted stops tracking every file not in listed, leaving the files
+// and their Gists alone. A file a directory rule matches is also excluded
+// from the rule, as remove does, so the monitor does not adopt it again.
+func pruneUnlisted(sm *state.Manager, listed map[string]bool) (int, error) {
+	var removed []string
+	excluded := make(map[string]string)
+	err := sm.Update(func(files map[string]state.FileState) error {
+		removed = removed[:0]
+		clear(excluded)
+		for path := range files {
+			if !listed[path] {
+				delete(files, path)
+				removed = append(removed, path)
+				if dir := sm.ExcludeFromRule(path); dir != "" {
+					excluded[path] = dir
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save state: %w", err)
+	}
+	sort.Strings(removed)
+	for _, path := range removed {
+		fmt.Printf("\n-> %s\n  [Untrack] not in the manifest\n", displayPath(path))
+		if dir, ok := excluded[path]; ok {
+			fmt.Printf("  [Untrack] excluded from the rule for %s\n", displayPath(dir))
+		}
+	}
+	return len(removed), nil
+}
+
+// settingsSummary describes fs's sync settings for import's output.
+func settingsSummary(fs state.FileState) string {
+	direction := fs.Direction
+	if direction == "" {
+		direction = state.DirectionBoth
+	}
+	s := "direction " + direction
+	if d, ok := fs.DebounceInterval(); ok {
+		s += ", debounce " + d.String()
+	}
+	if fs.Paused {
+		s += ", paused"
+	}
+	return s
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "Write the manifest to this file instead of stdout")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "", "Manifest format: yaml or json (default: from the file name, else yaml)")
+	exportCmd.Flags().BoolVar(&exportGist, "gist", false, "Upload the manifest to the manifest Gist, creating one if needed")
+	exportCmd.Flags().StringVar(&exportGistID, "gist-id", "", "With --gist: the manifest Gist to upload to")
+	exportCmd.Flags().StringVar(&exportHost, "hostname", "", "With --gist: GitHub host of the manifest Gist (default: gh's default host)")
+	exportCmd.Flags().StringVar(&exportAccount, "account", "", "With --gist: account to use when several are logged in to the host")
+	exportCmd.MarkFlagsMutuallyExclusive("output", "gist")
+
+	importCmd.Flags().BoolVar(&importGist, "gist", false, "Read the manifest from the manifest Gist")
+	importCmd.Flags().StringVar(&importGistID, "gist-id", "", "With --gist: the manifest Gist to read (remembered for next time)")
+	importCmd.Flags().StringVar(&importHost, "hostname", "", "With --gist: GitHub host of the manifest Gist (default: gh's default host)")
+	importCmd.Flags().StringVar(&importAccount, "account", "", "With --gist: account to use when several are logged in to the host")
+	importCmd.Flags().BoolVar(&importForce, "force", false, "Replace existing files with other content, after backing them up")
+	importCmd.Flags().BoolVar(&importPrune, "prune", false, "Stop tracking files the manifest does not list")
+
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/merge"
+	"github.com/noriyo_tcp/gh-automagist/pkg/objstore"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+)
+
+// basePruneAge keeps unreferenced base copies around for a while, so one
+// just stored by another process but not yet recorded in state.json survives.
+const basePruneAge = time.Hour
+
+func baseStore(sm *state.Manager) *objstore.Store {
+	return objstore.New(sm.ObjectsDir())
+}
+
+// rememberBase stores content as the version local and remote last agreed
+// on; the caller records its SHA in FileState.ContentSHA.
+func rememberBase(sm *state.Manager, content []byte) error {
+	_, err := baseStore(sm).Put(content)
+	return err
+}
+
+// pruneBases drops stored copies no tracked file references any more.
+func pruneBases(sm *state.Manager) {
+	keep := make(map[string]bool, len(sm.Files))
+	for _, fs := range sm.Files {
+		if fs.ContentSHA != "" {
+			keep[fs.ContentSHA] = true
+		}
+	}
+	if _, err := baseStore(sm).Prune(keep, basePruneAge); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// mergeFile is pullFile's --merge path for a file changed on both sides: a
+// three-way merge against the last-synced copy. A clean result is uploaded
+// and then written locally; a conflicted one is written with markers and the
+// file is marked conflicted, so the monitor holds off until they are gone.
+func mergeFile(sm *state.Manager, client *gist.Client, absPath string, localContent, remoteContent []byte, remoteUpdatedAt int64, perm os.FileMode) pullStatus {
+	fs := sm.Files[absPath]
+	if fs.ContentSHA == "" {
+		fmt.Println("  LOCAL AHEAD, and no last-synced copy to merge against — use --force to overwrite")
+		return pullStatusBlocked
+	}
+	base, err := baseStore(sm).Get(fs.ContentSHA)
+	if errors.Is(err, objstore.ErrNotFound) {
+		fmt.Println("  LOCAL AHEAD, and the last-synced copy is no longer stored — use --force to overwrite")
+		return pullStatusBlocked
+	}
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return pullStatusError
+	}
+
+	result := merge.ThreeWay(base, localContent, remoteContent, "local", "gist "+fs.GistID)
+	if result.Conflicts == 0 {
+		fmt.Println("  Merge:  clean — local and remote changes combined")
+	} else {
+		fmt.Printf("  Merge:  %d conflict(s) — markers will be written to the file\n", result.Conflicts)
+	}
+
+	if pullDryRun {
+		fmt.Println("  Dry-run: no write performed.")
+		return pullStatusSkipped
+	}
+	if ok, status := confirmPull("Proceed with backup and merge?"); !ok {
+		return status
+	}
+
+	if result.Conflicts == 0 {
+		updatedAt, err := client.UpdateFile(fs.GistID, fs.RemoteName(absPath), result.Content)
+		if err != nil {
+			fmt.Printf("  Error uploading merge: %v\n", err)
+			return pullStatusError
+		}
+		effective := monitorDebounce(sm)
+		backupID, _, err := applyRemote(sm, absPath, localContent, result.Content,
+			updatedAt, perm, pullBackupReason("merge"), effective)
+		if backupID != "" {
+			fmt.Printf("  [Backup] %s\n", backupID)
+		}
+		if err != nil {
+			fmt.Printf("  Error: %v\n", err)
+			return pullStatusError
+		}
+		fmt.Printf("  [Merge] %d bytes written and uploaded\n", len(result.Content))
+		return pullStatusPulled
+	}
+
+	if !pullNoBackup {
+		e, err := takeBackup(sm, absPath, localContent, "merge")
+		if err != nil {
+			fmt.Printf("  Error creating backup: %v\n", err)
+			return pullStatusError
+		}
+		fmt.Printf("  [Backup] %s\n", e.ID)
+	}
+
+	// The remote becomes the new base: once the markers are resolved, the
+	// result descends from it and pushes without a further conflict. Saved
+	// before the write so the monitor sees ConflictAt and holds the push.
+	if err := rememberBase(sm, remoteContent); err != nil {
+		fmt.Printf("  Error saving base copy: %v\n", err)
+		return pullStatusError
+	}
+	_, err = sm.UpdateFile(absPath, func(fs *state.FileState) {
+		fs.ConflictAt = time.Now().Unix()
+		fs.ConflictCopy = ""
+		fs.ContentSHA = sha256Hex(remoteContent)
+		fs.RemoteUpdatedAt = remoteUpdatedAt
+		fs.PullSuppressUntil = 0
+	})
+	if err != nil {
+		fmt.Printf("  Error saving conflict marker: %v\n", err)
+		return pullStatusError
+	}
+	if err := writeAtomic(absPath, result.Content, perm); err != nil {
+		fmt.Printf("  Error: %v\n", err)
+		return pullStatusError
+	}
+	if _, err := sm.UpdateFile(absPath, func(fs *state.FileState) { fs.UpdatedAt = time.Now().Unix() }); err != nil {
+		fmt.Printf("  Warning: failed to save state: %v\n", err)
+	}
+	fmt.Printf("  [Conflict] Resolve the <<<<<<< / >>>>>>> regions in %s and save; the monitor syncs it once they are gone\n",
+		displayPath(absPath))
+	return pullStatusBlocked
+}
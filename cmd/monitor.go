@@ -2,15 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/logfile"
 	"github.com/noriyo_tcp/gh-automagist/pkg/monitor"
+	"github.com/noriyo_tcp/gh-automagist/pkg/outbox"
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/spf13/cobra"
 )
@@ -32,6 +34,11 @@ var monitorCmd = &cobra.Command{
 			return nil
 		}
 
+		policy, err := parseConflictPolicy(onConflictFlag)
+		if err != nil {
+			return err
+		}
+
 		// --daemon: re-launch self without the flag as a detached background process
 		if daemonMode {
 			binary, err := os.Executable()
@@ -44,6 +51,18 @@ var monitorCmd = &cobra.Command{
 			if cmd.Flags().Changed("debounce") {
 				childArgs = append(childArgs, "--debounce", debounceInterval.String())
 			}
+			if cmd.Flags().Changed("on-conflict") {
+				childArgs = append(childArgs, "--on-conflict", string(policy))
+			}
+			if cmd.Flags().Changed("pull-interval") {
+				childArgs = append(childArgs, "--pull-interval", pullInterval.String())
+			}
+			if propagateRenames {
+				childArgs = append(childArgs, "--propagate-renames")
+			}
+			if propagateDeletes {
+				childArgs = append(childArgs, "--propagate-deletes")
+			}
 			child := exec.Command(binary, childArgs...)
 			child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
 			child.Stdin = nil
@@ -77,16 +96,30 @@ var monitorCmd = &cobra.Command{
 			return fmt.Errorf("failed to initialize state manager: %w", err)
 		}
 
+		// The --daemon child has no stdout/stderr, so the log file is the
+		// only record of what it did. Foreground runs tee to both.
+		logWriter, err := logfile.NewRotatingWriter(sm.LogPath(), logfile.DefaultMaxBytes, logfile.DefaultKeep)
+		if err != nil {
+			log.Printf("Warning: failed to open log file, logging to stderr only: %v", err)
+		} else {
+			defer logWriter.Close()
+			log.SetFlags(log.LstdFlags) // `gh automagist logs` parses this prefix
+			log.SetOutput(io.MultiWriter(logWriter, os.Stderr))
+			defer log.SetOutput(os.Stderr)
+		}
+
 		err = sm.Load()
 		if err != nil {
 			return fmt.Errorf("failed to load state.json: %w", err)
 		}
 
-		if len(sm.Files) == 0 {
+		if len(sm.Files) == 0 && len(sm.Dirs) == 0 {
+			// Keep running: the watcher follows state.json, so files added
+			// later are picked up without a restart.
 			fmt.Println("No files are currently configured for monitoring.")
 			fmt.Println("Use 'gh automagist add' to start tracking files.")
-			return nil
 		}
+		pruneBases(sm)
 
 		// 2. Initialize the file watcher
 		watcher, err := monitor.NewWatcher(sm)
@@ -111,43 +144,32 @@ var monitorCmd = &cobra.Command{
 			log.Printf("[gh-automagist] debounce disabled (every write triggers immediate sync)")
 		}
 
-		// 3. Initialize the GitHub API Client
-		gistClient := gist.NewClient()
-
-		// 4. Hook up the watcher's OnChange callback to trigger the Gist upload
-		watcher.OnChange = func(absPath string, gistID string) {
-			content, err := os.ReadFile(absPath)
-			if err != nil {
-				log.Printf("Error reading file %s: %v", absPath, err)
-				return
-			}
-
-			// Re-check the on-disk state right before deciding: pull may have
-			// written PullSuppressUntil after the event-loop reload.
-			if err := sm.Load(); err != nil {
-				log.Printf("Warning: failed to reload state.json before suppression check: %v", err)
-			}
-			fs := sm.Files[absPath]
-			currentSHA := sha256Hex(content)
-			if monitor.ShouldSuppress(fs, currentSHA, time.Now().Unix()) {
-				log.Printf("  [Suppressed] %s matches pull baseline; skipping redundant PATCH", filepath.Base(absPath))
-				fs.PullSuppressUntil = 0
-				sm.Files[absPath] = fs
-				if err := sm.Save(); err != nil {
-					log.Printf("  Warning: failed to clear pull_suppress_until: %v", err)
-				}
-				return
-			}
-
-			log.Printf("  -> Uploading %s to Gist %s...", filepath.Base(absPath), gistID)
+		// 3. Initialize the GitHub API Client and the retry outbox
+		clients := newGistClients()
+		box, err := outbox.Open(sm.OutboxPath())
+		if err != nil {
+			return fmt.Errorf("failed to open outbox: %w", err)
+		}
 
-			err = gistClient.UpdateFile(gistID, absPath, content)
-			if err != nil {
-				log.Printf("  [Error] Failed to update gist: %v", err)
-			} else {
-				log.Printf("  [Success] Gist updated successfully.")
-			}
+		// 4. Hook up the watcher's OnBatch callback to trigger the Gist upload
+		p := &pusher{sm: sm, clients: clients, outbox: box, onConflict: policy}
+		watcher.OnBatch = p.syncBatch
+		if policy != conflictOverwrite {
+			log.Printf("[gh-automagist] conflict policy: %s", policy)
 		}
+		prop := &propagator{sm: sm, clients: clients, renames: propagateRenames, deletes: propagateDeletes}
+		watcher.OnRename = prop.renamed
+		watcher.OnMissing = prop.missing
+
+		// `stop`/`restart` send SIGTERM and wait; Ctrl+C arrives as SIGINT.
+		// Either way, route through watcher.Stop() so edits still inside the
+		// debounce window are uploaded before the deferred cleanup runs.
+		// Registered before the PID file exists: `stop` may signal as soon
+		// as it can read the PID, and the default action would kill us
+		// without flushing or removing the PID file.
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+		defer signal.Stop(sigCh)
 
 		// 5. Start the blocking event loop
 		if err := sm.WritePID(); err != nil {
@@ -158,14 +180,57 @@ var monitorCmd = &cobra.Command{
 			Version:   Version,
 			Commit:    Commit,
 			StartedAt: time.Now().Unix(),
+			Debounce:  effective.String(),
 		}); err != nil {
 			log.Printf("Warning: failed to write monitor info: %v", err)
 		}
 		defer sm.DeletePID()
 		defer sm.DeleteMonitorInfo()
 
-		fmt.Printf("Monitoring %d files. Press Ctrl+C to stop.\n", len(sm.Files))
-		return watcher.Start()
+		// Retries run beside the event loop; uploads that fail during the
+		// shutdown flush stay in the outbox for the next start.
+		retryStop := make(chan struct{})
+		retryDone := make(chan struct{})
+		go func() {
+			defer close(retryDone)
+			p.runRetryLoop(retryStop)
+		}()
+		defer func() {
+			close(retryStop)
+			<-retryDone
+		}()
+
+		if pullInterval > 0 {
+			log.Printf("[gh-automagist] pulling remote changes every %s", pullInterval)
+			// The poller reloads state on its own goroutine, so it gets
+			// its own Manager; both coordinate through the state lock.
+			pl := &puller{sm: state.NewManagerAt(sm.ConfigDir()), clients: clients, watcher: watcher, outbox: box, debounce: effective}
+			pullStop := make(chan struct{})
+			pullDone := make(chan struct{})
+			go func() {
+				defer close(pullDone)
+				pl.run(pullInterval, pullStop)
+			}()
+			defer func() {
+				close(pullStop)
+				<-pullDone
+			}()
+		}
+
+		errCh := make(chan error, 1)
+		go func() { errCh <- watcher.Start() }()
+
+		fmt.Printf("Monitoring %d files. Press Ctrl+C to stop.\n", len(sm.Snapshot().Files))
+		select {
+		case sig := <-sigCh:
+			log.Printf("[gh-automagist] Received %s, flushing pending syncs...", sig)
+			watcher.Stop()
+			<-errCh
+			log.Printf("[gh-automagist] Shutdown complete.")
+			return nil
+		case err := <-errCh:
+			return err
+		}
 	},
 }
 
@@ -174,5 +239,13 @@ func init() {
 	monitorCmd.Flags().DurationVar(&debounceInterval, "debounce", 0,
 		"Quiet-window between the last write and the Gist sync (e.g. 5s, 500ms, 0 to disable). "+
 			"Overrides "+debounceEnvVar+" env var and the compiled-in default.")
+	monitorCmd.Flags().DurationVar(&pullInterval, "pull-interval", 0,
+		"Poll tracked Gists this often and apply newer remote content when there are no unsynced local edits (0 disables)")
+	monitorCmd.Flags().StringVar(&onConflictFlag, "on-conflict", string(conflictOverwrite),
+		"What to do when the Gist changed since the last sync: overwrite, skip, backup-remote or merge")
+	monitorCmd.Flags().BoolVar(&propagateRenames, "propagate-renames", false,
+		"Rename the file in its Gist when the local file is renamed (default: keep the Gist filename)")
+	monitorCmd.Flags().BoolVar(&propagateDeletes, "propagate-deletes", false,
+		"Delete the file from its Gist and stop tracking it when the local file is deleted (default: mark it missing)")
 	rootCmd.AddCommand(monitorCmd)
 }
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+)
+
+// Environment variables choosing the config directory. --config-dir is
+// exported as configDirEnvVar, so a daemon a command starts uses the same
+// directory.
+const (
+	configDirEnvVar = "GH_AUTOMAGIST_CONFIG_DIR"
+	profileEnvVar   = "GH_AUTOMAGIST_PROFILE"
+)
+
+var configDirFlag string
+
+// resolveConfigDir picks the config directory: --config-dir, then
+// GH_AUTOMAGIST_CONFIG_DIR, then GH_AUTOMAGIST_PROFILE's directory under
+// root. Empty means state.ConfigRoot itself.
+func resolveConfigDir(flagValue, envDir, profile, root string) (string, error) {
+	dir := flagValue
+	if dir == "" {
+		dir = envDir
+	}
+	if dir != "" {
+		return filepath.Abs(dir)
+	}
+	if profile == "" {
+		return "", nil
+	}
+	dir, err := state.ProfileDir(root, profile)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", profileEnvVar, err)
+	}
+	return dir, nil
+}
+
+// applyConfigDir points state.NewManager at the chosen directory.
+func applyConfigDir() error {
+	root, err := state.ConfigRoot()
+	if err != nil {
+		return err
+	}
+	dir, err := resolveConfigDir(configDirFlag, os.Getenv(configDirEnvVar), os.Getenv(profileEnvVar), root)
+	if err != nil || dir == "" {
+		return err
+	}
+	state.DefaultConfigDir = dir
+	return os.Setenv(configDirEnvVar, dir)
+}
+
+// profileStatus is one profile's monitor as status reports it.
+type profileStatus struct {
+	state.Profile
+	PID     int
+	Current bool
+}
+
+// runningProfiles returns the profiles under state.ConfigRoot whose monitor
+// is alive, marking the one this invocation uses.
+func runningProfiles(current string) ([]profileStatus, error) {
+	root, err := state.ConfigRoot()
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := state.Profiles(root)
+	if err != nil {
+		return nil, err
+	}
+	var running []profileStatus
+	for _, p := range profiles {
+		pid := state.NewManagerAt(p.Dir).GetPID()
+		if pid == 0 || !pidAlive(pid) {
+			continue
+		}
+		running = append(running, profileStatus{Profile: p, PID: pid, Current: p.Dir == current})
+	}
+	return running, nil
+}
+
+// printProfiles lists running monitors once more than one profile is in
+// play; a single default profile prints nothing.
+func printProfiles(w io.Writer, running []profileStatus) {
+	if len(running) == 0 || (len(running) == 1 && running[0].Name == state.DefaultProfile && running[0].Current) {
+		return
+	}
+	fmt.Fprintf(w, "Running Profiles (%d):\n", len(running))
+	for _, p := range running {
+		mark := " "
+		if p.Current {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s %s (PID: %d, %s)\n", mark, p.Name, p.PID, displayPath(p.Dir))
+	}
+	fmt.Fprintln(w)
+}
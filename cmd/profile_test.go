@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveConfigDir_Precedence(t *testing.T) {
+	dir, err := resolveConfigDir("/flag", "/env", "work", "/root")
+	require.NoError(t, err)
+	assert.Equal(t, "/flag", dir)
+
+	dir, err = resolveConfigDir("", "/env", "work", "/root")
+	require.NoError(t, err)
+	assert.Equal(t, "/env", dir)
+
+	dir, err = resolveConfigDir("", "", "work", "/root")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join("/root", "profiles", "work"), dir)
+
+	dir, err = resolveConfigDir("", "", "", "/root")
+	require.NoError(t, err)
+	assert.Empty(t, dir, "nothing chosen leaves state.ConfigRoot in charge")
+}
+
+func TestResolveConfigDir_RelativeFlagIsMadeAbsolute(t *testing.T) {
+	dir, err := resolveConfigDir("cfg", "", "", "/root")
+	require.NoError(t, err)
+	assert.True(t, filepath.IsAbs(dir))
+}
+
+func TestResolveConfigDir_BadProfile(t *testing.T) {
+	_, err := resolveConfigDir("", "", "../x", "/root")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), profileEnvVar)
+}
+
+func TestPrintProfiles(t *testing.T) {
+	var out bytes.Buffer
+	printProfiles(&out, []profileStatus{
+		{Profile: state.Profile{Name: state.DefaultProfile, Dir: "/cfg"}, PID: 10, Current: true},
+	})
+	assert.Empty(t, out.String(), "only the default profile running needs no listing")
+
+	printProfiles(&out, []profileStatus{
+		{Profile: state.Profile{Name: state.DefaultProfile, Dir: "/cfg"}, PID: 10},
+		{Profile: state.Profile{Name: "work", Dir: "/cfg/profiles/work"}, PID: 20, Current: true},
+	})
+	assert.Contains(t, out.String(), "Running Profiles (2):")
+	assert.Contains(t, out.String(), "  default (PID: 10, /cfg)")
+	assert.Contains(t, out.String(), "* work (PID: 20, /cfg/profiles/work)")
+}
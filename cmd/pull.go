@@ -21,6 +21,7 @@ var (
 	pullYes      bool
 	pullDryRun   bool
 	pullNoBackup bool
+	pullMerge    bool
 )
 
 var pullCmd = &cobra.Command{
@@ -59,10 +60,10 @@ If [path] is omitted, all tracked files are processed.`,
 			sort.Strings(targets)
 		}
 
-		gistClient := gist.NewClient()
+		clients := newGistClients()
 		var pulled, skipped, blocked, errored int
 		for _, path := range targets {
-			switch pullFile(sm, gistClient, path) {
+			switch pullFile(sm, clients, path) {
 			case pullStatusPulled:
 				pulled++
 			case pullStatusSkipped:
@@ -77,9 +78,7 @@ If [path] is omitted, all tracked files are processed.`,
 		fmt.Printf("\nPull complete: %d pulled, %d skipped, %d blocked, %d error(s)\n",
 			pulled, skipped, blocked, errored)
 
-		if err := sm.Save(); err != nil {
-			return fmt.Errorf("failed to save state: %w", err)
-		}
+		pruneBases(sm)
 		return nil
 	},
 }
@@ -93,12 +92,25 @@ const (
 	pullStatusError
 )
 
-func pullFile(sm *state.Manager, client *gist.Client, absPath string) pullStatus {
+func pullFile(sm *state.Manager, clients *gist.Clients, absPath string) pullStatus {
 	fs := sm.Files[absPath]
+	client := clientFor(clients, fs)
 	fmt.Printf("\n-> %s\n", displayPath(absPath))
 
-	filename := filepath.Base(absPath)
-	remoteContent, remoteUpdatedAt, err := client.FetchFile(fs.GistID, filename)
+	if fs.Status == state.StatusMissing {
+		fmt.Println("  Skipped: missing locally (deleted or moved) — restore it or 'gh automagist remove' it")
+		return pullStatusSkipped
+	}
+	if fs.Paused {
+		fmt.Println("  Skipped: paused — 'gh automagist resume' it first")
+		return pullStatusSkipped
+	}
+	if !fs.Pulls() {
+		fmt.Println("  Skipped: push-only")
+		return pullStatusSkipped
+	}
+
+	remoteContent, remoteUpdatedAt, err := client.FetchFile(fs.GistID, fs.RemoteName(absPath))
 	if err != nil {
 		fmt.Printf("  Error: %v\n", err)
 		return pullStatusError
@@ -121,20 +133,38 @@ func pullFile(sm *state.Manager, client *gist.Client, absPath string) pullStatus
 	localSHA := sha256Hex(localContent)
 	if remoteSHA == localSHA {
 		fmt.Println("  Skipped: content identical (in sync)")
-		fs.RemoteUpdatedAt = remoteUpdatedAt
-		fs.ContentSHA = remoteSHA
-		sm.Files[absPath] = fs
+		if err := rememberBase(sm, remoteContent); err != nil {
+			fmt.Printf("  Warning: failed to store base copy: %v\n", err)
+		}
+		if _, err := sm.UpdateFile(absPath, func(fs *state.FileState) {
+			fs.RemoteUpdatedAt = remoteUpdatedAt
+			fs.ContentSHA = remoteSHA
+			fs.ClearConflict()
+		}); err != nil {
+			fmt.Printf("  Warning: failed to save state: %v\n", err)
+		}
 		return pullStatusSkipped
 	}
 
-	// Check (a): local mtime ahead of last recorded sync — signals unsynced local edit
+	// Check (a): local mtime ahead of last recorded sync — signals unsynced
+	// local edit. So does a recorded conflict: the monitor held the edit
+	// back, and the event loop had already bumped UpdatedAt past its mtime.
 	localInfo, err := os.Stat(absPath)
 	if err != nil {
 		fmt.Printf("  Error stat'ing local file: %v\n", err)
 		return pullStatusError
 	}
 	localMtime := localInfo.ModTime().Unix()
-	if localMtime > fs.UpdatedAt && !pullForce {
+	unsynced := localMtime > fs.UpdatedAt || fs.ConflictAt != 0
+	if unsynced && pullMerge && !pullForce {
+		return mergeFile(sm, client, absPath, localContent, remoteContent, remoteUpdatedAt, localInfo.Mode().Perm())
+	}
+	if fs.ConflictAt != 0 && !pullForce {
+		fmt.Printf("  CONFLICT (recorded %s) — use --merge to merge or --force to overwrite\n",
+			time.Unix(fs.ConflictAt, 0).Format(time.RFC3339))
+		return pullStatusBlocked
+	}
+	if unsynced && !pullForce {
 		fmt.Printf("  LOCAL AHEAD (mtime %s > last-sync %s) — use --force to overwrite\n",
 			time.Unix(localMtime, 0).Format(time.RFC3339),
 			time.Unix(fs.UpdatedAt, 0).Format(time.RFC3339))
@@ -153,59 +183,22 @@ func pullFile(sm *state.Manager, client *gist.Client, absPath string) pullStatus
 		return pullStatusSkipped
 	}
 
-	if !pullYes {
-		if !term.IsTerminal(int(os.Stdin.Fd())) {
-			fmt.Println("  stdin is not a tty — pass --yes to overwrite non-interactively.")
-			return pullStatusBlocked
-		}
-		fmt.Print("  Proceed with backup and overwrite? [Y/n]: ")
-		var response string
-		_, _ = fmt.Scanln(&response)
-		response = strings.TrimSpace(strings.ToLower(response))
-		if response != "" && response != "y" && response != "yes" {
-			fmt.Println("  Skipped by user.")
-			return pullStatusSkipped
-		}
-	}
-
-	if !pullNoBackup {
-		backupPath := fmt.Sprintf("%s.bak.%s", absPath, time.Now().Format("20060102-150405"))
-		if err := os.WriteFile(backupPath, localContent, localInfo.Mode().Perm()); err != nil {
-			fmt.Printf("  Error creating backup: %v\n", err)
-			return pullStatusError
-		}
-		fmt.Printf("  [Backup] %s\n", displayPath(backupPath))
-	}
-
-	// Must Save() before the rename below; the daemon reacts to the fsnotify
-	// write and needs to see the marker before it decides on the PATCH.
-	effective, _ := resolveDebounce(false, 0, os.Getenv(debounceEnvVar))
-	suppressUntil := time.Now().Add(effective + pullSuppressGrace).Unix()
-	fs.PullSuppressUntil = suppressUntil
-	fs.ContentSHA = remoteSHA
-	sm.Files[absPath] = fs
-	if err := sm.Save(); err != nil {
-		fmt.Printf("  Error saving suppression marker: %v\n", err)
-		return pullStatusError
+	if ok, status := confirmPull("Proceed with backup and overwrite?"); !ok {
+		return status
 	}
 
-	// Atomic write: <path>.pull.tmp then rename over the original.
-	tmpPath := absPath + ".pull.tmp"
-	if err := os.WriteFile(tmpPath, remoteContent, localInfo.Mode().Perm()); err != nil {
-		fmt.Printf("  Error writing tmp: %v\n", err)
-		return pullStatusError
+	effective := monitorDebounce(sm)
+	backupID, suppressUntil, err := applyRemote(sm, absPath, localContent, remoteContent,
+		remoteUpdatedAt, localInfo.Mode().Perm(), pullBackupReason("pull"), effective)
+	if backupID != "" {
+		fmt.Printf("  [Backup] %s\n", backupID)
 	}
-	if err := os.Rename(tmpPath, absPath); err != nil {
-		_ = os.Remove(tmpPath)
-		fmt.Printf("  Error renaming into place: %v\n", err)
+	if err != nil {
+		fmt.Printf("  Error: %v\n", err)
 		return pullStatusError
 	}
 	fmt.Printf("  [Write] %d bytes written atomically\n", len(remoteContent))
 
-	fs.UpdatedAt = time.Now().Unix()
-	fs.RemoteUpdatedAt = remoteUpdatedAt
-	sm.Files[absPath] = fs
-
 	if isMonitorRunning() {
 		fmt.Printf("  Note: PATCH will be suppressed until %s (SHA + window match).\n",
 			time.Unix(suppressUntil, 0).Format(time.RFC3339))
@@ -214,9 +207,113 @@ func pullFile(sm *state.Manager, client *gist.Client, absPath string) pullStatus
 	return pullStatusPulled
 }
 
+// pullBackupReason is reason, or empty under --no-backup.
+func pullBackupReason(reason string) string {
+	if pullNoBackup {
+		return ""
+	}
+	return reason
+}
+
+// confirmPull asks question on the terminal unless --yes was given. When the
+// answer is no, status is what the file should count as.
+func confirmPull(question string) (ok bool, status pullStatus) {
+	if pullYes {
+		return true, pullStatusPulled
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println("  stdin is not a tty — pass --yes to proceed non-interactively.")
+		return false, pullStatusBlocked
+	}
+	if !askYes(question) {
+		fmt.Println("  Skipped by user.")
+		return false, pullStatusSkipped
+	}
+	return true, pullStatusPulled
+}
+
+// askYes asks question on the terminal; an empty answer counts as yes.
+func askYes(question string) bool {
+	fmt.Printf("  %s [Y/n]: ", question)
+	var response string
+	_, _ = fmt.Scanln(&response)
+	response = strings.TrimSpace(strings.ToLower(response))
+	return response == "" || response == "y" || response == "yes"
+}
+
+// applyRemote replaces absPath with remoteContent the way every pull path
+// must: optional backup of the local copy, then the suppression marker, then
+// an atomic write, then the sync bookkeeping, each state change written
+// through sm.UpdateFile. backupReason is recorded with the backup; empty
+// skips it. debounce is the monitor's interval, used to size the suppression
+// window unless the file has a Debounce of its own. Shared by `pull` and the monitor's --pull-interval loop.
+func applyRemote(sm *state.Manager, absPath string, localContent, remoteContent []byte, remoteUpdatedAt int64, perm os.FileMode, backupReason string, debounce time.Duration) (backupID string, suppressUntil int64, err error) {
+	if backupReason != "" {
+		e, err := takeBackup(sm, absPath, localContent, backupReason)
+		if err != nil {
+			return "", 0, fmt.Errorf("creating backup: %w", err)
+		}
+		backupID = e.ID
+	}
+
+	if err := rememberBase(sm, remoteContent); err != nil {
+		return backupID, 0, fmt.Errorf("saving base copy: %w", err)
+	}
+
+	// Must be on disk before the rename below; the daemon reacts to the
+	// fsnotify write and needs to see the marker before it decides on the PATCH.
+	_, err = sm.UpdateFile(absPath, func(fs *state.FileState) {
+		suppressUntil = suppressDeadline(*fs, debounce, time.Now())
+		fs.PullSuppressUntil, fs.PullSuppressSHA = suppressUntil, ""
+		fs.ContentSHA = sha256Hex(remoteContent)
+	})
+	if err != nil {
+		return backupID, 0, fmt.Errorf("saving suppression marker: %w", err)
+	}
+
+	if err := writeAtomic(absPath, remoteContent, perm); err != nil {
+		return backupID, 0, err
+	}
+
+	_, err = sm.UpdateFile(absPath, func(fs *state.FileState) {
+		fs.UpdatedAt = time.Now().Unix()
+		fs.RemoteUpdatedAt = remoteUpdatedAt
+		fs.ClearConflict()
+	})
+	if err != nil {
+		return backupID, suppressUntil, fmt.Errorf("recording sync: %w", err)
+	}
+	return backupID, suppressUntil, nil
+}
+
+// writeAtomic writes <path>.pull.tmp then renames it over the original.
+func writeAtomic(absPath string, content []byte, perm os.FileMode) error {
+	tmpPath := absPath + ".pull.tmp"
+	if err := os.WriteFile(tmpPath, content, perm); err != nil {
+		return fmt.Errorf("writing tmp: %w", err)
+	}
+	if err := os.Rename(tmpPath, absPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("renaming into place: %w", err)
+	}
+	return nil
+}
+
 // pullSuppressGrace absorbs fsnotify jitter and the pull-Save → daemon-Load gap.
 const pullSuppressGrace = 2 * time.Second
 
+// suppressDeadline is when the suppression marker for a write at now should
+// expire: after the debounce the monitor will wait for this file (its own
+// Debounce when set, else debounce) plus pullSuppressGrace. A shorter window
+// would let the sync fire after the marker is gone and push the content
+// straight back.
+func suppressDeadline(fs state.FileState, debounce time.Duration, now time.Time) int64 {
+	if d, ok := fs.DebounceInterval(); ok {
+		debounce = d
+	}
+	return now.Add(debounce + pullSuppressGrace).Unix()
+}
+
 func sha256Hex(content []byte) string {
 	h := sha256.Sum256(content)
 	return hex.EncodeToString(h[:])
@@ -260,6 +357,7 @@ func init() {
 	pullCmd.Flags().BoolVar(&pullForce, "force", false, "Overwrite even if local mtime is newer than last sync")
 	pullCmd.Flags().BoolVarP(&pullYes, "yes", "y", false, "Skip the confirmation prompt")
 	pullCmd.Flags().BoolVar(&pullDryRun, "dry-run", false, "Show what would happen without writing")
-	pullCmd.Flags().BoolVar(&pullNoBackup, "no-backup", false, "Skip creating the .bak file")
+	pullCmd.Flags().BoolVar(&pullNoBackup, "no-backup", false, "Skip backing up the local copy")
+	pullCmd.Flags().BoolVar(&pullMerge, "merge", false, "Three-way merge files changed both locally and on the Gist")
 	rootCmd.AddCommand(pullCmd)
 }
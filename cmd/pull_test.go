@@ -2,9 +2,13 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLineDiffSummary_Identical(t *testing.T) {
@@ -69,3 +73,166 @@ func TestSHA256Hex_Deterministic(t *testing.T) {
 func TestSHA256Hex_DifferentInputsDiffer(t *testing.T) {
 	assert.NotEqual(t, sha256Hex([]byte("hello")), sha256Hex([]byte("world")))
 }
+
+func TestApplyRemote_BacksUpArmsSuppressionAndWrites(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	target := filepath.Join(tempHome, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("local\n"), 0600))
+	sm.AddTrackedFile(target, "gist1", 100)
+	sm.Files[target] = state.FileState{GistID: "gist1", UpdatedAt: 100, Status: "active", ConflictAt: 90}
+	require.NoError(t, sm.Save())
+
+	before := time.Now()
+	backupID, suppressUntil, err := applyRemote(sm, target, []byte("local\n"), []byte("remote\n"),
+		500, 0600, "pull", 5*time.Second)
+	require.NoError(t, err)
+
+	got, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "remote\n", string(got))
+
+	e, err := backupStore(sm).Get(backupID)
+	require.NoError(t, err)
+	assert.Equal(t, target, e.Path)
+	assert.Equal(t, "gist1", e.GistID)
+	assert.Equal(t, "pull", e.Reason)
+	backup, err := backupStore(sm).Content(e)
+	require.NoError(t, err)
+	assert.Equal(t, "local\n", string(backup))
+	matches, _ := filepath.Glob(target + ".bak.*")
+	assert.Empty(t, matches, "nothing is left next to the file")
+
+	assert.GreaterOrEqual(t, suppressUntil, before.Add(5*time.Second).Unix())
+	fs := sm.Files[target]
+	assert.Equal(t, suppressUntil, fs.PullSuppressUntil)
+	assert.Equal(t, sha256Hex([]byte("remote\n")), fs.ContentSHA)
+	assert.Equal(t, int64(500), fs.RemoteUpdatedAt)
+	assert.Zero(t, fs.ConflictAt, "applying the remote resolves any recorded conflict")
+
+	// The marker must already be on disk for the daemon to see it.
+	onDisk, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, onDisk.Load())
+	assert.Equal(t, suppressUntil, onDisk.Files[target].PullSuppressUntil)
+}
+
+func TestApplyRemote_SuppressesForPerFileDebounce(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	target := filepath.Join(tempHome, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("local\n"), 0600))
+	sm.Files[target] = state.FileState{GistID: "gist1", UpdatedAt: 100, Status: "active", Debounce: "1m"}
+	require.NoError(t, sm.Save())
+
+	before := time.Now()
+	_, suppressUntil, err := applyRemote(sm, target, []byte("local\n"), []byte("remote\n"),
+		500, 0600, "", 5*time.Second)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, suppressUntil, before.Add(time.Minute+pullSuppressGrace).Unix(),
+		"the marker outlasts the file's own debounce, not just the monitor's")
+	assert.Equal(t, suppressUntil, sm.Files[target].PullSuppressUntil)
+}
+
+func TestApplyRemote_NoBackup(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	target := filepath.Join(tempHome, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("local\n"), 0644))
+	sm.AddTrackedFile(target, "gist1", 100)
+
+	backupID, _, err := applyRemote(sm, target, []byte("local\n"), []byte("remote\n"), 500, 0644, "", 0)
+	require.NoError(t, err)
+	assert.Empty(t, backupID)
+
+	entries, err := backupStore(sm).List()
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestApplyRemote_StoresBaseForMerge(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	target := filepath.Join(tempHome, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("local\n"), 0600))
+	sm.AddTrackedFile(target, "gist1", 100)
+	require.NoError(t, sm.Save())
+
+	_, _, err = applyRemote(sm, target, []byte("local\n"), []byte("remote\n"), 500, 0600, "", 0)
+	require.NoError(t, err)
+
+	base, err := baseStore(sm).Get(sm.Files[target].ContentSHA)
+	require.NoError(t, err)
+	assert.Equal(t, "remote\n", string(base))
+}
+
+func TestMergeFile_ConflictWritesMarkersAndMarksState(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	pullYes, pullNoBackup = true, true
+	t.Cleanup(func() { pullYes, pullNoBackup = false, false })
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	target := filepath.Join(tempHome, "notes.md")
+	local := []byte("a\nlocal\nc\n")
+	remote := []byte("a\nremote\nc\n")
+	require.NoError(t, os.WriteFile(target, local, 0600))
+	require.NoError(t, rememberBase(sm, []byte("a\nb\nc\n")))
+	sm.Files[target] = state.FileState{GistID: "gist1", UpdatedAt: 100, Status: "active",
+		ContentSHA: sha256Hex([]byte("a\nb\nc\n"))}
+	require.NoError(t, sm.Save())
+
+	// The conflicted path never talks to the Gist, so no client is needed.
+	status := mergeFile(sm, nil, target, local, remote, 500, 0600)
+	assert.Equal(t, pullStatusBlocked, status)
+
+	got, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Contains(t, string(got), "<<<<<<< local\nlocal\n=======\nremote\n>>>>>>> gist gist1\n")
+
+	fs := sm.Files[target]
+	assert.NotZero(t, fs.ConflictAt)
+	assert.Equal(t, sha256Hex(remote), fs.ContentSHA, "remote becomes the base for the resolved push")
+	assert.Equal(t, int64(500), fs.RemoteUpdatedAt)
+	base, err := baseStore(sm).Get(fs.ContentSHA)
+	require.NoError(t, err)
+	assert.Equal(t, string(remote), string(base))
+}
+
+func TestMergeFile_BlocksWithoutStoredBase(t *testing.T) {
+	tempHome := t.TempDir()
+	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	target := filepath.Join(tempHome, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("local\n"), 0600))
+	sm.Files[target] = state.FileState{GistID: "gist1", UpdatedAt: 100, Status: "active",
+		ContentSHA: sha256Hex([]byte("gone\n"))}
+
+	status := mergeFile(sm, nil, target, []byte("local\n"), []byte("remote\n"), 500, 0600)
+	assert.Equal(t, pullStatusBlocked, status)
+
+	got, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "local\n", string(got))
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"log"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/merge"
+	"github.com/noriyo_tcp/gh-automagist/pkg/monitor"
+	"github.com/noriyo_tcp/gh-automagist/pkg/outbox"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+)
+
+// outboxPollInterval is how often the monitor checks the outbox for retries
+// whose backoff has elapsed. Backoff itself starts at outbox.BaseBackoff, so
+// this only bounds how late a retry can run, not how often the API is hit.
+const outboxPollInterval = 5 * time.Second
+
+// pusher uploads local edits to their Gists on behalf of the monitor. Failed
+// uploads are parked in the outbox and retried with backoff until they go
+// through or the file stops being tracked.
+type pusher struct {
+	sm         *state.Manager
+	clients    *gist.Clients
+	outbox     *outbox.Outbox
+	onConflict conflictPolicy
+}
+
+// sync is the watcher's OnChange: push once, and queue for retry on failure.
+func (p *pusher) sync(absPath, gistID string) {
+	p.syncBatch(gistID, []string{absPath})
+}
+
+// syncBatch is the watcher's OnBatch: every path belongs to gistID and goes
+// up in one PATCH. On failure each uploaded path is queued for retry.
+func (p *pusher) syncBatch(gistID string, absPaths []string) {
+	shas, err := p.push(gistID, absPaths)
+	if err == nil {
+		for _, absPath := range absPaths {
+			if rmErr := p.outbox.Remove(absPath); rmErr != nil {
+				log.Printf("  Warning: failed to update outbox: %v", rmErr)
+			}
+		}
+		return
+	}
+
+	log.Printf("  [Error] Failed to update gist: %v", err)
+	for absPath, sha := range shas {
+		entry, qErr := p.outbox.RecordFailure(absPath, gistID, sha, err, time.Now(), rand.Float64())
+		if qErr != nil {
+			log.Printf("  Warning: failed to queue %s for retry: %v", filepath.Base(absPath), qErr)
+			continue
+		}
+		log.Printf("  [Retry] %s queued (attempt %d); next try at %s",
+			filepath.Base(absPath), entry.Attempts, time.Unix(entry.NextAttemptAt, 0).Format(time.TimeOnly))
+	}
+}
+
+// pendingUpload is one file push decided to send.
+type pendingUpload struct {
+	absPath    string
+	name       string
+	content    []byte
+	sha        string
+	conflicted bool
+}
+
+// push uploads the current content of every path that is not a pull echo,
+// in a single PATCH. It returns the SHA of the content it tried to upload per
+// path, and a non-nil error only when the upload itself failed — the one case
+// worth retrying.
+func (p *pusher) push(gistID string, absPaths []string) (map[string]string, error) {
+	// Re-check the on-disk state right before deciding: pull may have
+	// written PullSuppressUntil after the event-loop reload.
+	snap := p.snapshot("suppression check")
+
+	var uploads []pendingUpload
+	var checkErr error
+	shas := make(map[string]string, len(absPaths))
+	for _, absPath := range absPaths {
+		u, err := p.prepare(absPath, gistID, snap.Files[absPath])
+		if err != nil {
+			// A failed conflict check is an API failure like any other;
+			// the whole batch is retried together.
+			checkErr = err
+			shas[absPath] = u.sha
+			continue
+		}
+		if u.absPath != "" {
+			uploads = append(uploads, u)
+			shas[absPath] = u.sha
+		}
+	}
+	if checkErr != nil {
+		return shas, checkErr
+	}
+	if len(uploads) == 0 {
+		return shas, nil
+	}
+
+	files := make(map[string][]byte, len(uploads))
+	names := make([]string, 0, len(uploads))
+	for _, u := range uploads {
+		files[u.name] = u.content
+		names = append(names, u.name)
+	}
+	log.Printf("  -> Uploading %s to Gist %s...", strings.Join(names, ", "), gistID)
+
+	updatedAt, err := clientFor(p.clients, snap.Files[uploads[0].absPath]).UpdateFiles(gistID, files)
+	if err != nil {
+		return shas, err
+	}
+	log.Printf("  [Success] Gist updated successfully.")
+	for _, u := range uploads {
+		p.recordSynced(u.absPath, u.content, updatedAt, !u.conflicted)
+	}
+	return shas, nil
+}
+
+// prepare decides whether absPath, whose state is fs, should be uploaded. A
+// result without absPath means skip: unreadable, a pull echo, unresolved
+// conflict markers, or held back by the conflict policy.
+func (p *pusher) prepare(absPath, gistID string, fs state.FileState) (pendingUpload, error) {
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		log.Printf("Error reading file %s: %v", absPath, err)
+		return pendingUpload{}, nil
+	}
+
+	currentSHA := sha256Hex(content)
+	if monitor.ShouldSuppress(fs, currentSHA, time.Now().Unix()) {
+		log.Printf("  [Suppressed] %s matches pull baseline; skipping redundant PATCH", filepath.Base(absPath))
+		if _, err := p.sm.UpdateFile(absPath, func(fs *state.FileState) {
+			fs.PullSuppressUntil, fs.PullSuppressSHA = 0, ""
+		}); err != nil {
+			log.Printf("  Warning: failed to clear pull_suppress_until: %v", err)
+		}
+		return pendingUpload{sha: currentSHA}, nil
+	}
+
+	if fs.ConflictAt != 0 && merge.HasConflictMarkers(content) {
+		log.Printf("  [Conflict] %s still has conflict markers; resolve them and save to sync", filepath.Base(absPath))
+		return pendingUpload{sha: currentSHA}, nil
+	}
+
+	conflicted := false
+	if p.onConflict != conflictOverwrite && fs.RemoteUpdatedAt != 0 {
+		// RemoteUpdatedAt == 0 means we never observed the remote (e.g. a
+		// Gist created by `add`), so there is no baseline to conflict with.
+		var proceed bool
+		proceed, conflicted, err = p.checkConflict(absPath, gistID, fs, currentSHA)
+		if err != nil || !proceed {
+			return pendingUpload{sha: currentSHA}, err
+		}
+	}
+
+	return pendingUpload{
+		absPath:    absPath,
+		name:       fs.RemoteName(absPath),
+		content:    content,
+		sha:        currentSHA,
+		conflicted: conflicted,
+	}, nil
+}
+
+// retry re-pushes outbox entries. With all=false only entries whose backoff
+// has elapsed are tried; all=true drains everything, as on daemon startup.
+// Entries for files that are no longer tracked are dropped; those of paused
+// or pull-only files wait.
+func (p *pusher) retry(all bool) {
+	entries := p.outbox.Due(time.Now())
+	if all {
+		entries = p.outbox.Entries()
+	}
+	if len(entries) == 0 {
+		return
+	}
+	snap := p.snapshot("retry")
+	for absPath, e := range entries {
+		fs, tracked := snap.Files[absPath]
+		if !tracked {
+			log.Printf("[Retry] Dropping %s: no longer tracked", absPath)
+			_ = p.outbox.Remove(absPath)
+			continue
+		}
+		if !fs.Pushes() {
+			continue // kept queued until the file is resumed
+		}
+		log.Printf("[Retry] %s (previous attempts: %d)", filepath.Base(absPath), e.Attempts)
+		p.sync(absPath, fs.GistID)
+	}
+}
+
+// snapshot reads state.json as it is on disk now. push and retry run off the
+// event loop, so they must not Load into the Manager the watcher shares; if
+// the read fails they fall back to what the Manager last saw.
+func (p *pusher) snapshot(purpose string) state.Snapshot {
+	snap, err := p.sm.ReadSnapshot()
+	if err != nil {
+		log.Printf("Warning: failed to reload state.json before %s: %v", purpose, err)
+		return p.sm.Snapshot()
+	}
+	return snap
+}
+
+// runRetryLoop drains the outbox once, then retries due entries every
+// outboxPollInterval until stop is closed.
+func (p *pusher) runRetryLoop(stop <-chan struct{}) {
+	p.retry(true)
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			p.retry(false)
+		case <-stop:
+			return
+		}
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 var removeCmd = &cobra.Command{
 	Use:   "remove [path]",
-	Short: "Remove a file from monitoring",
+	Short: "Remove a file, or a directory added with --dir, from monitoring",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path := args[0]
@@ -27,18 +27,47 @@ var removeCmd = &cobra.Command{
 			return err
 		}
 
+		if _, isDir := sm.Dirs[absPath]; isDir {
+			var removed []string
+			err := sm.Update(func(files map[string]state.FileState) error {
+				delete(sm.Dirs, absPath)
+				removed = state.UntrackDir(files, absPath)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save state: %w", err)
+			}
+			fmt.Printf("Removed directory %s and its %d file(s) from monitor.\n", absPath, len(removed))
+			if isMonitorRunning() {
+				fmt.Println("The running monitor will stop watching it automatically.")
+			}
+			return nil
+		}
+
 		if _, exists := sm.Files[absPath]; !exists {
 			fmt.Printf("File not monitored: %s\n", path)
 			return nil
 		}
 
-		sm.RemoveTrackedFile(absPath)
-		if err := sm.Save(); err != nil {
+		// A file a directory rule matches would be adopted again on its
+		// next change, so the rule gets an --exclude for it.
+		var ruleDir string
+		err = sm.Update(func(files map[string]state.FileState) error {
+			delete(files, absPath)
+			ruleDir = sm.ExcludeFromRule(absPath)
+			return nil
+		})
+		if err != nil {
 			return fmt.Errorf("failed to save state: %w", err)
 		}
 
 		fmt.Printf("Removed %s from monitor.\n", absPath)
-		fmt.Println("Note: If 'gh-automagist monitor' is running, run 'gh automagist restart'.")
+		if ruleDir != "" {
+			fmt.Printf("Excluded it from the rule for %s, so it is not tracked again.\n", ruleDir)
+		}
+		if isMonitorRunning() {
+			fmt.Println("The running monitor will stop watching it automatically.")
+		}
 		return nil
 	},
 }
@@ -11,7 +11,7 @@ var restartForeground bool
 
 var restartCmd = &cobra.Command{
 	Use:   "restart",
-	Short: "Restart the monitor to pick up the current state.json. Runs as a daemon by default",
+	Short: "Restart the monitor, e.g. after upgrading or to change --debounce. Runs as a daemon by default",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sm, err := state.NewManager()
 		if err != nil {
@@ -22,13 +22,16 @@ var restartCmd = &cobra.Command{
 		}
 
 		if pid := sm.GetPID(); pid != 0 {
-			killed, err := sm.KillMonitor(pid)
+			result, err := sm.StopMonitor(pid, stopTimeout)
 			if err != nil {
 				return err
 			}
-			if killed {
+			switch result {
+			case state.StopGraceful:
 				fmt.Printf("Stopped monitor (PID: %d)\n", pid)
-			} else {
+			case state.StopKilled:
+				fmt.Printf("Monitor did not exit within %s; killed (PID: %d)\n", stopTimeout, pid)
+			default:
 				fmt.Printf("Note: monitor was not actually running (stale PID file for %d, cleaned up)\n", pid)
 			}
 		} else {
@@ -45,5 +48,15 @@ func init() {
 	restartCmd.Flags().DurationVar(&debounceInterval, "debounce", 0,
 		"Quiet-window between the last write and the Gist sync (e.g. 5s, 500ms, 0 to disable). "+
 			"Overrides "+debounceEnvVar+" env var and the compiled-in default.")
+	restartCmd.Flags().DurationVar(&pullInterval, "pull-interval", 0,
+		"Poll tracked Gists this often and apply newer remote content when there are no unsynced local edits (0 disables)")
+	restartCmd.Flags().StringVar(&onConflictFlag, "on-conflict", string(conflictOverwrite),
+		"What to do when the Gist changed since the last sync: overwrite, skip, backup-remote or merge")
+	restartCmd.Flags().BoolVar(&propagateRenames, "propagate-renames", false,
+		"Rename the file in its Gist when the local file is renamed (default: keep the Gist filename)")
+	restartCmd.Flags().BoolVar(&propagateDeletes, "propagate-deletes", false,
+		"Delete the file from its Gist and stop tracking it when the local file is deleted (default: mark it missing)")
+	restartCmd.Flags().DurationVar(&stopTimeout, "timeout", defaultStopTimeout,
+		"How long to wait for the old monitor to flush pending syncs before force-killing it")
 	rootCmd.AddCommand(restartCmd)
 }
@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/backup"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	restoreRevision string
+	restoreBackup   string
+	restorePush     bool
+	restoreYes      bool
+	restoreDryRun   bool
+	restoreNoBackup bool
+)
+
+// legacyBackupLayout is the timestamp earlier releases put in the
+// <path>.bak.<ts> files pull left next to the file.
+const legacyBackupLayout = "20060102-150405"
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore [path]",
+	Short: "Restore a tracked file from an earlier Gist revision or a backup",
+	Long: `Replace a tracked file with its content at an earlier Gist revision
+(--revision, a version SHA or prefix as listed by 'gh automagist history') or
+with a backup (--backup, an ID or prefix as listed by 'gh automagist backups
+list'; the timestamp after ".bak." also finds backups older releases left
+next to the file).
+
+Shows the diff from the current content first and asks before writing. The
+current content is backed up unless --no-backup is given. The running monitor
+does not upload the restored file; pass --push to upload it as a new revision
+now, or it goes up with the next save.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sm, err := state.NewManager()
+		if err != nil {
+			return err
+		}
+		if err := sm.Load(); err != nil {
+			return err
+		}
+		absPath, err := filepath.Abs(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to resolve path: %w", err)
+		}
+		fs, ok := sm.Files[absPath]
+		if !ok {
+			return fmt.Errorf("file not tracked: %s", absPath)
+		}
+		if restorePush && !fs.Pushes() {
+			return fmt.Errorf("%s is paused or pull-only; resume it or drop --push", displayPath(absPath))
+		}
+		client := clientFor(newGistClients(), fs)
+
+		var content []byte
+		var source string
+		if restoreRevision != "" {
+			content, source, err = revisionContent(client, fs.GistID, fs.RemoteName(absPath), restoreRevision)
+		} else {
+			content, source, err = backupContent(sm, absPath, restoreBackup)
+		}
+		if err != nil {
+			return err
+		}
+		return restoreFile(sm, client, absPath, content, source)
+	},
+}
+
+// restoreFile shows the diff from absPath's current content to content and,
+// once confirmed, writes it the way pull writes remote content: backup,
+// suppression marker so the monitor does not upload it, atomic write. With
+// --push the content is uploaded first and recorded as in sync; without, it
+// is a local edit the Gist has not seen, and the sync state stays at the
+// last synced remote.
+func restoreFile(sm *state.Manager, client *gist.Client, absPath string, content []byte, source string) error {
+	fs := sm.Files[absPath]
+	localContent, err := os.ReadFile(absPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", absPath, err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("-> %s\n", displayPath(absPath))
+	fmt.Printf("  Source: %s\n", source)
+	if bytes.Equal(localContent, content) {
+		fmt.Println("  Already matches; nothing to restore.")
+		return nil
+	}
+	added, removed := lineDiffSummary(localContent, content)
+	fmt.Printf("  Diff:   +%d lines, -%d lines\n\n", added, removed)
+	out, err := diffContents("current", localContent, "restored", content, colorForOutput(false))
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(out)
+	fmt.Println()
+
+	if restoreDryRun {
+		fmt.Println("  Dry-run: no write performed.")
+		return nil
+	}
+	if !restoreYes {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return fmt.Errorf("stdin is not a tty — pass --yes to restore non-interactively")
+		}
+		if !askYes("Restore this content?") {
+			fmt.Println("  Skipped by user.")
+			return nil
+		}
+	}
+
+	// Pushing first means a failed upload leaves the file untouched, and
+	// the write below records the revision it created as the one in sync.
+	remoteUpdatedAt := fs.RemoteUpdatedAt
+	if restorePush {
+		if remoteUpdatedAt, err = client.UpdateFile(fs.GistID, fs.RemoteName(absPath), content); err != nil {
+			return fmt.Errorf("failed to push restored content: %w", err)
+		}
+		fmt.Printf("  [Push] new revision at %s\n", time.Unix(remoteUpdatedAt, 0).Format(time.RFC3339))
+	}
+
+	effective := monitorDebounce(sm)
+	backupReason := "restore"
+	if restoreNoBackup {
+		backupReason = ""
+	}
+	var backupID string
+	if restorePush {
+		backupID, _, err = applyRemote(sm, absPath, localContent, content,
+			remoteUpdatedAt, info.Mode().Perm(), backupReason, effective)
+	} else {
+		backupID, err = applyLocal(sm, absPath, localContent, content,
+			info.Mode().Perm(), backupReason, effective)
+	}
+	if backupID != "" {
+		fmt.Printf("  [Backup] %s\n", backupID)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  [Write] %d bytes written atomically\n", len(content))
+	if !restorePush {
+		fmt.Println("  Not pushed; the restored content goes up with the next save, or rerun with --push.")
+	}
+	return nil
+}
+
+// applyLocal writes content the Gist does not have over absPath: the backup
+// and suppression marker of applyRemote, but ContentSHA, the merge base and
+// the sync timestamps are left alone, so the file reads as an unsynced local
+// edit until the next save pushes it.
+func applyLocal(sm *state.Manager, absPath string, localContent, content []byte, perm os.FileMode, backupReason string, debounce time.Duration) (backupID string, err error) {
+	if backupReason != "" {
+		e, err := takeBackup(sm, absPath, localContent, backupReason)
+		if err != nil {
+			return "", fmt.Errorf("creating backup: %w", err)
+		}
+		backupID = e.ID
+	}
+
+	_, err = sm.UpdateFile(absPath, func(fs *state.FileState) {
+		fs.PullSuppressUntil = suppressDeadline(*fs, debounce, time.Now())
+		fs.PullSuppressSHA = sha256Hex(content)
+	})
+	if err != nil {
+		return backupID, fmt.Errorf("saving suppression marker: %w", err)
+	}
+	return backupID, writeAtomic(absPath, content, perm)
+}
+
+// revisionContent returns filename's content at the Gist revision ref and a
+// description of it.
+func revisionContent(client *gist.Client, gistID, filename, ref string) ([]byte, string, error) {
+	rev, _, err := findRevision(client, gistID, ref)
+	if err != nil {
+		return nil, "", err
+	}
+	content, ok, err := client.FetchFileAt(gistID, rev.Version, filename)
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", fmt.Errorf("%s is not in revision %s (added later, or under another name)", filename, shortVersion(rev.Version))
+	}
+	return content, fmt.Sprintf("revision %s (%s)", shortVersion(rev.Version),
+		time.Unix(rev.CommittedAt, 0).Format(time.RFC3339)), nil
+}
+
+// backupContent returns the content of absPath's backup id (or ID prefix)
+// and a description of it. Backups in the store come first, then a
+// <path>.bak.<id> file left by an earlier release. An unknown id lists the
+// backups that exist.
+func backupContent(sm *state.Manager, absPath, id string) ([]byte, string, error) {
+	store := backupStore(sm)
+	entries, err := store.List()
+	if err != nil {
+		return nil, "", err
+	}
+	entries = backupsOf(entries, absPath)
+	e, err := backup.Find(entries, id)
+	if err == nil {
+		content, err := store.Content(e)
+		if err != nil {
+			return nil, "", err
+		}
+		return content, fmt.Sprintf("backup %s (%s, %s)", e.ID, e.Reason,
+			time.Unix(e.CreatedAt, 0).Format(time.RFC3339)), nil
+	}
+	if !errors.Is(err, backup.ErrNotFound) {
+		return nil, "", err
+	}
+
+	path := absPath + ".bak." + id
+	content, err := os.ReadFile(path)
+	if err == nil {
+		return content, "backup " + displayPath(path), nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, "", fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	var available []string
+	for _, e := range entries {
+		available = append(available, e.ID)
+	}
+	available = append(available, legacyBackups(absPath)...)
+	if len(available) == 0 {
+		return nil, "", fmt.Errorf("no backup %s; %s has no backups", id, displayPath(absPath))
+	}
+	return nil, "", fmt.Errorf("no backup %s; available: %s", id, strings.Join(available, ", "))
+}
+
+// legacyBackups lists the timestamps of the <path>.bak.<ts> files earlier
+// releases left next to absPath, newest first.
+func legacyBackups(absPath string) []string {
+	matches, _ := filepath.Glob(absPath + ".bak.*")
+	var out []string
+	for _, m := range matches {
+		ts := strings.TrimPrefix(m, absPath+".bak.")
+		if _, err := time.ParseInLocation(legacyBackupLayout, ts, time.Local); err == nil {
+			out = append(out, ts)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(out)))
+	return out
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreRevision, "revision", "", "Gist revision to restore (version SHA or prefix)")
+	restoreCmd.Flags().StringVar(&restoreBackup, "backup", "", "Backup to restore (ID or prefix, see 'gh automagist backups list')")
+	restoreCmd.Flags().BoolVar(&restorePush, "push", false, "Upload the restored content as a new Gist revision")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Skip the confirmation prompt")
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "Show the diff without writing")
+	restoreCmd.Flags().BoolVar(&restoreNoBackup, "no-backup", false, "Skip backing up the current content")
+	restoreCmd.MarkFlagsMutuallyExclusive("revision", "backup")
+	restoreCmd.MarkFlagsOneRequired("revision", "backup")
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/spf13/cobra"
 )
 
@@ -16,17 +18,41 @@ var (
 	Date    = "unknown"
 )
 
+// newGistClients builds the per-host clients every command talks to the Gist
+// API with, sharing the on-disk ETag cache. Tests swap it for one pointed at
+// a gisttest server.
+var newGistClients = func() *gist.Clients {
+	var opts []gist.Option
+	if sm, err := state.NewManager(); err == nil {
+		opts = append(opts, gist.WithETagCache(gist.OpenETagCache(sm.ETagCachePath())))
+	}
+	return gist.NewClients(opts...)
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "automagist",
 	Short: "Automagically sync local files to GitHub Gists",
 	Long: `gh-automagist is an extension for the GitHub CLI that watches local files
 and automatically synchronizes their changes seamlessly to GitHub Gists.`,
+	// Runs before every subcommand, so all of them share the config
+	// directory and state format.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := applyConfigDir(); err != nil {
+			return err
+		}
+		return applyStateFormat(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// No subcommand → show help.
 		cmd.Help()
 	},
 }
 
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configDirFlag, "config-dir", "",
+		fmt.Sprintf("Config directory holding state, PID file and log (env %s; default $XDG_CONFIG_HOME/gh-automagist, or its profiles/$%s)", configDirEnvVar, profileEnvVar))
+}
+
 // SetVersionInfo wires the goreleaser-injected build metadata into both the
 // package-scope vars (used at runtime by status/monitor) and Cobra's Version
 // field (which auto-adds the --version flag).
@@ -52,7 +78,10 @@ func Execute() {
 	}
 
 	if err := ghCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		code := exitCode(err)
+		if code == 1 {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(code)
 	}
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setDebounceFlag  string
+	setDirectionFlag string
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [path]",
+	Short: "Stop syncing a tracked file in either direction until resumed",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		absPath, err := updateSettings(args[0], func(fs *state.FileState) { fs.Paused = true })
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Paused %s; the monitor and pull leave it alone until 'gh automagist resume'.\n", absPath)
+		return nil
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [path]",
+	Short: "Resume syncing a paused file",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		absPath, err := updateSettings(args[0], func(fs *state.FileState) { fs.Paused = false })
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Resumed %s.\n", absPath)
+		if isMonitorRunning() {
+			fmt.Println("The running monitor will upload any edits made while it was paused.")
+		}
+		return nil
+	},
+}
+
+var setCmd = &cobra.Command{
+	Use:   "set [path]",
+	Short: "Change a tracked file's debounce interval or sync direction",
+	Long: `Override the monitor's settings for one file:
+  --debounce=<dur>   quiet-window before its edits are uploaded ("default" drops the override)
+  --direction=<dir>  push (local → Gist only), pull (Gist → local only) or both`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		debounceSet, directionSet := cmd.Flags().Changed("debounce"), cmd.Flags().Changed("direction")
+		if !debounceSet && !directionSet {
+			return fmt.Errorf("nothing to set; pass --debounce and/or --direction")
+		}
+		var debounce string
+		if debounceSet && setDebounceFlag != "default" {
+			d, err := time.ParseDuration(setDebounceFlag)
+			if err != nil {
+				return fmt.Errorf("invalid --debounce: %w", err)
+			}
+			debounce = d.String()
+		}
+		direction, err := state.ParseDirection(setDirectionFlag)
+		if err != nil {
+			return err
+		}
+
+		absPath, err := updateSettings(args[0], func(fs *state.FileState) {
+			if debounceSet {
+				fs.Debounce = debounce
+			}
+			if directionSet {
+				fs.Direction = direction
+				if direction == state.DirectionBoth {
+					fs.Direction = ""
+				}
+			}
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Updated %s.\n", absPath)
+		if isMonitorRunning() {
+			fmt.Println("The running monitor picks up the change automatically.")
+		}
+		return nil
+	},
+}
+
+// updateSettings applies fn to path's tracked entry and returns its
+// absolute path.
+func updateSettings(path string, fn func(fs *state.FileState)) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve absolute path: %w", err)
+	}
+	sm, err := state.NewManager()
+	if err != nil {
+		return "", err
+	}
+	tracked, err := sm.UpdateFile(absPath, fn)
+	if err != nil {
+		return "", fmt.Errorf("failed to save state: %w", err)
+	}
+	if !tracked {
+		return "", fmt.Errorf("file not tracked: %s", absPath)
+	}
+	return absPath, nil
+}
+
+// settingsBadge lists a file's own sync settings for status, or "".
+func settingsBadge(fs state.FileState) string {
+	var parts []string
+	if fs.Paused {
+		parts = append(parts, "paused")
+	}
+	switch fs.Direction {
+	case state.DirectionPush:
+		parts = append(parts, "push only")
+	case state.DirectionPull:
+		parts = append(parts, "pull only")
+	}
+	if d, ok := fs.DebounceInterval(); ok {
+		parts = append(parts, "debounce "+d.String())
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return newerStyle.Render("[" + strings.Join(parts, ", ") + "]")
+}
+
+func init() {
+	setCmd.Flags().StringVar(&setDebounceFlag, "debounce", "", `Debounce interval for this file, e.g. 500ms or 2m; "default" follows the monitor`)
+	setCmd.Flags().StringVar(&setDirectionFlag, "direction", "", "Sync direction: push, pull or both")
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(resumeCmd)
+	rootCmd.AddCommand(setCmd)
+}
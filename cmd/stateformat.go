@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// stateFormatEnvVar picks the state.json format when --state-format is not
+// given. Commands also export it, so a daemon they start inherits theirs.
+const stateFormatEnvVar = "GH_AUTOMAGIST_STATE_FORMAT"
+
+var stateFormatFlag string
+
+// resolveStateFormat picks the state.json format: an explicit flag wins,
+// otherwise the env var, otherwise state.FormatVersioned. Unlike debounce,
+// a malformed env var is an error: guessing could migrate a file the Ruby
+// implementation still needs.
+func resolveStateFormat(flagChanged bool, flagValue, envRaw string) (state.Format, error) {
+	if flagChanged {
+		return state.ParseFormat(flagValue)
+	}
+	f, err := state.ParseFormat(envRaw)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", stateFormatEnvVar, err)
+	}
+	return f, nil
+}
+
+// applyStateFormat sets the format state.NewManager gives Managers.
+func applyStateFormat(cmd *cobra.Command) error {
+	f, err := resolveStateFormat(cmd.Flags().Changed("state-format"), stateFormatFlag, os.Getenv(stateFormatEnvVar))
+	if err != nil {
+		return err
+	}
+	state.DefaultFormat = f
+	return os.Setenv(stateFormatEnvVar, string(f))
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&stateFormatFlag, "state-format", string(state.FormatVersioned),
+		fmt.Sprintf("state.json layout: %s, or %s to keep the Ruby implementation's layout (env %s)",
+			state.FormatVersioned, state.FormatRubyCompat, stateFormatEnvVar))
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveStateFormat_FlagBeatsEnv(t *testing.T) {
+	f, err := resolveStateFormat(true, "versioned", "ruby-compat")
+	require.NoError(t, err)
+	assert.Equal(t, state.FormatVersioned, f)
+}
+
+func TestResolveStateFormat_EnvWhenNoFlag(t *testing.T) {
+	f, err := resolveStateFormat(false, "versioned", "ruby-compat")
+	require.NoError(t, err)
+	assert.Equal(t, state.FormatRubyCompat, f)
+}
+
+func TestResolveStateFormat_DefaultWhenBothMissing(t *testing.T) {
+	f, err := resolveStateFormat(false, "versioned", "")
+	require.NoError(t, err)
+	assert.Equal(t, state.FormatVersioned, f)
+}
+
+func TestResolveStateFormat_MalformedEnvIsAnError(t *testing.T) {
+	_, err := resolveStateFormat(false, "versioned", "ruby")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), stateFormatEnvVar)
+}
@@ -2,12 +2,17 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
 	"github.com/noriyo_tcp/gh-automagist/pkg/notify"
+	"github.com/noriyo_tcp/gh-automagist/pkg/outbox"
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/spf13/cobra"
 )
@@ -20,6 +25,8 @@ var (
 	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("1")) // red
 )
 
+var statusOutput jsonOutput
+
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show monitor process status and currently monitored files",
@@ -32,57 +39,212 @@ var statusCmd = &cobra.Command{
 			return err
 		}
 
-		pid := sm.GetPID()
 		info, infoErr := sm.ReadMonitorInfo()
-		if infoErr != nil {
+		if infoErr != nil && !statusOutput.enabled() {
 			fmt.Printf("Warning: failed to read monitor info: %v\n", infoErr)
 		}
-		if pid == 0 {
+		mon := readMonitor(sm, info)
+		if statusOutput.enabled() {
+			return runStatusJSON(cmd, sm, mon)
+		}
+		if mon.State == monitorStopped {
 			fmt.Println("Monitor Status: STOPPED")
 		} else {
-			out, err := exec.Command("ps", "-o", "state=", "-p", fmt.Sprintf("%d", pid)).Output()
-			if err != nil || len(out) == 0 {
-				fmt.Println("Monitor Status: STOPPED")
+			label := strings.ToUpper(mon.State)
+			if mon.Version != "" {
+				fmt.Printf("Monitor Status: %s (PID: %d, version: %s)\n", label, mon.PID, mon.Version)
 			} else {
-				stateStr := strings.TrimSpace(string(out))
-				label := "RUNNING"
-				if strings.HasPrefix(stateStr, "T") {
-					label = "SUSPENDED"
-				}
-				daemonVersion := ""
-				if info != nil {
-					daemonVersion = info.Version
-				}
-				if daemonVersion != "" {
-					fmt.Printf("Monitor Status: %s (PID: %d, version: %s)\n", label, pid, daemonVersion)
-				} else {
-					fmt.Printf("Monitor Status: %s (PID: %d)\n", label, pid)
-				}
-				if daemonVersion != "" && daemonVersion != Version {
-					fmt.Printf("  %s daemon is %s but installed binary is %s — run 'gh automagist restart' to pick it up.\n",
-						errorStyle.Render("!"), daemonVersion, Version)
-				}
+				fmt.Printf("Monitor Status: %s (PID: %d)\n", label, mon.PID)
+			}
+			if mon.Version != "" && mon.Version != Version {
+				fmt.Printf("  %s daemon is %s but installed binary is %s — run 'gh automagist restart' to pick it up.\n",
+					errorStyle.Render("!"), mon.Version, Version)
 			}
 		}
 
 		fmt.Println()
 
+		if running, err := runningProfiles(sm.ConfigDir()); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		} else {
+			printProfiles(os.Stdout, running)
+		}
+
 		if len(sm.Files) == 0 {
 			fmt.Println("No files registered.")
 			return nil
 		}
 
-		statuses := notify.Detect(sm, gist.NewClient())
+		clients := newGistClients()
+		statuses, err := detect(cmd.Context(), sm, clients)
+		if err != nil {
+			return err
+		}
+
+		pending := map[string]outbox.Entry{}
+		if box, err := outbox.Open(sm.OutboxPath()); err != nil {
+			fmt.Printf("Warning: failed to read outbox: %v\n", err)
+		} else {
+			pending = box.Entries()
+		}
 
 		fmt.Printf("Registered Files (%d):\n", len(sm.Files))
-		for _, s := range statuses {
-			fmt.Printf("- %s (Gist ID: %s)  %s\n", s.Path, s.GistID, statusBadge(s))
+		hosts, byHost := groupByHost(statuses)
+		for _, host := range hosts {
+			if showHosts(hosts) {
+				fmt.Printf("%s:\n", host)
+			}
+			for _, s := range byHost[host] {
+				line := fmt.Sprintf("- %s (Gist ID: %s)  %s", s.Path, s.GistID, statusBadge(s))
+				if badge := settingsBadge(sm.Files[s.Path]); badge != "" {
+					line += "  " + badge
+				}
+				if s.Missing {
+					line += "  " + errorStyle.Render("[missing locally]")
+				}
+				if s.Conflict {
+					line += "  " + errorStyle.Render("[conflict]")
+				}
+				if e, ok := pending[s.Path]; ok {
+					line += "  " + pendingBadge(e)
+				}
+				fmt.Println(line)
+			}
 		}
 
+		printRateLimits(os.Stdout, clients.RateLimits())
+		printConflicts(os.Stdout, sm)
+		printPendingUploads(sm, pending)
 		return nil
 	},
 }
 
+// Values of monitorJSON.State.
+const (
+	monitorRunning   = "running"
+	monitorSuspended = "suspended"
+	monitorStopped   = "stopped"
+)
+
+// readMonitor reports whether the daemon named in monitor.pid is alive, and
+// with what MonitorInfo. info may be nil.
+func readMonitor(sm *state.Manager, info *state.MonitorInfo) monitorJSON {
+	pid := sm.GetPID()
+	if pid == 0 {
+		return monitorJSON{State: monitorStopped}
+	}
+	out, err := exec.Command("ps", "-o", "state=", "-p", fmt.Sprintf("%d", pid)).Output()
+	if err != nil || len(out) == 0 {
+		return monitorJSON{State: monitorStopped}
+	}
+	mon := monitorJSON{State: monitorRunning, PID: pid}
+	if strings.HasPrefix(strings.TrimSpace(string(out)), "T") {
+		mon.State = monitorSuspended
+	}
+	if info != nil {
+		mon.Version, mon.Commit = info.Version, info.Commit
+		mon.StartedAt = jsonTime(info.StartedAt)
+	}
+	return mon
+}
+
+// statusJSON is `status --json`.
+type statusJSON struct {
+	Monitor    monitorJSON     `json:"monitor"`
+	Files      []statusFile    `json:"files"`
+	RateLimits []rateLimitJSON `json:"rate_limits"`
+}
+
+// statusFile adds the outbox's view to checkJSON.
+type statusFile struct {
+	checkJSON
+	FailedUploads int    `json:"failed_uploads,omitempty"`
+	NextRetryAt   string `json:"next_retry_at,omitempty"`
+}
+
+func runStatusJSON(cmd *cobra.Command, sm *state.Manager, mon monitorJSON) error {
+	clients := newGistClients()
+	statuses, err := detect(cmd.Context(), sm, clients)
+	if err != nil {
+		return err
+	}
+	pending := map[string]outbox.Entry{}
+	if box, err := outbox.Open(sm.OutboxPath()); err == nil {
+		pending = box.Entries()
+	}
+
+	out := statusJSON{Monitor: mon, Files: []statusFile{}, RateLimits: newRateLimitJSONs(clients.RateLimits())}
+	for _, c := range newCheckJSONs(sm, statuses) {
+		f := statusFile{checkJSON: c}
+		if e, ok := pending[c.Path]; ok {
+			f.FailedUploads, f.NextRetryAt = e.Attempts, jsonTime(e.NextAttemptAt)
+		}
+		out.Files = append(out.Files, f)
+	}
+	if err := statusOutput.write(cmd.OutOrStdout(), out); err != nil {
+		return err
+	}
+	return checkExitStatus(cmd, statuses)
+}
+
+// printRateLimits reports the API quota left per host (and account), as of
+// the checks status just made. Quota spent is flagged so an "error" badge
+// above reads as "wait", not "broken".
+func printRateLimits(w io.Writer, limits []gist.RateLimit) {
+	if len(limits) == 0 {
+		return
+	}
+	fmt.Fprintln(w)
+	for _, rl := range limits {
+		who := rl.Host
+		if rl.Account != "" {
+			who = rl.Account + "@" + rl.Host
+		}
+		line := fmt.Sprintf("API quota (%s): %d/%d remaining, resets %s",
+			who, rl.Remaining, rl.Limit, rl.Reset.Format(time.TimeOnly))
+		if rl.Remaining == 0 {
+			line = errorStyle.Render(line + " — checks are paused until then")
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// pendingBadge marks a file whose last upload failed and is queued for retry.
+func pendingBadge(e outbox.Entry) string {
+	return errorStyle.Render(fmt.Sprintf("[upload failed ×%d, retrying]", e.Attempts))
+}
+
+// printPendingUploads explains each queued retry: when it will run and why
+// the last attempt failed. Entries for untracked files are skipped; the
+// daemon drops them on its next pass.
+func printPendingUploads(sm *state.Manager, pending map[string]outbox.Entry) {
+	var paths []string
+	for path := range pending {
+		if _, tracked := sm.Files[path]; tracked {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+	sort.Strings(paths)
+
+	fmt.Printf("\nPending Uploads (%d):\n", len(paths))
+	for _, path := range paths {
+		e := pending[path]
+		fmt.Printf("- %s: %d failed attempt(s) since %s, next retry %s\n",
+			displayPath(path), e.Attempts,
+			time.Unix(e.FirstFailedAt, 0).Format(time.RFC3339),
+			time.Unix(e.NextAttemptAt, 0).Format(time.RFC3339))
+		if e.LastError != "" {
+			fmt.Printf("    last error: %s\n", e.LastError)
+		}
+	}
+	if !isMonitorRunning() {
+		fmt.Println("  Monitor is not running — start it to retry these uploads.")
+	}
+}
+
 // statusBadge renders a short suffix describing the file's notify state.
 // Kept trivial so both status and dashboard can reuse it if we ever wire
 // dashboard through the same struct.
@@ -98,5 +260,7 @@ func statusBadge(s notify.FileStatus) string {
 }
 
 func init() {
+	addDetectFlags(statusCmd)
+	addJSONFlags(statusCmd, &statusOutput)
 	rootCmd.AddCommand(statusCmd)
 }
@@ -2,11 +2,18 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/spf13/cobra"
 )
 
+// defaultStopTimeout bounds how long stop/restart wait for the daemon to
+// flush pending syncs after SIGTERM before escalating to SIGKILL.
+const defaultStopTimeout = 10 * time.Second
+
+var stopTimeout time.Duration
+
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop the running monitor process",
@@ -25,14 +32,18 @@ var stopCmd = &cobra.Command{
 			return nil
 		}
 
-		killed, err := sm.KillMonitor(pid)
+		fmt.Printf("Stopping monitor (PID: %d)...\n", pid)
+		result, err := sm.StopMonitor(pid, stopTimeout)
 		if err != nil {
 			fmt.Printf("Failed to stop monitor: %v\n", err)
 			return err
 		}
-		if killed {
+		switch result {
+		case state.StopGraceful:
 			fmt.Printf("Stopped monitor (PID: %d)\n", pid)
-		} else {
+		case state.StopKilled:
+			fmt.Printf("Monitor did not exit within %s; killed (PID: %d). Pending syncs may have been lost.\n", stopTimeout, pid)
+		default:
 			fmt.Printf("Monitor process %d was not running (stale PID file, cleaned up)\n", pid)
 		}
 		return nil
@@ -40,5 +51,7 @@ var stopCmd = &cobra.Command{
 }
 
 func init() {
+	stopCmd.Flags().DurationVar(&stopTimeout, "timeout", defaultStopTimeout,
+		"How long to wait for the monitor to flush pending syncs before force-killing it")
 	rootCmd.AddCommand(stopCmd)
 }
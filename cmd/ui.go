@@ -23,14 +23,13 @@ func isMonitorRunning() bool {
 		return false
 	}
 	pid := sm.GetPID()
-	if pid == 0 {
-		return false
-	}
+	return pid != 0 && pidAlive(pid)
+}
+
+// pidAlive reports whether ps still knows pid.
+func pidAlive(pid int) bool {
 	out, err := exec.Command("ps", "-o", "state=", "-p", fmt.Sprintf("%d", pid)).Output()
-	if err != nil || len(strings.TrimSpace(string(out))) == 0 {
-		return false
-	}
-	return true
+	return err == nil && len(strings.TrimSpace(string(out))) > 0
 }
 
 // renderCompactHeader draws the sub-screen status bar.
@@ -13,6 +13,7 @@ import (
 func TestHomeDir(t *testing.T) {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
 
 	assert.Equal(t, tempHome, homeDir())
 }
@@ -20,6 +21,7 @@ func TestHomeDir(t *testing.T) {
 func TestIsMonitorRunning(t *testing.T) {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
 
 	sm, err := state.NewManager()
 	require.NoError(t, err)
@@ -0,0 +1,257 @@
+// Package backup keeps the copies of tracked files that pull, merge and
+// restore take before overwriting them, in one place under the config
+// directory rather than next to the files themselves.
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/objstore"
+)
+
+// ErrNotFound is returned by Get and Find when no backup has the given ID.
+var ErrNotFound = errors.New("backup not found")
+
+// Entry describes one backup. The content itself is stored by SHA, so
+// identical backups of a file share one copy.
+type Entry struct {
+	ID        string `json:"id"`
+	SHA       string `json:"sha"`
+	Path      string `json:"path"`
+	GistID    string `json:"gist_id,omitempty"`
+	Reason    string `json:"reason"`
+	CreatedAt int64  `json:"created_at"`
+	Size      int64  `json:"size"`
+}
+
+// Policy bounds what Prune keeps. A zero field is no limit.
+type Policy struct {
+	KeepPerFile int           // newest backups kept per original path
+	MaxAge      time.Duration // backups older than this are dropped
+	MaxSize     int64         // total bytes of stored content; never drops a file's newest backup
+}
+
+// DefaultPolicy is applied after every backup is taken.
+var DefaultPolicy = Policy{
+	KeepPerFile: 10,
+	MaxAge:      90 * 24 * time.Hour,
+	MaxSize:     100 << 20,
+}
+
+// objectPruneAge keeps content another process has just stored, but not yet
+// written the entry for, out of Prune's reach.
+const objectPruneAge = time.Minute
+
+// idTimeLayout is the timestamp an Entry's ID starts with.
+const idTimeLayout = "20060102-150405"
+
+// Store is the backup directory: content in <dir>/objects (see
+// pkg/objstore), one JSON file per Entry in <dir>/entries. Entries are
+// separate files so processes taking backups at once never contend.
+type Store struct {
+	dir     string
+	objects *objstore.Store
+}
+
+func New(dir string) *Store {
+	return &Store{dir: dir, objects: objstore.New(filepath.Join(dir, "objects"))}
+}
+
+func (s *Store) entriesDir() string {
+	return filepath.Join(s.dir, "entries")
+}
+
+// Save stores content as a backup of path, taken for reason (e.g. "pull").
+func (s *Store) Save(path, gistID, reason string, content []byte, now time.Time) (Entry, error) {
+	sha, err := s.objects.Put(content)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to store backup: %w", err)
+	}
+	e := Entry{
+		SHA:       sha,
+		Path:      path,
+		GistID:    gistID,
+		Reason:    reason,
+		CreatedAt: now.Unix(),
+		Size:      int64(len(content)),
+	}
+	if err := os.MkdirAll(s.entriesDir(), 0755); err != nil {
+		return Entry{}, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// O_EXCL picks a free ID when two backups share a second and content.
+	base := now.Format(idTimeLayout) + "-" + sha[:8]
+	for n := 1; ; n++ {
+		e.ID = base
+		if n > 1 {
+			e.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		data, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return Entry{}, err
+		}
+		f, err := os.OpenFile(s.entryPath(e.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return Entry{}, fmt.Errorf("failed to record backup: %w", err)
+		}
+		_, err = f.Write(data)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			_ = os.Remove(s.entryPath(e.ID))
+			return Entry{}, fmt.Errorf("failed to record backup: %w", err)
+		}
+		return e, nil
+	}
+}
+
+func (s *Store) entryPath(id string) string {
+	return filepath.Join(s.entriesDir(), id+".json")
+}
+
+// List returns every backup, newest first. Unreadable entry files are
+// skipped.
+func (s *Store) List() ([]Entry, error) {
+	names, err := os.ReadDir(s.entriesDir())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+	var out []Entry
+	for _, d := range names {
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.entriesDir(), d.Name()))
+		if err != nil {
+			continue
+		}
+		var e Entry
+		if json.Unmarshal(data, &e) != nil || e.ID == "" {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt > out[j].CreatedAt
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out, nil
+}
+
+// Get returns the backup whose ID is id, or starts with it if only one does.
+func (s *Store) Get(id string) (Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return Entry{}, err
+	}
+	return Find(entries, id)
+}
+
+// Find is Get among entries, e.g. the backups of one file.
+func Find(entries []Entry, id string) (Entry, error) {
+	var match []Entry
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+		if strings.HasPrefix(e.ID, id) {
+			match = append(match, e)
+		}
+	}
+	switch len(match) {
+	case 0:
+		return Entry{}, ErrNotFound
+	case 1:
+		return match[0], nil
+	default:
+		return Entry{}, fmt.Errorf("backup id %q is ambiguous (%d matches)", id, len(match))
+	}
+}
+
+// Content returns the backed-up content of e.
+func (s *Store) Content(e Entry) ([]byte, error) {
+	data, err := s.objects.Get(e.SHA)
+	if err != nil {
+		return nil, fmt.Errorf("backup %s: %w", e.ID, err)
+	}
+	return data, nil
+}
+
+// Prune drops backups outside p, oldest first for MaxSize, and the content
+// no remaining backup refers to. MaxSize spares each file's newest backup,
+// so the one just taken survives however large it is. Returns the entries
+// removed.
+func (s *Store) Prune(p Policy, now time.Time) ([]Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	drop := make(map[string]bool)
+	perFile := make(map[string]int)
+	for _, e := range entries {
+		perFile[e.Path]++
+		if p.KeepPerFile > 0 && perFile[e.Path] > p.KeepPerFile {
+			drop[e.ID] = true
+		}
+		if p.MaxAge > 0 && now.Sub(time.Unix(e.CreatedAt, 0)) > p.MaxAge {
+			drop[e.ID] = true
+		}
+	}
+	if p.MaxSize > 0 {
+		// Shared content counts once; walking newest first, the first
+		// backup past the limit and every older one go.
+		seen := make(map[string]bool)
+		newest := make(map[string]bool)
+		var total int64
+		for _, e := range entries {
+			if drop[e.ID] {
+				continue
+			}
+			if !seen[e.SHA] {
+				seen[e.SHA] = true
+				total += e.Size
+			}
+			if !newest[e.Path] {
+				newest[e.Path] = true
+				continue
+			}
+			if total > p.MaxSize {
+				drop[e.ID] = true
+			}
+		}
+	}
+
+	var removed []Entry
+	keep := make(map[string]bool)
+	for _, e := range entries {
+		if !drop[e.ID] {
+			keep[e.SHA] = true
+			continue
+		}
+		if err := os.Remove(s.entryPath(e.ID)); err != nil && !os.IsNotExist(err) {
+			return removed, fmt.Errorf("failed to remove backup %s: %w", e.ID, err)
+		}
+		removed = append(removed, e)
+	}
+	if _, err := s.objects.Prune(keep, objectPruneAge); err != nil {
+		return removed, err
+	}
+	return removed, nil
+}
@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var t0 = time.Date(2026, 3, 1, 14, 15, 2, 0, time.UTC)
+
+func TestStore_SaveListGet(t *testing.T) {
+	s := New(t.TempDir())
+	old, err := s.Save("/home/u/.zshrc", "g1", "pull", []byte("old\n"), t0)
+	require.NoError(t, err)
+	assert.Equal(t, "20260301-141502-"+old.SHA[:8], old.ID[:len("20260301-141502-")+8])
+	newer, err := s.Save("/home/u/.zshrc", "g1", "restore", []byte("newer\n"), t0.Add(time.Minute))
+	require.NoError(t, err)
+
+	list, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, newer.ID, list[0].ID, "newest first")
+
+	_, err = s.Get("20260301-14")
+	assert.Error(t, err, "prefix of both is ambiguous")
+	assert.NotErrorIs(t, err, ErrNotFound)
+	got, err := s.Get(old.ID[:20])
+	require.NoError(t, err)
+	assert.Equal(t, old, got)
+	content, err := s.Content(got)
+	require.NoError(t, err)
+	assert.Equal(t, "old\n", string(content))
+
+	_, err = s.Get("1999")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_SameSecondSameContentGetsNewID(t *testing.T) {
+	s := New(t.TempDir())
+	a, err := s.Save("/a", "", "pull", []byte("x"), t0)
+	require.NoError(t, err)
+	b, err := s.Save("/b", "", "pull", []byte("x"), t0)
+	require.NoError(t, err)
+	assert.NotEqual(t, a.ID, b.ID)
+	assert.Equal(t, a.SHA, b.SHA)
+}
+
+func TestStore_Prune(t *testing.T) {
+	s := New(t.TempDir())
+	for i, content := range []string{"1", "2", "3"} {
+		_, err := s.Save("/a", "", "pull", []byte(content), t0.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+	_, err := s.Save("/b", "", "pull", []byte("b-old"), t0.Add(-100*24*time.Hour))
+	require.NoError(t, err)
+
+	now := t0.Add(3 * time.Hour)
+	removed, err := s.Prune(Policy{KeepPerFile: 2, MaxAge: 90 * 24 * time.Hour}, now)
+	require.NoError(t, err)
+	require.Len(t, removed, 2)
+	list, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	for _, e := range list {
+		assert.Equal(t, "/a", e.Path)
+		_, err := s.Content(e)
+		assert.NoError(t, err)
+	}
+
+	// Each left is one byte; a two-byte budget drops just the older.
+	removed, err = s.Prune(Policy{MaxSize: 1}, now)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, t0.Add(time.Hour).Unix(), removed[0].CreatedAt)
+}
+
+func TestStore_PruneMaxSizeKeepsEachFilesNewest(t *testing.T) {
+	s := New(t.TempDir())
+	_, err := s.Save("/a", "", "pull", []byte("a-old"), t0)
+	require.NoError(t, err)
+	_, err = s.Save("/b", "", "pull", []byte("b"), t0.Add(time.Hour))
+	require.NoError(t, err)
+	big, err := s.Save("/a", "", "pull", []byte("much larger than the budget"), t0.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	removed, err := s.Prune(Policy{MaxSize: 4}, t0.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.Equal(t, t0.Unix(), removed[0].CreatedAt)
+
+	list, err := s.List()
+	require.NoError(t, err)
+	require.Len(t, list, 2, "the newest backup of /a and of /b survive")
+	assert.Equal(t, big.ID, list[0].ID)
+}
@@ -0,0 +1,183 @@
+package gist_test
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist/gisttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_CreateAndFetch(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	client := srv.Client()
+
+	local := filepath.Join(t.TempDir(), "local.txt")
+	require.NoError(t, os.WriteFile(local, []byte("hello\n"), 0o644))
+
+	id, createdAt, err := client.CreateGist(local, "remote.txt", "desc", false)
+	require.NoError(t, err)
+
+	content, updatedAt, err := client.FetchFile(id, "remote.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(content))
+	assert.Equal(t, gisttest.Epoch.Unix(), updatedAt)
+	assert.Equal(t, updatedAt, createdAt, "create reports the revision it made")
+
+	_, _, err = client.FetchFile(id, "local.txt")
+	assert.Error(t, err, "file is stored under its remote name")
+
+	g, ok := srv.Gist(id)
+	require.True(t, ok)
+	assert.Equal(t, "desc", g.Description)
+}
+
+func TestClient_UpdateFilesIsOneRevision(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	client := srv.Client()
+	id := srv.AddGist(map[string]string{"a.txt": "a", "b.txt": "b"})
+
+	updatedAt, err := client.UpdateFiles(id, map[string][]byte{"a.txt": []byte("A"), "b.txt": []byte("B")})
+	require.NoError(t, err)
+
+	g, _ := srv.Gist(id)
+	assert.Len(t, g.History, 2)
+	assert.Equal(t, g.UpdatedAt().Unix(), updatedAt)
+	assert.Equal(t, map[string]string{"a.txt": "A", "b.txt": "B"}, g.Files)
+
+	meta, err := client.FetchGistMeta(id)
+	require.NoError(t, err)
+	assert.Equal(t, updatedAt, meta)
+
+	files, filesAt, err := client.FetchAllFiles(id)
+	require.NoError(t, err)
+	assert.Equal(t, updatedAt, filesAt)
+	assert.Equal(t, []byte("A"), files["a.txt"])
+}
+
+func TestClient_RemoteEditMovesMetaForward(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	client := srv.Client()
+	id := srv.AddGist(map[string]string{"a.txt": "a"})
+
+	before, err := client.FetchGistMeta(id)
+	require.NoError(t, err)
+	srv.SetFile(id, "a.txt", "edited in browser")
+	after, err := client.FetchGistMeta(id)
+	require.NoError(t, err)
+	assert.Greater(t, after, before)
+}
+
+func TestClient_RevisionsPaginateAndFetchFileAt(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	client := srv.Client()
+	id := srv.AddGist(map[string]string{"a.txt": "one\n"})
+	srv.SetFile(id, "a.txt", "one\ntwo\n")
+	srv.SetFile(id, "b.txt", "b\n")
+
+	first, more, err := client.Revisions(id, 1, 2)
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.True(t, more)
+	assert.Equal(t, "v3", first[0].Version, "newest first")
+	assert.Equal(t, 1, first[1].Additions)
+	assert.Equal(t, 0, first[1].Deletions)
+
+	last, more, err := client.Revisions(id, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, last, 1)
+	assert.False(t, more)
+	assert.Equal(t, gisttest.Epoch.Unix(), last[0].CommittedAt)
+
+	content, ok, err := client.FetchFileAt(id, last[0].Version, "a.txt")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "one\n", string(content))
+	_, ok, err = client.FetchFileAt(id, last[0].Version, "b.txt")
+	require.NoError(t, err)
+	assert.False(t, ok, "b.txt was added later")
+
+	_, _, err = client.FetchFileAt(id, "nope", "a.txt")
+	assert.Error(t, err)
+}
+
+func TestClient_RenameAndDelete(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	client := srv.Client()
+	id := srv.AddGist(map[string]string{"a.txt": "a", "b.txt": "b"})
+
+	_, err := client.RenameFile(id, "a.txt", "c.txt")
+	require.NoError(t, err)
+	_, err = client.DeleteFile(id, "b.txt")
+	require.NoError(t, err)
+
+	g, _ := srv.Gist(id)
+	assert.Equal(t, map[string]string{"c.txt": "a"}, g.Files)
+}
+
+func TestClient_ErrorsSurface(t *testing.T) {
+	srv := gisttest.NewServer(t)
+
+	_, err := srv.Client().FetchGistMeta("missing")
+	assert.Error(t, err)
+
+	unauthenticated := gist.NewClient(gist.WithBaseURL(srv.URL), gist.WithAuthToken("wrong"))
+	_, _, err = unauthenticated.FetchFile(srv.AddGist(map[string]string{"a.txt": "a"}), "a.txt")
+	assert.Error(t, err)
+}
+
+type countingTransport struct{ n atomic.Int32 }
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.n.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestClient_WithTransport(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	rt := &countingTransport{}
+	client := gist.NewClient(gist.WithBaseURL(srv.URL+"/"), gist.WithAuthToken(gisttest.Token), gist.WithTransport(rt))
+
+	_, err := client.FetchGistMeta(srv.AddGist(map[string]string{"a.txt": "a"}))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, rt.n.Load())
+	assert.Equal(t, 1, srv.CountRequests(http.MethodGet))
+}
+
+type recordingTransport struct{ urls []string }
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.urls = append(r.urls, req.URL.String())
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`[{"committed_at":"2026-01-01T00:00:00Z"}]`)),
+		Request:    req,
+	}, nil
+}
+
+func TestClients_RouteEnterpriseHostToItsAPI(t *testing.T) {
+	rt := &recordingTransport{}
+	clients := gist.NewClients(gist.WithTransport(rt), gist.WithAuthToken("t"))
+
+	ghes := clients.For("GHE.example.com", "")
+	assert.Same(t, ghes, clients.For("ghe.example.com", ""), "clients are cached per host")
+	assert.NotSame(t, ghes, clients.For("ghe.example.com", "other"), "and per account")
+	assert.Equal(t, "ghe.example.com", ghes.Host())
+
+	_, err := ghes.FetchGistMeta("abc")
+	require.NoError(t, err)
+	_, err = clients.For("github.com", "").FetchGistMeta("abc")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"https://ghe.example.com/api/v3/gists/abc/commits?per_page=1",
+		"https://api.github.com/gists/abc/commits?per_page=1",
+	}, rt.urls)
+}
@@ -0,0 +1,144 @@
+package gist
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxCachedBody keeps whole-Gist responses with large files out of the
+// cache; those are fetched in full every time.
+const maxCachedBody = 1 << 20
+
+// The cache keeps at most maxCacheEntries responses totalling maxCacheBytes
+// of body, dropping the least recently used first. Every put rewrites the
+// file, so this also bounds what a put costs.
+const (
+	maxCacheEntries = 256
+	maxCacheBytes   = 8 << 20
+)
+
+// ETagCache remembers the ETag and body of GET responses by host, account
+// and URL, persisted to a JSON file so conditional requests work across CLI
+// invocations. It is only an optimisation: an unreadable file starts empty
+// and a failed write is ignored. Safe for concurrent use.
+type ETagCache struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+	clock   int64 // last Used handed out
+}
+
+type cachedResponse struct {
+	ETag string `json:"etag"`
+	Body []byte `json:"body"`
+	Used int64  `json:"used"` // UnixNano of the last get or put, for eviction
+}
+
+// OpenETagCache loads the cache stored at path. An empty path keeps the
+// cache in memory only.
+func OpenETagCache(path string) *ETagCache {
+	c := &ETagCache{path: path, entries: make(map[string]cachedResponse)}
+	if path == "" {
+		return c
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		_ = json.Unmarshal(data, &c.entries)
+	}
+	for _, e := range c.entries {
+		c.clock = max(c.clock, e.Used)
+	}
+	return c
+}
+
+// cacheKey keeps responses apart per account: two accounts on one host can
+// see different Gists at the same URL.
+func cacheKey(host, account, url string) string {
+	return host + " " + account + " " + url
+}
+
+func (c *ETagCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if ok {
+		e.Used = c.tickLocked()
+		c.entries[key] = e
+	}
+	return e, ok
+}
+
+func (c *ETagCache) put(key, etag string, body []byte) {
+	if len(body) > maxCachedBody {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && e.ETag == etag {
+		return
+	}
+	c.entries[key] = cachedResponse{ETag: etag, Body: body, Used: c.tickLocked()}
+	c.evictLocked()
+	c.saveLocked()
+}
+
+// tickLocked returns a Used stamp later than any handed out before: the wall
+// clock, so the order holds across invocations, but never a repeat.
+func (c *ETagCache) tickLocked() int64 {
+	c.clock = max(time.Now().UnixNano(), c.clock+1)
+	return c.clock
+}
+
+// evictLocked drops least recently used entries until the cache is within
+// maxCacheEntries and maxCacheBytes.
+func (c *ETagCache) evictLocked() {
+	size := 0
+	for _, e := range c.entries {
+		size += len(e.Body)
+	}
+	for len(c.entries) > maxCacheEntries || size > maxCacheBytes {
+		oldest, oldestUsed := "", int64(0)
+		for key, e := range c.entries {
+			if oldest == "" || e.Used < oldestUsed {
+				oldest, oldestUsed = key, e.Used
+			}
+		}
+		size -= len(c.entries[oldest].Body)
+		delete(c.entries, oldest)
+	}
+}
+
+// saveLocked writes the cache atomically: a temp file of its own in the
+// same directory, renamed over the cache, so concurrent CLI invocations
+// never write into each other's temp file.
+func (c *ETagCache) saveLocked() {
+	if c.path == "" {
+		return
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return
+	}
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(c.path)+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, werr := tmp.Write(data)
+	if cerr := tmp.Close(); werr == nil {
+		werr = cerr
+	}
+	if werr != nil {
+		_ = os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
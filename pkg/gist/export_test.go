@@ -0,0 +1,18 @@
+package gist
+
+import (
+	"context"
+	"time"
+)
+
+// StubRateLimitWaits makes Retry-After waits instant for the duration of a
+// test, recording each requested wait in waits, and caps them at max.
+func StubRateLimitWaits(max time.Duration, waits *[]time.Duration) (restore func()) {
+	prevMax, prevSleep := maxRetryWait, sleep
+	maxRetryWait = max
+	sleep = func(_ context.Context, d time.Duration) error {
+		*waits = append(*waits, d)
+		return nil
+	}
+	return func() { maxRetryWait, sleep = prevMax, prevSleep }
+}
@@ -2,19 +2,228 @@ package gist
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"os"
-	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cli/go-gh/v2"
 	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/go-gh/v2/pkg/auth"
 )
 
-type Client struct{}
+// Client talks to the Gist REST API of one host. The zero configuration
+// behaves like gh itself: host and token come from gh's config and `gh auth`.
+type Client struct {
+	opts    api.ClientOptions
+	baseURL string
+	host    string
+	account string
+	cache   *ETagCache
+
+	mu        sync.Mutex
+	rest      *api.RESTClient
+	transport *transport
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithBaseURL sends every request to baseURL (e.g. an httptest server)
+// instead of the API URL derived from the host.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+	}
+}
+
+// WithTransport makes requests through rt instead of http.DefaultTransport.
+func WithTransport(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.opts.Transport = rt
+	}
+}
+
+// WithAuthToken authenticates with token instead of looking one up via gh.
+func WithAuthToken(token string) Option {
+	return func(c *Client) {
+		c.opts.AuthToken = token
+	}
+}
+
+// WithHost talks to host (e.g. a GitHub Enterprise Server hostname) instead
+// of gh's default host.
+func WithHost(host string) Option {
+	return func(c *Client) {
+		c.host = auth.NormalizeHostname(host)
+	}
+}
+
+// WithAccount authenticates as account, one of several users logged in to
+// the host with `gh auth login`, instead of the host's active account.
+func WithAccount(account string) Option {
+	return func(c *Client) {
+		c.account = account
+	}
+}
+
+// WithETagCache makes GETs conditional on the ETags remembered in cache, so
+// re-reading an unchanged Gist costs a 304 instead of quota.
+func WithETagCache(cache *ETagCache) Option {
+	return func(c *Client) {
+		c.cache = cache
+	}
+}
+
+func NewClient(opts ...Option) *Client {
+	c := &Client{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Host is the host the client talks to; empty means gh's default host.
+func (c *Client) Host() string {
+	return c.host
+}
+
+// accountToken looks up the token `gh auth login` stored for account on
+// host. A variable so tests need no gh binary.
+var accountToken = func(host, account string) (string, error) {
+	stdout, stderr, err := gh.Exec("auth", "token", "--hostname", host, "--user", account)
+	if err != nil {
+		return "", fmt.Errorf("no token for %s on %s (run 'gh auth login --hostname %s'): %s",
+			account, host, host, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// restClient builds the underlying go-gh client on first use, so commands
+// that never reach the API do not need gh to be authenticated.
+func (c *Client) restClient() (*api.RESTClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rest != nil {
+		return c.rest, nil
+	}
+
+	opts := c.opts
+	opts.Host = c.host
+	if c.baseURL != "" {
+		// go-gh only attaches the token to requests for its own host.
+		if u, err := url.Parse(c.baseURL); err == nil {
+			opts.Host = u.Hostname()
+		}
+	}
+	host := c.host
+	if host == "" {
+		host, _ = auth.DefaultHost()
+	}
+	if c.account != "" && opts.AuthToken == "" {
+		token, err := accountToken(host, c.account)
+		if err != nil {
+			return nil, err
+		}
+		opts.AuthToken = token
+	}
+
+	base := opts.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{base: base, host: host, account: c.account, cache: c.cache}
+	opts.Transport = t
+
+	rest, err := api.NewRESTClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize github rest client: %w", err)
+	}
+	c.rest = rest
+	c.transport = t
+	return rest, nil
+}
+
+// RateLimit returns the quota the host reported on this client's latest
+// response; false until a response carried one.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	c.mu.Lock()
+	t := c.transport
+	c.mu.Unlock()
+	if t == nil {
+		return RateLimit{}, false
+	}
+	return t.rateLimit()
+}
+
+// Clients hands out one Client per host and account, so files tracked on
+// github.com and on a GitHub Enterprise Server each reach their own API with
+// their own credentials. Safe for concurrent use.
+type Clients struct {
+	opts []Option
+
+	mu      sync.Mutex
+	clients map[clientKey]*Client
+}
+
+type clientKey struct{ host, account string }
+
+// NewClients returns a Clients whose every Client is built with opts.
+func NewClients(opts ...Option) *Clients {
+	return &Clients{opts: opts, clients: make(map[clientKey]*Client)}
+}
+
+// For returns the Client for host and account, creating it on first use.
+// Empty values mean gh's default host and that host's active account.
+func (cs *Clients) For(host, account string) *Client {
+	key := clientKey{auth.NormalizeHostname(host), account}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if c, ok := cs.clients[key]; ok {
+		return c
+	}
+	opts := append([]Option{}, cs.opts...)
+	if key.host != "" {
+		opts = append(opts, WithHost(key.host))
+	}
+	if key.account != "" {
+		opts = append(opts, WithAccount(key.account))
+	}
+	c := NewClient(opts...)
+	cs.clients[key] = c
+	return c
+}
+
+// RateLimits returns the latest quota of every host and account a client has
+// heard from, sorted by host then account.
+func (cs *Clients) RateLimits() []RateLimit {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	var out []RateLimit
+	for _, c := range cs.clients {
+		if rl, ok := c.RateLimit(); ok {
+			out = append(out, rl)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Host != out[j].Host {
+			return out[i].Host < out[j].Host
+		}
+		return out[i].Account < out[j].Account
+	})
+	return out
+}
 
-func NewClient() *Client {
-	return &Client{}
+// endpoint resolves an API path against WithBaseURL, if set; go-gh passes
+// absolute URLs through untouched.
+func (c *Client) endpoint(path string) string {
+	return c.baseURL + path
 }
 
 type gistUpdateRequest struct {
@@ -25,36 +234,91 @@ type gistFile struct {
 	Content string `json:"content"`
 }
 
-// UpdateFile PATCHes the Gist with the file's content.
-func (c *Client) UpdateFile(gistID string, localFilePath string, content []byte) error {
-	filename := filepath.Base(localFilePath)
+// UpdateFile PATCHes filename in the Gist with content and returns the
+// timestamp of the revision it created as a unix epoch — the same clock
+// FetchGistMeta reads, so callers can record it as the last-synced remote time.
+func (c *Client) UpdateFile(gistID, filename string, content []byte) (updatedAt int64, err error) {
+	return c.UpdateFiles(gistID, map[string][]byte{filename: content})
+}
 
-	payload := gistUpdateRequest{
-		Files: map[string]gistFile{
-			filename: {
-				Content: string(content),
-			},
-		},
+// UpdateFiles PATCHes several files of one Gist in a single request, so they
+// land as one revision in the Gist's history. Returns that revision's
+// timestamp like UpdateFile.
+func (c *Client) UpdateFiles(gistID string, files map[string][]byte) (updatedAt int64, err error) {
+	payload := gistUpdateRequest{Files: make(map[string]gistFile, len(files))}
+	for name, content := range files {
+		payload.Files[name] = gistFile{Content: string(content)}
 	}
+	return c.patch(gistID, payload)
+}
 
+// gistFileEdit is a PATCH entry that renames a file. A nil *gistFileEdit
+// encodes as null, which tells the API to delete the file.
+type gistFileEdit struct {
+	Filename string `json:"filename"`
+}
+
+type gistEditRequest struct {
+	Files map[string]*gistFileEdit `json:"files"`
+}
+
+// RenameFile renames oldName to newName inside the Gist, keeping its content
+// and history. Returns the new revision's timestamp like UpdateFile.
+func (c *Client) RenameFile(gistID, oldName, newName string) (updatedAt int64, err error) {
+	return c.patch(gistID, gistEditRequest{
+		Files: map[string]*gistFileEdit{oldName: {Filename: newName}},
+	})
+}
+
+// DeleteFile removes filename from the Gist. The Gist itself is left in
+// place even if this was its last file. Returns the new revision's timestamp.
+func (c *Client) DeleteFile(gistID, filename string) (updatedAt int64, err error) {
+	return c.patch(gistID, gistEditRequest{
+		Files: map[string]*gistFileEdit{filename: nil},
+	})
+}
+
+func (c *Client) patch(gistID string, payload any) (int64, error) {
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal gist update payload: %w", err)
+		return 0, fmt.Errorf("failed to marshal gist update payload: %w", err)
 	}
 
 	apiEndpoint := fmt.Sprintf("gists/%s", gistID)
 
-	restClient, err := api.DefaultRESTClient()
+	restClient, err := c.restClient()
 	if err != nil {
-		return fmt.Errorf("failed to initialize github rest client: %w", err)
+		return 0, err
 	}
 
-	err = restClient.Patch(apiEndpoint, bytes.NewReader(payloadBytes), nil)
+	var resp gistPatchResponse
+	err = restClient.Patch(c.endpoint(apiEndpoint), bytes.NewReader(payloadBytes), &resp)
 	if err != nil {
-		return fmt.Errorf("failed to execute gist patch request: %w", err)
+		return 0, fmt.Errorf("failed to execute gist patch request: %w", err)
 	}
 
-	return nil
+	return resp.revisionTime()
+}
+
+// gistPatchResponse is the slice of the PATCH response we read back. history
+// is newest-first; its head is the revision the PATCH just created.
+type gistPatchResponse struct {
+	UpdatedAt string            `json:"updated_at"`
+	History   []gistCommitEntry `json:"history"`
+}
+
+// revisionTime prefers the newest history entry's committed_at (what
+// FetchGistMeta compares against) and falls back to the Gist's updated_at.
+func (r gistPatchResponse) revisionTime() (int64, error) {
+	raw := r.UpdatedAt
+	if len(r.History) > 0 && r.History[0].CommittedAt != "" {
+		raw = r.History[0].CommittedAt
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse gist revision time %q: %w", raw, err)
+	}
+	return t.Unix(), nil
 }
 
 type gistCreateRequest struct {
@@ -67,6 +331,12 @@ type GistResponse struct {
 	ID string `json:"id"`
 }
 
+// gistCreateResponse is GistResponse plus the revision the POST created.
+type gistCreateResponse struct {
+	ID string `json:"id"`
+	gistPatchResponse
+}
+
 type gistFetchFile struct {
 	Content string `json:"content"`
 }
@@ -80,13 +350,13 @@ type gistFetchResponse struct {
 // the Gist's updated_at as a unix epoch. GitHub does not expose a per-file
 // endpoint, so we fetch the whole Gist and pick out the entry.
 func (c *Client) FetchFile(gistID, filename string) (content []byte, gistUpdatedAt int64, err error) {
-	restClient, err := api.DefaultRESTClient()
+	restClient, err := c.restClient()
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to initialize github rest client: %w", err)
+		return nil, 0, err
 	}
 
 	var resp gistFetchResponse
-	if err := restClient.Get(fmt.Sprintf("gists/%s", gistID), &resp); err != nil {
+	if err := restClient.Get(c.endpoint(fmt.Sprintf("gists/%s", gistID)), &resp); err != nil {
 		return nil, 0, fmt.Errorf("failed to fetch gist %s: %w", gistID, err)
 	}
 
@@ -107,13 +377,13 @@ func (c *Client) FetchFile(gistID, filename string) (content []byte, gistUpdated
 // the Gist's updated_at as a unix epoch. Single API call — call this instead
 // of looping FetchFile when you need multiple files from the same Gist.
 func (c *Client) FetchAllFiles(gistID string) (files map[string][]byte, updatedAt int64, err error) {
-	restClient, err := api.DefaultRESTClient()
+	restClient, err := c.restClient()
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to initialize github rest client: %w", err)
+		return nil, 0, err
 	}
 
 	var resp gistFetchResponse
-	if err := restClient.Get(fmt.Sprintf("gists/%s", gistID), &resp); err != nil {
+	if err := restClient.Get(c.endpoint(fmt.Sprintf("gists/%s", gistID)), &resp); err != nil {
 		return nil, 0, fmt.Errorf("failed to fetch gist %s: %w", gistID, err)
 	}
 
@@ -138,13 +408,19 @@ type gistCommitEntry struct {
 // payload does not include any file content — useful for periodic polling
 // where only "did anything change" matters.
 func (c *Client) FetchGistMeta(gistID string) (updatedAt int64, err error) {
-	restClient, err := api.DefaultRESTClient()
+	return c.FetchGistMetaContext(context.Background(), gistID)
+}
+
+// FetchGistMetaContext is FetchGistMeta, abandoned when ctx ends.
+func (c *Client) FetchGistMetaContext(ctx context.Context, gistID string) (updatedAt int64, err error) {
+	restClient, err := c.restClient()
 	if err != nil {
-		return 0, fmt.Errorf("failed to initialize github rest client: %w", err)
+		return 0, err
 	}
 
 	var commits []gistCommitEntry
-	if err := restClient.Get(fmt.Sprintf("gists/%s/commits?per_page=1", gistID), &commits); err != nil {
+	path := c.endpoint(fmt.Sprintf("gists/%s/commits?per_page=1", gistID))
+	if err := restClient.DoWithContext(ctx, http.MethodGet, path, nil, &commits); err != nil {
 		return 0, fmt.Errorf("failed to fetch gist %s commits: %w", gistID, err)
 	}
 	if len(commits) == 0 {
@@ -158,39 +434,47 @@ func (c *Client) FetchGistMeta(gistID string) (updatedAt int64, err error) {
 	return t.Unix(), nil
 }
 
-func (c *Client) CreateGist(localFilePath, description string, public bool) (string, error) {
-	filename := filepath.Base(localFilePath)
-
+// CreateGist creates a Gist holding localFilePath's content under filename.
+func (c *Client) CreateGist(localFilePath, filename, description string, public bool) (string, int64, error) {
 	content, err := os.ReadFile(localFilePath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read file %s: %w", localFilePath, err)
+		return "", 0, fmt.Errorf("failed to read file %s: %w", localFilePath, err)
 	}
+	return c.CreateGistFiles(map[string][]byte{filename: content}, description, public)
+}
 
+// CreateGistFiles creates a Gist holding every entry of files, keyed by
+// filename, and returns its ID along with the time of its first revision
+// (the baseline FetchGistMeta compares against, as for UpdateFile).
+func (c *Client) CreateGistFiles(files map[string][]byte, description string, public bool) (string, int64, error) {
 	payload := gistCreateRequest{
 		Description: description,
 		Public:      public,
-		Files: map[string]gistFile{
-			filename: {
-				Content: string(content),
-			},
-		},
+		Files:       make(map[string]gistFile, len(files)),
+	}
+	for name, content := range files {
+		payload.Files[name] = gistFile{Content: string(content)}
 	}
 
 	payloadBytes, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal create gist payload: %w", err)
+		return "", 0, fmt.Errorf("failed to marshal create gist payload: %w", err)
 	}
 
-	restClient, err := api.DefaultRESTClient()
+	restClient, err := c.restClient()
 	if err != nil {
-		return "", fmt.Errorf("failed to initialize github rest client: %w", err)
+		return "", 0, err
 	}
 
-	var response GistResponse
-	err = restClient.Post("gists", bytes.NewReader(payloadBytes), &response)
+	var response gistCreateResponse
+	err = restClient.Post(c.endpoint("gists"), bytes.NewReader(payloadBytes), &response)
 	if err != nil {
-		return "", fmt.Errorf("failed to create gist via API: %w", err)
+		return "", 0, fmt.Errorf("failed to create gist via API: %w", err)
 	}
 
-	return response.ID, nil
+	updatedAt, err := response.revisionTime()
+	if err != nil {
+		return "", 0, err
+	}
+	return response.ID, updatedAt, nil
 }
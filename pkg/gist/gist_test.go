@@ -2,6 +2,7 @@ package gist
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
@@ -40,6 +41,20 @@ func TestClient_PayloadGeneration(t *testing.T) {
 	assert.JSONEq(t, expectedJSON, string(payloadBytes))
 }
 
+func TestEditPayloads(t *testing.T) {
+	rename, err := json.Marshal(gistEditRequest{
+		Files: map[string]*gistFileEdit{"old.md": {Filename: "new.md"}},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"files":{"old.md":{"filename":"new.md"}}}`, string(rename))
+
+	del, err := json.Marshal(gistEditRequest{
+		Files: map[string]*gistFileEdit{"gone.md": nil},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"files":{"gone.md":null}}`, string(del))
+}
+
 // Note: We avoid an actual integration test calling restClient.Patch() here to prevent
 // mutating the user's real GitHub account or exceeding API rate limits during standard local tests.
 
@@ -93,3 +108,73 @@ func TestGistCommitEntry_ExtractsCommittedAt(t *testing.T) {
 	require.Len(t, commits, 1)
 	assert.Equal(t, "2026-07-10T22:03:26Z", commits[0].CommittedAt)
 }
+
+func TestGistPatchResponse_RevisionTimePrefersHistory(t *testing.T) {
+	body := `{
+		"updated_at": "2026-07-12T01:26:35Z",
+		"history": [
+			{ "version": "abc", "committed_at": "2026-07-12T01:26:31Z" },
+			{ "version": "def", "committed_at": "2026-07-11T00:00:00Z" }
+		]
+	}`
+	var resp gistPatchResponse
+	require.NoError(t, json.Unmarshal([]byte(body), &resp))
+
+	got, err := resp.revisionTime()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1783819591), got)
+}
+
+func TestGistPatchResponse_RevisionTimeFallsBackToUpdatedAt(t *testing.T) {
+	resp := gistPatchResponse{UpdatedAt: "2026-07-12T01:26:35Z"}
+	got, err := resp.revisionTime()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1783819595), got)
+}
+
+func TestETagCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "etags.json")
+	c := OpenETagCache(path)
+
+	key := func(i int) string {
+		return cacheKey("github.com", "", fmt.Sprintf("https://api.github.com/gists/%d", i))
+	}
+	c.put(key(0), `"e0"`, []byte("first"))
+	for i := 1; i <= maxCacheEntries; i++ {
+		_, _ = c.get(key(0)) // keep the first one in use
+		c.put(key(i), fmt.Sprintf(`"e%d"`, i), []byte("x"))
+	}
+
+	reopened := OpenETagCache(path)
+	assert.Len(t, reopened.entries, maxCacheEntries)
+	_, ok := reopened.get(key(0))
+	assert.True(t, ok, "recently read entries survive")
+	_, ok = reopened.get(key(1))
+	assert.False(t, ok, "the least recently used entry goes")
+
+	big := make([]byte, maxCachedBody)
+	for i := 0; i < maxCacheBytes/maxCachedBody+1; i++ {
+		c.put(key(1000+i), `"big"`, big)
+	}
+	size := 0
+	for _, e := range c.entries {
+		size += len(e.Body)
+	}
+	assert.LessOrEqual(t, size, maxCacheBytes)
+
+	leftovers, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	require.NoError(t, err)
+	assert.Empty(t, leftovers)
+}
+
+func TestETagCache_KeysByAccount(t *testing.T) {
+	c := OpenETagCache("")
+	url := "https://api.github.com/gists/abc"
+	c.put(cacheKey("github.com", "alice", url), `"a"`, []byte("alice's view"))
+	_, ok := c.get(cacheKey("github.com", "bob", url))
+	assert.False(t, ok)
+	e, ok := c.get(cacheKey("github.com", "alice", url))
+	require.True(t, ok)
+	assert.Equal(t, "alice's view", string(e.Body))
+}
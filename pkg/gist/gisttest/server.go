@@ -0,0 +1,499 @@
+// Package gisttest is an in-memory fake of the slice of the Gist REST API
+// gh-automagist uses — create, get, patch (edit, rename, delete files),
+// commits and revisions — served over httptest, so client and command flows can be tested
+// end to end without the network.
+package gisttest
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+)
+
+// Token is the auth token Server accepts; Client sends it.
+const Token = "gisttest-token"
+
+// Epoch is the commit time of the first revision the server creates. Each
+// later revision is one minute after the previous one, so timestamps are
+// deterministic and strictly increasing at the API's one-second resolution.
+var Epoch = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Gist is a snapshot of one stored Gist. History is newest first, like the API.
+type Gist struct {
+	ID          string
+	Description string
+	Public      bool
+	Files       map[string]string
+	History     []Revision
+}
+
+// Revision is one entry of a Gist's history, with the Gist's files as they
+// were after it and the lines it added and deleted across them.
+type Revision struct {
+	Version     string
+	CommittedAt time.Time
+	Files       map[string]string
+	Additions   int
+	Deletions   int
+}
+
+// UpdatedAt is the commit time of the newest revision.
+func (g Gist) UpdatedAt() time.Time {
+	if len(g.History) == 0 {
+		return time.Time{}
+	}
+	return g.History[0].CommittedAt
+}
+
+// Server is a running fake Gist API. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	gists      map[string]*Gist
+	nextID     int
+	revisions  int
+	requests   []string
+	remaining  int
+	retryAfter []time.Duration
+	resetAt    time.Time
+}
+
+// RateLimit is the hourly quota the server starts with. Every request
+// except a 304 spends one unit, as on GitHub.
+const RateLimit = 5000
+
+// NewServer starts a fake Gist API that is shut down when t finishes.
+func NewServer(t testing.TB) *Server {
+	s := &Server{gists: make(map[string]*Gist), remaining: RateLimit,
+		resetAt: time.Now().Add(time.Hour).Truncate(time.Second)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a gist.Client talking to this server.
+func (s *Server) Client() *gist.Client {
+	return gist.NewClient(gist.WithBaseURL(s.URL), gist.WithAuthToken(Token))
+}
+
+// Clients returns a gist.Clients whose every host and account talks to this
+// server, built with any extra opts.
+func (s *Server) Clients(opts ...gist.Option) *gist.Clients {
+	return gist.NewClients(append([]gist.Option{gist.WithBaseURL(s.URL), gist.WithAuthToken(Token)}, opts...)...)
+}
+
+// AddGist stores a Gist with files, as if created by another tool, and
+// returns its ID.
+func (s *Server) AddGist(files map[string]string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.createLocked("", false, files)
+}
+
+// SetFile edits (or adds) one file as if someone changed the Gist in the
+// browser, recording a new revision.
+func (s *Server) SetFile(gistID, filename, content string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.gists[gistID]
+	if !ok {
+		panic(fmt.Sprintf("gisttest: no gist %s", gistID))
+	}
+	g.Files[filename] = content
+	s.commitLocked(g)
+}
+
+// Gist returns a copy of the stored Gist.
+func (s *Server) Gist(id string) (Gist, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.gists[id]
+	if !ok {
+		return Gist{}, false
+	}
+	cp := *g
+	cp.Files = make(map[string]string, len(g.Files))
+	for k, v := range g.Files {
+		cp.Files[k] = v
+	}
+	cp.History = append([]Revision(nil), g.History...)
+	return cp, true
+}
+
+// Requests returns every request served so far as "METHOD /path".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// SetRateLimitRemaining sets how much quota is left; at zero every request
+// is rejected with 403 until it is raised again. The quota claims to reset an
+// hour after the server started.
+func (s *Server) SetRateLimitRemaining(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remaining = n
+}
+
+// RateLimitRemaining returns the quota left.
+func (s *Server) RateLimitRemaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remaining
+}
+
+// Throttle rejects the next request with 429 and Retry-After: after, like a
+// GitHub secondary rate limit. Calls queue up, one rejection each.
+func (s *Server) Throttle(after time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retryAfter = append(s.retryAfter, after)
+}
+
+// CountRequests returns how many served requests had the given method.
+func (s *Server) CountRequests(method string) int {
+	n := 0
+	for _, r := range s.Requests() {
+		if strings.HasPrefix(r, method+" ") {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Server) createLocked(description string, public bool, files map[string]string) string {
+	s.nextID++
+	id := fmt.Sprintf("gist%04d", s.nextID)
+	g := &Gist{ID: id, Description: description, Public: public, Files: make(map[string]string, len(files))}
+	for k, v := range files {
+		g.Files[k] = v
+	}
+	s.gists[id] = g
+	s.commitLocked(g)
+	return id
+}
+
+func (s *Server) commitLocked(g *Gist) {
+	at := Epoch.Add(time.Duration(s.revisions) * time.Minute)
+	s.revisions++
+	rev := Revision{Version: fmt.Sprintf("v%d", s.revisions), CommittedAt: at, Files: make(map[string]string, len(g.Files))}
+	for k, v := range g.Files {
+		rev.Files[k] = v
+	}
+	var prev map[string]string
+	if len(g.History) > 0 {
+		prev = g.History[0].Files
+	}
+	rev.Additions, rev.Deletions = lineChanges(prev, rev.Files)
+	g.History = append([]Revision{rev}, g.History...)
+}
+
+// lineChanges counts the lines added and deleted going from before to after,
+// file by file. Lines are compared as multisets, not aligned like a real
+// diff; close enough for change_status in tests.
+func lineChanges(before, after map[string]string) (additions, deletions int) {
+	names := make(map[string]bool)
+	for name := range before {
+		names[name] = true
+	}
+	for name := range after {
+		names[name] = true
+	}
+	for name := range names {
+		counts := make(map[string]int)
+		for _, line := range splitLines(before[name]) {
+			counts[line]++
+		}
+		for _, line := range splitLines(after[name]) {
+			if counts[line] > 0 {
+				counts[line]--
+			} else {
+				additions++
+			}
+		}
+		for _, n := range counts {
+			deletions += n
+		}
+	}
+	return additions, deletions
+}
+
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+type fileJSON struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+type changeStatusJSON struct {
+	Additions int `json:"additions"`
+	Deletions int `json:"deletions"`
+	Total     int `json:"total"`
+}
+
+type commitJSON struct {
+	Version      string           `json:"version"`
+	CommittedAt  string           `json:"committed_at"`
+	ChangeStatus changeStatusJSON `json:"change_status"`
+}
+
+type gistJSON struct {
+	ID          string              `json:"id"`
+	Description string              `json:"description"`
+	Public      bool                `json:"public"`
+	UpdatedAt   string              `json:"updated_at"`
+	Files       map[string]fileJSON `json:"files"`
+	History     []commitJSON        `json:"history"`
+}
+
+func encodeGist(g *Gist) gistJSON {
+	out := gistJSON{
+		ID:          g.ID,
+		Description: g.Description,
+		Public:      g.Public,
+		UpdatedAt:   g.UpdatedAt().Format(time.RFC3339),
+		Files:       make(map[string]fileJSON, len(g.Files)),
+		History:     encodeHistory(g.History),
+	}
+	for name, content := range g.Files {
+		out.Files[name] = fileJSON{Filename: name, Content: content}
+	}
+	return out
+}
+
+func encodeHistory(history []Revision) []commitJSON {
+	out := make([]commitJSON, len(history))
+	for i, r := range history {
+		out[i] = commitJSON{
+			Version:      r.Version,
+			CommittedAt:  r.CommittedAt.Format(time.RFC3339),
+			ChangeStatus: changeStatusJSON{Additions: r.Additions, Deletions: r.Deletions, Total: r.Additions + r.Deletions},
+		}
+	}
+	return out
+}
+
+// handle serves one request with GitHub's quota and conditional-request
+// semantics around route: responses carry X-RateLimit-* headers and GETs an
+// ETag, and a GET whose If-None-Match still matches gets a free 304.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	rec := httptest.NewRecorder()
+	switch {
+	case len(s.retryAfter) > 0:
+		rec.Header().Set("Retry-After", fmt.Sprint(int(s.retryAfter[0].Seconds())))
+		s.retryAfter = s.retryAfter[1:]
+		writeError(rec, http.StatusTooManyRequests, "You have exceeded a secondary rate limit")
+	case s.remaining <= 0:
+		writeError(rec, http.StatusForbidden, "API rate limit exceeded")
+	default:
+		s.route(rec, r)
+	}
+
+	etag := ""
+	if r.Method == http.MethodGet && rec.Code == http.StatusOK {
+		etag = fmt.Sprintf(`"%x"`, sha256.Sum256(rec.Body.Bytes()))
+	}
+	notModified := etag != "" && r.Header.Get("If-None-Match") == etag
+	if !notModified && rec.Code != http.StatusForbidden && rec.Code != http.StatusTooManyRequests {
+		s.remaining--
+	}
+
+	for k, v := range rec.Header() {
+		w.Header()[k] = v
+	}
+	w.Header().Set("X-RateLimit-Limit", fmt.Sprint(RateLimit))
+	w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(max(s.remaining, 0)))
+	w.Header().Set("X-RateLimit-Reset", fmt.Sprint(s.resetAt.Unix()))
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if notModified {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(rec.Code)
+	_, _ = w.Write(rec.Body.Bytes())
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != "token "+Token {
+		writeError(w, http.StatusUnauthorized, "Bad credentials")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "gists" && r.Method == http.MethodPost:
+		s.create(w, r)
+	case len(parts) == 2 && parts[0] == "gists":
+		g, ok := s.gists[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, encodeGist(g))
+		case http.MethodPatch:
+			s.patch(w, r, g)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		}
+	case len(parts) == 3 && parts[0] == "gists" && parts[2] == "commits" && r.Method == http.MethodGet:
+		g, ok := s.gists[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		s.commits(w, r, g)
+	case len(parts) == 3 && parts[0] == "gists" && r.Method == http.MethodGet:
+		g, ok := s.gists[parts[1]]
+		if !ok {
+			writeError(w, http.StatusNotFound, "Not Found")
+			return
+		}
+		for _, rev := range g.History {
+			if rev.Version == parts[2] {
+				at := *g
+				at.Files = rev.Files
+				writeJSON(w, http.StatusOK, encodeGist(&at))
+				return
+			}
+		}
+		writeError(w, http.StatusNotFound, "Not Found")
+	default:
+		writeError(w, http.StatusNotFound, "Not Found")
+	}
+}
+
+// commits serves one page of g's history, 30 entries unless per_page says
+// otherwise, with a Link header pointing at the next page when there is one.
+func (s *Server) commits(w http.ResponseWriter, r *http.Request, g *Gist) {
+	page, perPage := 1, 30
+	if _, err := fmt.Sscan(r.URL.Query().Get("page"), &page); err != nil || page < 1 {
+		page = 1
+	}
+	if _, err := fmt.Sscan(r.URL.Query().Get("per_page"), &perPage); err != nil || perPage < 1 {
+		perPage = 30
+	}
+	start := min((page-1)*perPage, len(g.History))
+	end := min(start+perPage, len(g.History))
+	if end < len(g.History) {
+		w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d&per_page=%d>; rel="next"`, s.URL, r.URL.Path, page+1, perPage))
+	}
+	writeJSON(w, http.StatusOK, encodeHistory(g.History[start:end]))
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Description string `json:"description"`
+		Public      bool   `json:"public"`
+		Files       map[string]struct {
+			Content string `json:"content"`
+		} `json:"files"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+	if len(req.Files) == 0 {
+		writeError(w, http.StatusUnprocessableEntity, "Validation Failed: files missing")
+		return
+	}
+	files := make(map[string]string, len(req.Files))
+	for name, f := range req.Files {
+		if f.Content == "" {
+			writeError(w, http.StatusUnprocessableEntity, "Validation Failed: contents can't be blank")
+			return
+		}
+		files[name] = f.Content
+	}
+	id := s.createLocked(req.Description, req.Public, files)
+	writeJSON(w, http.StatusCreated, encodeGist(s.gists[id]))
+}
+
+// patch applies a files map the way the API does: {"content"} edits or adds,
+// {"filename"} renames (optionally with new content), null deletes.
+func (s *Server) patch(w http.ResponseWriter, r *http.Request, g *Gist) {
+	var req struct {
+		Description *string `json:"description"`
+		Files       map[string]*struct {
+			Content  *string `json:"content"`
+			Filename *string `json:"filename"`
+		} `json:"files"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Problems parsing JSON")
+		return
+	}
+
+	names := make([]string, 0, len(req.Files))
+	for name := range req.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		edit := req.Files[name]
+		current, exists := g.Files[name]
+		switch {
+		case edit == nil:
+			if !exists {
+				writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation Failed: %s does not exist", name))
+				return
+			}
+			delete(g.Files, name)
+		case edit.Filename != nil && *edit.Filename != name:
+			if !exists {
+				writeError(w, http.StatusUnprocessableEntity, fmt.Sprintf("Validation Failed: %s does not exist", name))
+				return
+			}
+			content := current
+			if edit.Content != nil {
+				content = *edit.Content
+			}
+			delete(g.Files, name)
+			g.Files[*edit.Filename] = content
+		case edit.Content != nil:
+			if *edit.Content == "" {
+				writeError(w, http.StatusUnprocessableEntity, "Validation Failed: contents can't be blank")
+				return
+			}
+			g.Files[name] = *edit.Content
+		}
+	}
+	if req.Description != nil {
+		g.Description = *req.Description
+	}
+	s.commitLocked(g)
+	writeJSON(w, http.StatusOK, encodeGist(g))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
@@ -0,0 +1,97 @@
+package gist
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Revision is one entry of a Gist's history: the version SHA the API
+// addresses it by, when it was committed, and its line counts across every
+// file of the Gist.
+type Revision struct {
+	Version     string
+	CommittedAt int64
+	Additions   int
+	Deletions   int
+	Total       int
+}
+
+type gistRevisionEntry struct {
+	Version      string `json:"version"`
+	CommittedAt  string `json:"committed_at"`
+	ChangeStatus struct {
+		Additions int `json:"additions"`
+		Deletions int `json:"deletions"`
+		Total     int `json:"total"`
+	} `json:"change_status"`
+}
+
+// Revisions returns page (1-based) of the Gist's history, perPage entries
+// newest first, and whether a later page exists.
+func (c *Client) Revisions(gistID string, page, perPage int) (revs []Revision, more bool, err error) {
+	restClient, err := c.restClient()
+	if err != nil {
+		return nil, false, err
+	}
+
+	path := c.endpoint(fmt.Sprintf("gists/%s/commits?page=%d&per_page=%d", gistID, page, perPage))
+	resp, err := restClient.Request(http.MethodGet, path, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch gist %s commits: %w", gistID, err)
+	}
+	defer resp.Body.Close()
+
+	var entries []gistRevisionEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, false, fmt.Errorf("failed to parse gist %s commits: %w", gistID, err)
+	}
+
+	revs = make([]Revision, 0, len(entries))
+	for _, e := range entries {
+		t, err := time.Parse(time.RFC3339, e.CommittedAt)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to parse gist committed_at %q: %w", e.CommittedAt, err)
+		}
+		revs = append(revs, Revision{
+			Version:     e.Version,
+			CommittedAt: t.Unix(),
+			Additions:   e.ChangeStatus.Additions,
+			Deletions:   e.ChangeStatus.Deletions,
+			Total:       e.ChangeStatus.Total,
+		})
+	}
+	return revs, hasNextPage(resp.Header.Get("Link")), nil
+}
+
+// hasNextPage reports whether a Link header offers a rel="next" page.
+func hasNextPage(link string) bool {
+	for _, part := range strings.Split(link, ",") {
+		if strings.Contains(part, `rel="next"`) {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchFileAt returns the content of filename as it was at the Gist's
+// revision version. ok is false when the file did not exist then — it was
+// added later, or had another name.
+func (c *Client) FetchFileAt(gistID, version, filename string) (content []byte, ok bool, err error) {
+	restClient, err := c.restClient()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var resp gistFetchResponse
+	if err := restClient.Get(c.endpoint(fmt.Sprintf("gists/%s/%s", gistID, version)), &resp); err != nil {
+		return nil, false, fmt.Errorf("failed to fetch gist %s at %s: %w", gistID, version, err)
+	}
+	f, ok := resp.Files[filename]
+	if !ok {
+		return nil, false, nil
+	}
+	return []byte(f.Content), true, nil
+}
@@ -0,0 +1,202 @@
+package gist
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRetryWait bounds how long a request waits out a Retry-After before
+// giving up with a RateLimitError instead. Variables so tests run instantly.
+var (
+	maxRetryWait = 30 * time.Second
+	now          = time.Now
+	sleep        = func(ctx context.Context, d time.Duration) error {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+)
+
+// RateLimit is the API quota a host reported on its latest response.
+// Quotas are per account; Account is empty for the host's active one.
+type RateLimit struct {
+	Host      string
+	Account   string
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// RateLimitError is returned instead of making a request the host has told
+// us to hold off on, either because the hourly quota is spent or because a
+// Retry-After was longer than we are willing to wait.
+type RateLimitError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("GitHub API rate limit reached on %s; retry after %s",
+		e.Host, e.Until.Format(time.TimeOnly))
+}
+
+// transport sits under go-gh's own round trippers. It makes GETs
+// conditional against the ETag cache, so an unchanged Gist costs a 304 that
+// does not count against the quota, records the quota headers, waits out
+// short Retry-After responses, and refuses requests while the quota is spent.
+type transport struct {
+	base    http.RoundTripper
+	host    string
+	account string
+	cache   *ETagCache
+
+	mu           sync.Mutex
+	limit        RateLimit
+	seen         bool
+	blockedUntil time.Time
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if until := t.blocked(); !until.IsZero() {
+		return nil, &RateLimitError{Host: t.host, Until: until}
+	}
+
+	key := cacheKey(t.host, t.account, req.URL.String())
+	var cached cachedResponse
+	conditional := false
+	if req.Method == http.MethodGet && t.cache != nil {
+		if cached, conditional = t.cache.get(key); conditional {
+			req = req.Clone(req.Context())
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	t.record(resp)
+
+	if wait, limited := retryAfter(resp); limited {
+		if wait <= 0 || wait > maxRetryWait || (req.Body != nil && req.GetBody == nil) {
+			resp.Body.Close()
+			return nil, t.block(wait)
+		}
+		resp.Body.Close()
+		if err := sleep(req.Context(), wait); err != nil {
+			return nil, err
+		}
+		retry := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if retry.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		if resp, err = t.base.RoundTrip(retry); err != nil {
+			return nil, err
+		}
+		t.record(resp)
+		if wait, limited := retryAfter(resp); limited {
+			resp.Body.Close()
+			return nil, t.block(wait)
+		}
+	}
+
+	switch {
+	case conditional && resp.StatusCode == http.StatusNotModified:
+		resp.Body.Close()
+		resp.StatusCode = http.StatusOK
+		resp.Status = "200 OK"
+		resp.Header.Set("Content-Type", "application/json; charset=utf-8")
+		resp.Body = io.NopCloser(bytes.NewReader(cached.Body))
+		resp.ContentLength = int64(len(cached.Body))
+	case req.Method == http.MethodGet && resp.StatusCode == http.StatusOK && t.cache != nil:
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			t.cache.put(key, etag, body)
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	}
+	return resp, nil
+}
+
+// retryAfter reports whether resp is a rate-limit rejection and how long the
+// host asked us to wait. A spent hourly quota waits until X-RateLimit-Reset.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s := resp.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0).Sub(now()), true
+		}
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		// Secondary limit without a hint: GitHub asks for at least a minute.
+		return time.Minute, true
+	}
+	return 0, false
+}
+
+func (t *transport) record(resp *http.Response) {
+	limit, err1 := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit = RateLimit{Host: t.host, Account: t.account, Limit: limit, Remaining: remaining, Reset: time.Unix(reset, 0)}
+	t.seen = true
+	if remaining == 0 && t.limit.Reset.After(t.blockedUntil) {
+		// The next request would only be rejected; don't spend it.
+		t.blockedUntil = t.limit.Reset
+	}
+}
+
+func (t *transport) block(wait time.Duration) error {
+	if wait < 0 {
+		wait = 0
+	}
+	until := now().Add(wait)
+	t.mu.Lock()
+	t.blockedUntil = until
+	t.mu.Unlock()
+	return &RateLimitError{Host: t.host, Until: until}
+}
+
+func (t *transport) blocked() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if now().Before(t.blockedUntil) {
+		return t.blockedUntil
+	}
+	return time.Time{}
+}
+
+func (t *transport) rateLimit() (RateLimit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.limit, t.seen
+}
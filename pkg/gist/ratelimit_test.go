@@ -0,0 +1,101 @@
+package gist_test
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist"
+	"github.com/noriyo_tcp/gh-automagist/pkg/gist/gisttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestETagCache_UnchangedGistCostsNoQuota(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	id := srv.AddGist(map[string]string{"a.txt": "a"})
+	cachePath := filepath.Join(t.TempDir(), "etags.json")
+	client := gist.NewClient(gist.WithBaseURL(srv.URL), gist.WithAuthToken(gisttest.Token),
+		gist.WithETagCache(gist.OpenETagCache(cachePath)))
+
+	first, err := client.FetchGistMeta(id)
+	require.NoError(t, err)
+	spent := gisttest.RateLimit - srv.RateLimitRemaining()
+
+	again, err := client.FetchGistMeta(id)
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "a 304 is answered from the cache")
+	assert.Equal(t, spent, gisttest.RateLimit-srv.RateLimitRemaining())
+
+	// The cache outlives the process.
+	fresh := gist.NewClient(gist.WithBaseURL(srv.URL), gist.WithAuthToken(gisttest.Token),
+		gist.WithETagCache(gist.OpenETagCache(cachePath)))
+	_, err = fresh.FetchGistMeta(id)
+	require.NoError(t, err)
+	assert.Equal(t, spent, gisttest.RateLimit-srv.RateLimitRemaining())
+
+	srv.SetFile(id, "a.txt", "b")
+	changed, err := client.FetchGistMeta(id)
+	require.NoError(t, err)
+	assert.Greater(t, changed, first)
+
+	rl, ok := client.RateLimit()
+	require.True(t, ok)
+	assert.Equal(t, srv.RateLimitRemaining(), rl.Remaining)
+	assert.Equal(t, gisttest.RateLimit, rl.Limit)
+}
+
+func TestRateLimit_WaitsOutShortRetryAfter(t *testing.T) {
+	var waits []time.Duration
+	defer gist.StubRateLimitWaits(10*time.Second, &waits)()
+
+	srv := gisttest.NewServer(t)
+	id := srv.AddGist(map[string]string{"a.txt": "a"})
+	srv.Throttle(3 * time.Second)
+
+	_, err := srv.Client().UpdateFile(id, "a.txt", []byte("b"))
+	require.NoError(t, err)
+	assert.Equal(t, []time.Duration{3 * time.Second}, waits)
+	g, _ := srv.Gist(id)
+	assert.Equal(t, "b", g.Files["a.txt"], "the retried PATCH carried its body")
+}
+
+func TestRateLimit_LongRetryAfterFails(t *testing.T) {
+	var waits []time.Duration
+	defer gist.StubRateLimitWaits(10*time.Second, &waits)()
+
+	srv := gisttest.NewServer(t)
+	id := srv.AddGist(map[string]string{"a.txt": "a"})
+	srv.Throttle(time.Minute)
+
+	client := srv.Client()
+	_, err := client.FetchGistMeta(id)
+	var rlErr *gist.RateLimitError
+	require.True(t, errors.As(err, &rlErr), "got %v", err)
+	assert.Empty(t, waits)
+
+	// Held off locally until the wait is over.
+	before := srv.CountRequests(http.MethodGet)
+	_, err = client.FetchGistMeta(id)
+	assert.True(t, errors.As(err, &rlErr))
+	assert.Equal(t, before, srv.CountRequests(http.MethodGet))
+}
+
+func TestRateLimit_SpentQuotaStopsRequests(t *testing.T) {
+	srv := gisttest.NewServer(t)
+	id := srv.AddGist(map[string]string{"a.txt": "a"})
+	srv.SetRateLimitRemaining(1)
+
+	client := srv.Client()
+	_, err := client.FetchGistMeta(id)
+	require.NoError(t, err, "the last unit of quota is still usable")
+
+	before := srv.CountRequests(http.MethodGet)
+	_, err = client.FetchGistMeta(id)
+	var rlErr *gist.RateLimitError
+	require.True(t, errors.As(err, &rlErr), "got %v", err)
+	assert.Equal(t, before, srv.CountRequests(http.MethodGet), "no request is spent on a certain 403")
+	assert.True(t, rlErr.Until.After(time.Now()))
+}
@@ -0,0 +1,235 @@
+package logfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxBytes is the size at which the daemon log rotates. A busy monitor
+// logs a few lines per sync, so 5 MiB holds weeks of history.
+const DefaultMaxBytes = 5 << 20
+
+// DefaultKeep is how many rotated files (monitor.log.1 … .N) are kept
+// alongside the live log.
+const DefaultKeep = 3
+
+// TimeLayout matches the prefix the standard logger writes with log.LstdFlags.
+// The daemon keeps those flags so every line is parseable here.
+const TimeLayout = "2006/01/02 15:04:05"
+
+// RotatingWriter is an io.Writer that appends to path and, once a write would
+// push the file past maxBytes, shifts path → path.1 → … → path.keep and starts
+// a fresh file. Safe for concurrent use; the standard logger already
+// serialises writes, but timer callbacks may log through other loggers.
+type RotatingWriter struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	keep     int
+	file     *os.File
+	size     int64
+}
+
+// NewRotatingWriter opens (or creates) path for appending, creating its
+// parent directory if needed.
+func NewRotatingWriter(path string, maxBytes int64, keep int) (*RotatingWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	w := &RotatingWriter{path: path, maxBytes: maxBytes, keep: keep}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *RotatingWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate closes the live file and shifts the numbered backups up by one,
+// dropping the oldest. Rename failures for missing backups are expected.
+func (w *RotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file for rotation: %w", err)
+	}
+	for i := w.keep - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+	}
+	if w.keep > 0 {
+		if err := os.Rename(w.path, w.path+".1"); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else if err := os.Remove(w.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to truncate log file: %w", err)
+	}
+	return w.open()
+}
+
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// Files returns the existing log files for path, oldest first: the highest
+// numbered rotation down to path itself. Reading them in order yields the
+// log chronologically.
+func Files(path string) []string {
+	matches, _ := filepath.Glob(path + ".*")
+	type numbered struct {
+		path string
+		n    int
+	}
+	var rotated []numbered
+	for _, m := range matches {
+		n, err := strconv.Atoi(strings.TrimPrefix(m, path+"."))
+		if err != nil {
+			continue
+		}
+		rotated = append(rotated, numbered{m, n})
+	}
+	sort.Slice(rotated, func(i, j int) bool { return rotated[i].n > rotated[j].n })
+
+	out := make([]string, 0, len(rotated)+1)
+	for _, r := range rotated {
+		out = append(out, r.path)
+	}
+	if _, err := os.Stat(path); err == nil {
+		out = append(out, path)
+	}
+	return out
+}
+
+// Level is a coarse severity inferred from a log message.
+type Level int
+
+const (
+	LevelInfo Level = iota
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// ParseLevel accepts the names printed by Level.String, plus "warning".
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level %q (want info, warn or error)", s)
+}
+
+// LevelOf classifies a message by the marker the monitor starts it with:
+// "Warning:" for recoverable problems, "[Error]" or "Error" for failures,
+// either possibly after a component tag such as "[AutoPull]". "[Conflict]"
+// lines are warnings too: each one is about an edit that is not syncing.
+// Only the start counts, so file names and error text later in the line
+// ("errors.md", "failed to") do not change the level.
+func LevelOf(msg string) Level {
+	msg = strings.TrimSpace(msg)
+	switch {
+	case strings.HasPrefix(msg, "[Error]"):
+		return LevelError
+	case strings.HasPrefix(msg, "[Conflict]"):
+		return LevelWarn
+	}
+	if strings.HasPrefix(msg, "[") {
+		if _, rest, ok := strings.Cut(msg, "] "); ok {
+			msg = rest
+		}
+	}
+	switch {
+	case strings.HasPrefix(msg, "Warning:"):
+		return LevelWarn
+	case strings.HasPrefix(msg, "Error ") || strings.HasPrefix(msg, "Error:"):
+		return LevelError
+	default:
+		return LevelInfo
+	}
+}
+
+// ParseTime extracts the log.LstdFlags timestamp prefix from line. ok is
+// false for continuation lines that the logger did not prefix.
+func ParseTime(line string) (t time.Time, ok bool) {
+	if len(line) < len(TimeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(TimeLayout, line[:len(TimeLayout)], time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// Filter selects log lines by time and minimum level. Lines without a
+// timestamp (e.g. the indented hint under a watch warning) inherit the
+// verdict of the entry above them, so multi-line entries stay whole.
+// The zero Filter matches everything.
+type Filter struct {
+	Since    time.Time
+	MinLevel Level
+
+	keep    bool
+	started bool
+}
+
+// Match reports whether line should be shown. Call it once per line, in order.
+func (f *Filter) Match(line string) bool {
+	t, ok := ParseTime(line)
+	if !ok {
+		if !f.started {
+			// Leading continuation lines with no parent: show unless filtering.
+			return f.Since.IsZero() && f.MinLevel == LevelInfo
+		}
+		return f.keep
+	}
+	f.started = true
+	f.keep = !t.Before(f.Since) && LevelOf(line[len(TimeLayout):]) >= f.MinLevel
+	return f.keep
+}
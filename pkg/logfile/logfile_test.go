@@ -0,0 +1,109 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingWriter_RotatesPastMaxBytes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.log")
+	w, err := NewRotatingWriter(path, 10, 2)
+	require.NoError(t, err)
+	defer w.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+
+	live, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth\n", string(live))
+
+	one, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Equal(t, "third\n", string(one))
+
+	two, err := os.ReadFile(path + ".2")
+	require.NoError(t, err)
+	assert.Equal(t, "second\n", string(two))
+
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err), "rotations beyond keep must be dropped")
+}
+
+func TestRotatingWriter_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitor.log")
+	require.NoError(t, os.WriteFile(path, []byte("old\n"), 0644))
+
+	w, err := NewRotatingWriter(path, DefaultMaxBytes, DefaultKeep)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("new\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old\nnew\n", string(data))
+}
+
+func TestFiles_OldestFirst(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "monitor.log")
+	for _, p := range []string{path, path + ".1", path + ".2", path + ".10", path + ".tmp"} {
+		require.NoError(t, os.WriteFile(p, nil, 0644))
+	}
+
+	assert.Equal(t, []string{path + ".10", path + ".2", path + ".1", path}, Files(path))
+}
+
+func TestFiles_MissingLogIsEmpty(t *testing.T) {
+	assert.Empty(t, Files(filepath.Join(t.TempDir(), "monitor.log")))
+}
+
+func TestLevelOf(t *testing.T) {
+	assert.Equal(t, LevelInfo, LevelOf("[Sync] Change detected in a.txt"))
+	assert.Equal(t, LevelInfo, LevelOf("  [Success] Gist updated successfully."))
+	assert.Equal(t, LevelWarn, LevelOf("Warning: failed to reload state.json: boom"))
+	assert.Equal(t, LevelError, LevelOf("  [Error] Failed to update gist: 502"))
+	assert.Equal(t, LevelError, LevelOf("[Error] fsnotify: queue overflow"))
+	assert.Equal(t, LevelError, LevelOf(" [AutoPull] Error fetching a.txt: 502"))
+	assert.Equal(t, LevelWarn, LevelOf("[AutoPull] Warning: could not check a.txt: boom"))
+	assert.Equal(t, LevelInfo, LevelOf("[Sync] errors.md updated"))
+	assert.Equal(t, LevelInfo, LevelOf("[Dir] Now tracking failed-builds.txt"))
+	assert.Equal(t, LevelWarn, LevelOf("  [Conflict] Not pushing; merge the remote copy into a.txt and save to sync"))
+	assert.Equal(t, LevelWarn, LevelOf("  [Conflict] a.txt still has conflict markers; resolve them and save to sync"))
+	assert.Equal(t, LevelWarn, LevelOf("[AutoPull] Warning: a.txt has newer remote content but unsynced local edits; run 'gh automagist pull' to resolve"))
+	assert.Equal(t, LevelWarn, LevelOf("[AutoPull] Warning: a.txt has newer remote content but an unresolved conflict; run 'gh automagist pull' to resolve"))
+	assert.Equal(t, LevelInfo, LevelOf("[Retry] warnings.md (previous attempts: 1)"))
+}
+
+func TestParseLevel(t *testing.T) {
+	l, err := ParseLevel("warning")
+	require.NoError(t, err)
+	assert.Equal(t, LevelWarn, l)
+
+	_, err = ParseLevel("debug")
+	assert.Error(t, err)
+}
+
+func TestFilter_LevelKeepsContinuationLines(t *testing.T) {
+	f := &Filter{MinLevel: LevelWarn}
+	assert.False(t, f.Match("2026/07/10 10:00:00 [gh-automagist] Watching directory: /tmp"))
+	assert.True(t, f.Match("2026/07/10 10:00:01 Warning: failed to watch directory cleanly /x: boom"))
+	assert.True(t, f.Match("  -> This is often caused by broken symlinks in the directory. Continuing anyway."))
+	assert.False(t, f.Match("2026/07/10 10:00:02 [Sync] Change detected in a.txt"))
+	assert.False(t, f.Match("  -> continuation of a hidden entry"))
+}
+
+func TestFilter_Since(t *testing.T) {
+	since := time.Date(2026, 7, 10, 10, 0, 0, 0, time.Local)
+	f := &Filter{Since: since}
+	assert.False(t, f.Match("2026/07/10 09:59:59 [Sync] Change detected in a.txt"))
+	assert.True(t, f.Match("2026/07/10 10:00:00 [Sync] Change detected in b.txt"))
+}
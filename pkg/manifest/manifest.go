@@ -0,0 +1,224 @@
+// Package manifest is the portable form of a tracked set, written by
+// `export` and read by `import` and `clone --manifest`: where each file
+// lives relative to the home directory, which Gist file it syncs with, and
+// its own sync settings. Unlike state.json it holds no machine-specific
+// paths, so it can be committed, shared, or synced through a Gist of its own.
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/noriyo_tcp/gh-automagist/pkg/state"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the manifest format this build reads.
+const CurrentVersion = 1
+
+// Manifest is a list of tracked files.
+type Manifest struct {
+	Version int     `json:"version" yaml:"version"`
+	Files   []Entry `json:"files" yaml:"files"`
+}
+
+// Entry is one tracked file. Path is "~/"-prefixed or relative to the home
+// directory, and never leaves it; Filename defaults to Path's base name. Debounce, Direction and
+// Paused mirror the state.FileState fields of the same names.
+type Entry struct {
+	Path      string `json:"path" yaml:"path"`
+	GistID    string `json:"gist_id" yaml:"gist_id"`
+	Filename  string `json:"filename,omitempty" yaml:"filename,omitempty"`
+	Host      string `json:"host,omitempty" yaml:"host,omitempty"`
+	Account   string `json:"account,omitempty" yaml:"account,omitempty"`
+	Debounce  string `json:"debounce,omitempty" yaml:"debounce,omitempty"`
+	Direction string `json:"direction,omitempty" yaml:"direction,omitempty"`
+	Paused    bool   `json:"paused,omitempty" yaml:"paused,omitempty"`
+}
+
+// RemoteName is the Gist filename e syncs with.
+func (e Entry) RemoteName() string {
+	if e.Filename != "" {
+		return e.Filename
+	}
+	return filepath.Base(e.Path)
+}
+
+// Parse decodes a manifest. YAML is a superset of JSON, so one decoder
+// reads both.
+func Parse(data []byte) (*Manifest, error) {
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if m.Version > CurrentVersion {
+		return nil, fmt.Errorf("manifest is version %d, newer than this gh-automagist understands (%d); upgrade gh-automagist", m.Version, CurrentVersion)
+	}
+	for i, e := range m.Files {
+		switch {
+		case e.Path == "":
+			return nil, fmt.Errorf("manifest entry %d has no path", i+1)
+		case !insideHome(e.Path):
+			return nil, fmt.Errorf("manifest entry %s: paths must stay inside the home directory", e.Path)
+		case e.GistID == "":
+			return nil, fmt.Errorf("manifest entry %s has no gist_id", e.Path)
+		case strings.ContainsAny(e.RemoteName(), `/\`):
+			return nil, fmt.Errorf("manifest entry %s: Gist filenames cannot contain path separators", e.Path)
+		}
+		if e.Debounce != "" {
+			if _, err := time.ParseDuration(e.Debounce); err != nil {
+				return nil, fmt.Errorf("manifest entry %s: invalid debounce: %w", e.Path, err)
+			}
+		}
+		if _, err := state.ParseDirection(e.Direction); err != nil {
+			return nil, fmt.Errorf("manifest entry %s: %w", e.Path, err)
+		}
+	}
+	return &m, nil
+}
+
+// Formats Encode writes.
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// FormatFor picks the format for a file name: JSON for ".json", else YAML.
+func FormatFor(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// Encode renders m in format, stamped with CurrentVersion.
+func Encode(m *Manifest, format string) ([]byte, error) {
+	out := *m
+	out.Version = CurrentVersion
+	if out.Files == nil {
+		out.Files = []Entry{}
+	}
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(out); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown manifest format %q (want %s or %s)", format, FormatYAML, FormatJSON)
+	}
+}
+
+// Read parses the manifest at path.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	return Parse(data)
+}
+
+// insideHome reports whether manifest path p resolves under the home
+// directory whatever that is: it must be relative and must not climb out
+// with "..". A manifest can come from someone else's Gist, and clone writes
+// wherever its paths point.
+func insideHome(p string) bool {
+	if filepath.IsAbs(p) {
+		return false
+	}
+	rel := p
+	switch {
+	case p == "~":
+		rel = "."
+	case strings.HasPrefix(p, "~/"):
+		rel = p[2:]
+	}
+	rel = filepath.Clean(filepath.FromSlash(rel))
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ExpandPath resolves a manifest path against home: "~" and "~/x" expand,
+// other relative paths are taken as relative to home, absolute ones stay
+// (Parse rejects those; clone also expands "~/" on the command line).
+func ExpandPath(p, home string) string {
+	switch {
+	case p == "~":
+		return home
+	case strings.HasPrefix(p, "~/"):
+		return filepath.Join(home, p[2:])
+	case filepath.IsAbs(p):
+		return filepath.Clean(p)
+	default:
+		return filepath.Join(home, p)
+	}
+}
+
+// HomeRelative is the inverse of ExpandPath: a path under home becomes
+// "~/"-prefixed. ok is false for any other path, which a manifest cannot
+// hold.
+func HomeRelative(absPath, home string) (p string, ok bool) {
+	rel, err := filepath.Rel(home, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	if rel == "." {
+		return "~", true
+	}
+	return "~/" + filepath.ToSlash(rel), true
+}
+
+// Remote is the Gist a manifest is synced through, remembered by `export
+// --gist` and `import --gist` so later runs need no ID.
+type Remote struct {
+	GistID   string `json:"gist_id"`
+	Filename string `json:"filename"`
+	Host     string `json:"host,omitempty"`
+	Account  string `json:"account,omitempty"`
+}
+
+// ReadRemote loads the Remote saved at path; ok is false when none is.
+func ReadRemote(path string) (r Remote, ok bool, err error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Remote{}, false, nil
+	}
+	if err != nil {
+		return Remote{}, false, fmt.Errorf("failed to read manifest Gist: %w", err)
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Remote{}, false, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return r, r.GistID != "", nil
+}
+
+// WriteRemote saves r at path.
+func WriteRemote(path string, r Remote) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to save manifest Gist: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package manifest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_YAMLAndJSON(t *testing.T) {
+	yamlDoc := `
+version: 1
+files:
+  - path: ~/.zshrc
+    gist_id: g1
+  - path: .config/nvim/init.lua
+    gist_id: g1
+    filename: nvim-init.lua
+    host: ghe.example.com
+`
+	m, err := Parse([]byte(yamlDoc))
+	require.NoError(t, err)
+	require.Len(t, m.Files, 2)
+	assert.Equal(t, ".zshrc", m.Files[0].RemoteName())
+	assert.Equal(t, "nvim-init.lua", m.Files[1].RemoteName())
+	assert.Equal(t, "ghe.example.com", m.Files[1].Host)
+
+	jsonDoc := `{"version": 1, "files": [{"path": "~/.zshrc", "gist_id": "g1"}]}`
+	m, err = Parse([]byte(jsonDoc))
+	require.NoError(t, err)
+	require.Len(t, m.Files, 1)
+	assert.Equal(t, "g1", m.Files[0].GistID)
+}
+
+func TestParse_Rejects(t *testing.T) {
+	for name, doc := range map[string]string{
+		"newer version":  `{"version": 2, "files": []}`,
+		"missing gist":   `{"files": [{"path": "~/a"}]}`,
+		"missing path":   `{"files": [{"gist_id": "g"}]}`,
+		"separator":      `{"files": [{"path": "~/a", "gist_id": "g", "filename": "x/y"}]}`,
+		"absolute path":  `{"files": [{"path": "/etc/cron.d/x", "gist_id": "g"}]}`,
+		"escapes home":   `{"files": [{"path": "~/../../etc/x", "gist_id": "g"}]}`,
+		"relative climb": `{"files": [{"path": "a/../../x", "gist_id": "g"}]}`,
+		"not a manifest": `[1, 2]`,
+	} {
+		_, err := Parse([]byte(doc))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	assert.Equal(t, "/home/u", ExpandPath("~", "/home/u"))
+	assert.Equal(t, "/home/u/.zshrc", ExpandPath("~/.zshrc", "/home/u"))
+	assert.Equal(t, "/home/u/.config/x", ExpandPath(".config/x", "/home/u"))
+	assert.Equal(t, "/etc/hosts", ExpandPath("/etc/../etc/hosts", "/home/u"))
+}
+
+func TestEncode_RoundTrips(t *testing.T) {
+	m := &Manifest{Files: []Entry{
+		{Path: "~/.zshrc", GistID: "g1", Debounce: "2s", Direction: "push"},
+		{Path: "~/.config/nvim/init.lua", GistID: "g1", Filename: "nvim-init.lua", Paused: true},
+	}}
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		data, err := Encode(m, format)
+		require.NoError(t, err, format)
+		got, err := Parse(data)
+		require.NoError(t, err, format)
+		assert.Equal(t, CurrentVersion, got.Version, format)
+		assert.Equal(t, m.Files, got.Files, format)
+	}
+	_, err := Encode(m, "toml")
+	assert.Error(t, err)
+	assert.Equal(t, FormatJSON, FormatFor("team.JSON"))
+	assert.Equal(t, FormatYAML, FormatFor("team.yml"))
+}
+
+func TestParse_RejectsBadSettings(t *testing.T) {
+	_, err := Parse([]byte(`{"files": [{"path": "~/a", "gist_id": "g", "debounce": "soon"}]}`))
+	assert.ErrorContains(t, err, "debounce")
+	_, err = Parse([]byte(`{"files": [{"path": "~/a", "gist_id": "g", "direction": "sideways"}]}`))
+	assert.ErrorContains(t, err, "direction")
+}
+
+func TestHomeRelative(t *testing.T) {
+	p, ok := HomeRelative("/home/u/.zshrc", "/home/u")
+	assert.True(t, ok)
+	assert.Equal(t, "~/.zshrc", p)
+	assert.Equal(t, "/home/u/.zshrc", ExpandPath(p, "/home/u"))
+	p, ok = HomeRelative("/home/u", "/home/u")
+	assert.True(t, ok)
+	assert.Equal(t, "~", p)
+	_, ok = HomeRelative("/home/uv/x", "/home/u")
+	assert.False(t, ok)
+	_, ok = HomeRelative("/etc/hosts", "/home/u")
+	assert.False(t, ok)
+}
+
+func TestParse_KeepsPathsInsideHome(t *testing.T) {
+	for _, p := range []string{"~", "~/.zshrc", ".config/x", "~/a/../b", "~/..rc"} {
+		_, err := Parse([]byte(`{"files": [{"path": "` + p + `", "gist_id": "g"}]}`))
+		assert.NoError(t, err, p)
+	}
+}
+
+func TestRemote_RoundTrips(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg", "manifest-gist.json")
+	_, ok, err := ReadRemote(path)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	want := Remote{GistID: "g1", Filename: "automagist-manifest.yml", Host: "ghe.example.com"}
+	require.NoError(t, WriteRemote(path, want))
+	got, ok, err := ReadRemote(path)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, want, got)
+}
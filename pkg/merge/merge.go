@@ -0,0 +1,233 @@
+package merge
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Conflict marker lines, matching git's default "merge" conflict style so
+// editors that highlight git conflicts pick these up too.
+const (
+	markerLocal  = "<<<<<<<"
+	markerSep    = "======="
+	markerRemote = ">>>>>>>"
+)
+
+// Result is the outcome of a three-way merge.
+type Result struct {
+	Content []byte
+	// Conflicts is the number of regions where local and remote both changed
+	// the same base lines differently; each is wrapped in conflict markers.
+	Conflicts int
+}
+
+// ThreeWay merges local and remote, both descended from base, line by line.
+// Regions changed on only one side take that side; regions changed
+// identically on both sides are taken once; anything else becomes a
+// conflict region labelled with localLabel / remoteLabel.
+func ThreeWay(base, local, remote []byte, localLabel, remoteLabel string) Result {
+	o := splitLines(base)
+	a := splitLines(local)
+	b := splitLines(remote)
+
+	matchA := matchIndex(o, a)
+	matchB := matchIndex(o, b)
+
+	var out bytes.Buffer
+	conflicts := 0
+	i, ia, ib := 0, 0, 0
+	for {
+		// Stable run: base line present, unchanged, on both sides.
+		for i < len(o) && matchA[i] == ia && matchB[i] == ib {
+			out.WriteString(o[i])
+			i++
+			ia++
+			ib++
+		}
+
+		// Find the next base line both sides kept; everything before it is
+		// one unstable chunk.
+		next := i
+		for next < len(o) && (matchA[next] < 0 || matchB[next] < 0) {
+			next++
+		}
+		endA, endB := len(a), len(b)
+		if next < len(o) {
+			endA, endB = matchA[next], matchB[next]
+		}
+
+		chunkO, chunkA, chunkB := o[i:next], a[ia:endA], b[ib:endB]
+		switch {
+		case len(chunkO) == 0 && len(chunkA) == 0 && len(chunkB) == 0:
+		case equalLines(chunkA, chunkO):
+			writeLines(&out, chunkB)
+		case equalLines(chunkB, chunkO), equalLines(chunkA, chunkB):
+			writeLines(&out, chunkA)
+		default:
+			conflicts++
+			out.WriteString(markerLocal + " " + localLabel + "\n")
+			writeTerminated(&out, chunkA)
+			out.WriteString(markerSep + "\n")
+			writeTerminated(&out, chunkB)
+			out.WriteString(markerRemote + " " + remoteLabel + "\n")
+		}
+
+		if next >= len(o) {
+			break
+		}
+		i, ia, ib = next, endA, endB
+	}
+
+	return Result{Content: out.Bytes(), Conflicts: conflicts}
+}
+
+// HasConflictMarkers reports whether content still contains a complete
+// conflict region written by ThreeWay — i.e. the user has not resolved it.
+func HasConflictMarkers(content []byte) bool {
+	var sawLocal, sawSep bool
+	for _, line := range splitLines(content) {
+		switch {
+		case strings.HasPrefix(line, markerLocal+" "):
+			sawLocal, sawSep = true, false
+		case sawLocal && strings.TrimRight(line, "\r\n") == markerSep:
+			sawSep = true
+		case sawSep && strings.HasPrefix(line, markerRemote+" "):
+			return true
+		}
+	}
+	return false
+}
+
+// splitLines splits after each "\n", keeping the terminator so a missing
+// final newline survives the merge unchanged.
+func splitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func equalLines(x, y []string) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(out *bytes.Buffer, lines []string) {
+	for _, l := range lines {
+		out.WriteString(l)
+	}
+}
+
+// writeTerminated is writeLines for content inside a conflict region, where
+// a final line without "\n" would glue itself to the next marker.
+func writeTerminated(out *bytes.Buffer, lines []string) {
+	for _, l := range lines {
+		out.WriteString(l)
+		if !strings.HasSuffix(l, "\n") {
+			out.WriteString("\n")
+		}
+	}
+}
+
+// matchIndex maps each line of base to its position in other along a longest
+// common subsequence, or -1 if the line was removed or changed.
+func matchIndex(base, other []string) []int {
+	m := make([]int, len(base))
+	for i := range m {
+		m[i] = -1
+	}
+	for _, p := range lcs(base, other) {
+		m[p[0]] = p[1]
+	}
+	return m
+}
+
+// maxEditDistance bounds the Myers search in lcs. Its trace grows with the
+// square of the edit distance, so past this many inserted plus deleted lines
+// (about 32 MiB of trace) lcs gives up and reports no common lines.
+const maxEditDistance = 2000
+
+// lcs returns the (i, j) index pairs of a longest common subsequence of a and
+// b in increasing order, using Myers' O(ND) algorithm so that large files
+// with small edits stay cheap. When a and b are more than maxEditDistance
+// lines apart it returns nil, which makes ThreeWay treat the whole file as
+// one region: taken from the side that changed, or a single conflict.
+func lcs(a, b []string) [][2]int {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	off := max
+	v := make([]int, 2*max+2)
+	// trace[d] holds v[-d..d] after step d, enough to walk the path back.
+	var trace [][]int
+
+	final := -1
+	for d := 0; d <= max && final < 0; d++ {
+		if d > maxEditDistance {
+			return nil
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				final = d
+			}
+		}
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+	}
+
+	var pairs [][2]int
+	x, y := n, m
+	for d := final; d > 0; d-- {
+		prev := trace[d-1] // indexed by k + (d-1)
+		at := func(k int) int { return prev[k+d-1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			pairs = append(pairs, [2]int{x - 1, y - 1})
+			x--
+			y--
+		}
+		x, y = prevX, prevY
+	}
+	for x > 0 && y > 0 {
+		pairs = append(pairs, [2]int{x - 1, y - 1})
+		x--
+		y--
+	}
+
+	for l, r := 0, len(pairs)-1; l < r; l, r = l+1, r-1 {
+		pairs[l], pairs[r] = pairs[r], pairs[l]
+	}
+	return pairs
+}
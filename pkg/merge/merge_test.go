@@ -0,0 +1,129 @@
+package merge
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func lines(ls ...string) []byte {
+	return []byte(strings.Join(ls, "\n") + "\n")
+}
+
+func TestThreeWay_NonOverlappingEditsMergeCleanly(t *testing.T) {
+	base := lines("a", "b", "c", "d", "e")
+	local := lines("A", "b", "c", "d", "e")
+	remote := lines("a", "b", "c", "d", "E")
+
+	r := ThreeWay(base, local, remote, "local", "remote")
+	assert.Equal(t, 0, r.Conflicts)
+	assert.Equal(t, string(lines("A", "b", "c", "d", "E")), string(r.Content))
+}
+
+func TestThreeWay_OneSideUnchangedTakesOther(t *testing.T) {
+	base := lines("a", "b")
+	remote := lines("a", "b", "c")
+
+	r := ThreeWay(base, base, remote, "local", "remote")
+	assert.Equal(t, 0, r.Conflicts)
+	assert.Equal(t, string(remote), string(r.Content))
+}
+
+func TestThreeWay_IdenticalChangesOnBothSides(t *testing.T) {
+	base := lines("a", "b", "c")
+	both := lines("a", "B", "c")
+
+	r := ThreeWay(base, both, both, "local", "remote")
+	assert.Equal(t, 0, r.Conflicts)
+	assert.Equal(t, string(both), string(r.Content))
+}
+
+func TestThreeWay_OverlappingEditsConflict(t *testing.T) {
+	base := lines("a", "b", "c")
+	local := lines("a", "local", "c")
+	remote := lines("a", "remote", "c")
+
+	r := ThreeWay(base, local, remote, "local", "gist abc")
+	require.Equal(t, 1, r.Conflicts)
+	assert.Equal(t, strings.Join([]string{
+		"a",
+		"<<<<<<< local",
+		"local",
+		"=======",
+		"remote",
+		">>>>>>> gist abc",
+		"c",
+	}, "\n")+"\n", string(r.Content))
+	assert.True(t, HasConflictMarkers(r.Content))
+}
+
+func TestThreeWay_InsertionsAtSamePointConflict(t *testing.T) {
+	base := lines("a", "z")
+	local := lines("a", "x", "z")
+	remote := lines("a", "y", "z")
+
+	r := ThreeWay(base, local, remote, "local", "remote")
+	assert.Equal(t, 1, r.Conflicts)
+}
+
+func TestThreeWay_PreservesMissingFinalNewline(t *testing.T) {
+	base := []byte("a\nb")
+	local := []byte("A\nb")
+	remote := []byte("a\nb")
+
+	r := ThreeWay(base, local, remote, "local", "remote")
+	assert.Equal(t, 0, r.Conflicts)
+	assert.Equal(t, "A\nb", string(r.Content))
+}
+
+func TestThreeWay_ConflictOnUnterminatedLastLineKeepsMarkersOnOwnLines(t *testing.T) {
+	r := ThreeWay([]byte("x"), []byte("l"), []byte("r"), "local", "remote")
+	require.Equal(t, 1, r.Conflicts)
+	assert.Equal(t, "<<<<<<< local\nl\n=======\nr\n>>>>>>> remote\n", string(r.Content))
+}
+
+func TestThreeWay_EmptyBase(t *testing.T) {
+	r := ThreeWay(nil, lines("a"), nil, "local", "remote")
+	assert.Equal(t, 0, r.Conflicts)
+	assert.Equal(t, "a\n", string(r.Content))
+}
+
+func TestHasConflictMarkers_IgnoresLoneMarkers(t *testing.T) {
+	assert.False(t, HasConflictMarkers(lines("# heading", "=======", "text")))
+	assert.False(t, HasConflictMarkers(lines("<<<<<<< local", "only half")))
+}
+
+func TestLCS(t *testing.T) {
+	a := []string{"a", "b", "c", "a", "b", "b", "a"}
+	b := []string{"c", "b", "a", "b", "a", "c"}
+	pairs := lcs(a, b)
+	assert.Len(t, pairs, 4, "classic Myers example has an LCS of length 4")
+	for _, p := range pairs {
+		assert.Equal(t, a[p[0]], b[p[1]])
+	}
+	for i := 1; i < len(pairs); i++ {
+		assert.Less(t, pairs[i-1][0], pairs[i][0])
+		assert.Less(t, pairs[i-1][1], pairs[i][1])
+	}
+}
+
+func TestThreeWay_HugeRewriteFallsBackToWholeFileConflict(t *testing.T) {
+	var base, local, remote []string
+	for i := 0; i < maxEditDistance; i++ {
+		base = append(base, fmt.Sprintf("base %d", i))
+		local = append(local, fmt.Sprintf("local %d", i))
+		remote = append(remote, fmt.Sprintf("remote %d", i))
+	}
+	assert.Nil(t, lcs(base, local), "past maxEditDistance lcs gives up")
+
+	r := ThreeWay(lines(base...), lines(local...), lines(remote...), "local", "remote")
+	assert.Equal(t, 1, r.Conflicts)
+	assert.True(t, strings.HasPrefix(string(r.Content), "<<<<<<< local\nlocal 0\n"))
+
+	r = ThreeWay(lines(base...), lines(base...), lines(remote...), "local", "remote")
+	assert.Zero(t, r.Conflicts, "a side that did not change still yields to the other")
+	assert.Equal(t, lines(remote...), r.Content)
+}
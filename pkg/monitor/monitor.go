@@ -1,9 +1,14 @@
 package monitor
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
+	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -20,24 +25,91 @@ import (
 // / GH_AUTOMAGIST_DEBOUNCE_INTERVAL env var wired up in cmd/monitor.go.
 const DefaultDebounceInterval = 5 * time.Second
 
+// errNoChange aborts a state.Manager.Update that turned out to have nothing
+// to write.
+var errNoChange = errors.New("no change")
+
+// DefaultVanishGrace is how long a tracked file may be gone before the
+// watcher treats it as renamed or deleted. Editors that save by writing a
+// new file and renaming it over the old one recreate the path within
+// milliseconds, so this only has to outlast that dance.
+const DefaultVanishGrace = 2 * time.Second
+
 // Watcher watches the parent directories of tracked files and calls OnChange on write.
+// It also watches state.json and dirs.json, so files added or removed by other
+// commands are picked up (or dropped) on the fly without restarting the
+// daemon, and new files matching a directory rule are tracked as they appear.
 type Watcher struct {
 	watcher      *fsnotify.Watcher
 	stateManager *state.Manager
 	OnChange     func(absPath string, gistID string) // Callback when a watched file changes
-	done         chan bool
+	// OnBatch, when set, replaces OnChange: it receives every file of gistID
+	// that changed during the Gist's debounce window, so they can go up in
+	// one PATCH and land as one revision in the Gist's history.
+	OnBatch func(gistID string, absPaths []string)
+	done    chan bool
 
 	// DebounceInterval overrides DefaultDebounceInterval; must be set before Start().
 	// A zero or negative value disables debouncing.
 	DebounceInterval time.Duration
 
+	// VanishGrace overrides DefaultVanishGrace; must be set before Start().
+	VanishGrace time.Duration
+
+	// OnRename is called after a renamed tracked file has been re-keyed in
+	// state.json, and OnMissing after a deleted one was marked StatusMissing.
+	// Both run on a goroutine of their own, like OnBatch, so the Gist calls
+	// they make never stall the event loop; Stop waits for them.
+	OnRename  func(oldPath, newPath string)
+	OnMissing func(absPath string)
+
+	// timers holds one debounce window per Gist ID.
 	timersMu sync.Mutex
 	timers   map[string]*debounceEntry
+	// inflight counts debounce callbacks that have claimed their entry and
+	// are running OnChange, and running OnRename/OnMissing calls, so Stop
+	// can wait for them before returning.
+	inflight sync.WaitGroup
+
+	// Owned by the Start() event loop; never touched from timer callbacks.
+	// snap is the tracked state the loop works from: callbacks Update the
+	// shared stateManager from their own goroutines, so the loop never reads
+	// stateManager.Files itself, only this copy taken after each reload.
+	snap        state.Snapshot
+	stateDir    string
+	watchedDirs map[string]bool
+	tracked     map[string]bool
+	rules       map[string]bool
+	// held is the tracked files that currently do not push (paused or
+	// pull-only), so reconcile notices when one is resumed.
+	held map[string]bool
+	// vanished holds tracked paths removed or renamed away and waiting out
+	// VanishGrace, each with whether it had a sync pending when it went;
+	// created holds untracked files that recently appeared in a watched
+	// directory, the candidates for a rename's new name; seen is what the
+	// loop last saw of each tracked file, which a rename right after an
+	// edit carries instead of the last-synced ContentSHA.
+	vanished map[string]bool
+	created  map[string]time.Time
+	seen     map[string]seenFile
+	vanishCh chan string
+}
+
+// seenFile is a tracked file as the event loop last saw it: the file itself,
+// which a rename keeps however late its write events are handled, and the
+// hash of its content, which survives a copy-and-delete move too.
+type seenFile struct {
+	info os.FileInfo
+	sha  string
 }
 
+// debounceEntry is one Gist's armed debounce window; paths are its files
+// that changed during the window.
 type debounceEntry struct {
-	timer  *time.Timer
-	gistID string
+	timer    *time.Timer
+	gistID   string
+	paths    map[string]bool
+	interval time.Duration // the longest debounce among paths
 }
 
 func NewWatcher(sm *state.Manager) (*Watcher, error) {
@@ -49,39 +121,33 @@ func NewWatcher(sm *state.Manager) (*Watcher, error) {
 	return &Watcher{
 		watcher:          w,
 		stateManager:     sm,
+		snap:             sm.Snapshot(),
 		done:             make(chan bool),
 		DebounceInterval: DefaultDebounceInterval,
+		VanishGrace:      DefaultVanishGrace,
 		timers:           make(map[string]*debounceEntry),
+		watchedDirs:      make(map[string]bool),
+		vanished:         make(map[string]bool),
+		created:          make(map[string]time.Time),
+		seen:             make(map[string]seenFile),
+		vanishCh:         make(chan string),
 	}, nil
 }
 
 // Start runs the event loop; blocks until Stop().
 func (w *Watcher) Start() error {
-	// 1. Add all directories containing tracked files to the watcher
-	// fsnotify works best by watching the parent directory to catch vim/editor "save by replace" events.
-	dirsToWatch := make(map[string]bool)
-	for absPath := range w.stateManager.Files {
-		dir := filepath.Dir(absPath)
-		dirsToWatch[dir] = true
+	// 1. Watch state.json's directory so edits by add/remove reach us. Save()
+	// replaces the file via rename, so watching the file itself would lose
+	// the watch after the first write.
+	w.stateDir = filepath.Dir(w.stateManager.StatePath())
+	if err := w.watcher.Add(w.stateDir); err != nil {
+		log.Printf("Warning: failed to watch %s; tracked-file changes need a restart: %v", w.stateDir, err)
 	}
 
-	for dir := range dirsToWatch {
-		// When using fsnotify.Add(), macOS FSEvents might attempt to scan the directory.
-		// If the directory contains broken symlinks (e.g., dangling dotfiles), it can throw an error like:
-		// "no such file or directory". We should catch this but not let it crash the whole monitor.
-		// With go's fsnotify, if we add a path ending in `/...`, it watches recursively, but we are just adding `dir`.
-		err := w.watcher.Add(dir)
-		if err != nil {
-			log.Printf("Warning: failed to watch directory cleanly %s: %v", dir, err)
-			log.Printf("  -> This is often caused by broken symlinks in the directory. Continuing anyway.")
-			// We intentionally do not 'continue' or 'return' here, because fsnotify often still succeeds
-			// in watching the valid files in the directory despite throwing an error on the broken symlink.
-		} else {
-			log.Printf("[gh-automagist] Watching directory: %s", dir)
-		}
-	}
+	// 2. Add all directories containing tracked files to the watcher
+	w.reconcile()
 
-	// 2. Start the event loop
+	// 3. Start the event loop
 	for {
 		select {
 		case event, ok := <-w.watcher.Events:
@@ -89,21 +155,72 @@ func (w *Watcher) Start() error {
 				return nil
 			}
 
-			// We are only interested in Write or Create events (editors sometimes Create/Rename instead of Write)
+			if event.Name == w.stateManager.DirsPath() {
+				// Also on Remove: Save deletes dirs.json when the last rule goes.
+				if err := w.stateManager.Load(); err != nil {
+					log.Printf("Warning: failed to reload dirs.json: %v", err)
+					continue
+				}
+				w.snap = w.stateManager.Snapshot()
+				w.reconcile()
+				continue
+			}
+			if event.Name == w.stateManager.StatePath() {
+				if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
+					if err := w.stateManager.Load(); err != nil {
+						log.Printf("Warning: failed to reload state.json: %v", err)
+						continue
+					}
+					w.snap = w.stateManager.Snapshot()
+					w.reconcile()
+				}
+				continue
+			}
+
+			_, isTracked := w.snap.Files[event.Name]
+			if isTracked && (event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)) {
+				w.noteVanished(event.Name)
+			}
+			if !isTracked && (event.Has(fsnotify.Write) || event.Has(fsnotify.Create)) {
+				if rule, ok := w.snap.RuleFor(event.Name); ok && w.adopt(event.Name, rule) {
+					isTracked = true
+				} else if event.Has(fsnotify.Create) {
+					w.created[event.Name] = time.Now()
+				}
+			}
+
+			// Write or Create mean new content (editors sometimes Create/Rename instead of Write)
 			if event.Has(fsnotify.Write) || event.Has(fsnotify.Create) {
-				if _, isTracked := w.stateManager.Files[event.Name]; isTracked {
+				if isTracked {
 					log.Printf("[Sync] Change detected in %s", filepath.Base(event.Name))
+					w.see(event.Name, true)
 
-					// Reload so a concurrent `gh automagist pull` write is not
-					// clobbered by our Save() below.
-					if err := w.stateManager.Load(); err != nil {
-						log.Printf("Warning: failed to reload state.json: %v", err)
+					var fs state.FileState
+					stillTracked, err := w.stateManager.UpdateFile(event.Name, func(cur *state.FileState) {
+						if cur.Status == state.StatusMissing {
+							log.Printf("[Restore] %s is back", event.Name)
+							cur.Status = state.StatusActive
+						}
+						// A file that does not push keeps its last-sync time,
+						// so pull and a later resume still see the edit.
+						if cur.Pushes() {
+							cur.UpdatedAt = time.Now().Unix()
+						}
+						fs = *cur
+					})
+					if err != nil {
+						// Still sync the edit; only its timestamp went unrecorded.
+						log.Printf("Warning: failed to update state.json: %v", err)
+						fs, stillTracked = w.snap.Files[event.Name]
 					}
-					if fileState, stillTracked := w.stateManager.Files[event.Name]; stillTracked {
-						fileState.UpdatedAt = time.Now().Unix()
-						w.stateManager.Files[event.Name] = fileState
-						w.stateManager.Save()
-						w.scheduleSync(event.Name, fileState.GistID)
+					switch {
+					case !stillTracked:
+					case fs.Paused:
+						log.Printf("[Sync] %s is paused; not uploading", filepath.Base(event.Name))
+					case !fs.Pushes():
+						log.Printf("[Sync] %s is pull-only; not uploading", filepath.Base(event.Name))
+					default:
+						w.scheduleSync(event.Name, fs.GistID)
 					}
 				}
 			}
@@ -112,7 +229,10 @@ func (w *Watcher) Start() error {
 			if !ok {
 				return nil
 			}
-			log.Printf("fsnotify error: %v", err)
+			log.Printf("[Error] fsnotify: %v", err)
+
+		case absPath := <-w.vanishCh:
+			w.resolveVanished(absPath)
 
 		case <-w.done:
 			log.Println("[gh-automagist] Stopping file monitor...")
@@ -121,63 +241,520 @@ func (w *Watcher) Start() error {
 	}
 }
 
-// scheduleSync arms (or resets) the per-file debounce timer. gistID is captured
-// in the timer's closure so the AfterFunc callback never touches stateManager.Files
-// concurrently with the Start() event loop.
-func (w *Watcher) scheduleSync(absPath, gistID string) {
-	if w.DebounceInterval <= 0 {
-		if w.OnChange != nil {
-			w.OnChange(absPath, gistID)
+// noteVanished starts the VanishGrace wait for a tracked path that was
+// removed or renamed away; the event loop resolves it afterwards. Whether a
+// sync was pending is noted now: a short debounce may fire it during the
+// wait.
+func (w *Watcher) noteVanished(absPath string) {
+	if _, ok := w.vanished[absPath]; ok {
+		return
+	}
+	w.vanished[absPath] = w.HasPendingSync(absPath)
+	time.AfterFunc(w.VanishGrace, func() {
+		select {
+		case w.vanishCh <- absPath:
+		case <-w.done:
+		}
+	})
+}
+
+// resolveVanished decides what happened to a tracked file that disappeared:
+// nothing if it is back (a replace-style save), a rename if an untracked file
+// with its last-synced or last-seen content appeared in a watched directory
+// meanwhile, and a deletion otherwise. A deletion of a file with an edit not
+// yet synced is only recorded, never handed to OnMissing: that edit may be
+// a rename this could not match, and propagating it would lose the file.
+func (w *Watcher) resolveVanished(oldPath string) {
+	pending := w.vanished[oldPath] || w.HasPendingSync(oldPath)
+	delete(w.vanished, oldPath)
+	if _, err := os.Stat(oldPath); err == nil {
+		return
+	}
+	seen := w.seen[oldPath]
+	delete(w.seen, oldPath)
+
+	var fs state.FileState
+	var newPath string
+	err := w.stateManager.Update(func(files map[string]state.FileState) error {
+		cur, ok := files[oldPath]
+		if !ok || cur.Status == state.StatusMissing {
+			return errNoChange
+		}
+		if newPath = w.findRenameTarget(seen.info, cur.ContentSHA, seen.sha); newPath != "" {
+			// Keep syncing to the same Gist file under the new local name.
+			cur.RemoteFilename = cur.RemoteName(oldPath)
+			if cur.RemoteFilename == filepath.Base(newPath) {
+				cur.RemoteFilename = ""
+			}
+			delete(files, oldPath)
+			files[newPath] = cur
+		} else {
+			cur.Status = state.StatusMissing
+			files[oldPath] = cur
+		}
+		fs = cur
+		return nil
+	})
+	switch {
+	case errors.Is(err, errNoChange):
+		return
+	case err != nil && newPath != "":
+		log.Printf("Warning: failed to record rename of %s: %v", oldPath, err)
+		return
+	case err != nil:
+		log.Printf("Warning: failed to mark %s missing: %v", oldPath, err)
+		return
+	}
+	w.cancelSync(oldPath)
+
+	if newPath != "" {
+		log.Printf("[Rename] %s -> %s", oldPath, newPath)
+		w.reconcile()
+		if pending {
+			w.scheduleSync(newPath, fs.GistID)
+		}
+		if w.OnRename != nil {
+			w.async(func() { w.OnRename(oldPath, newPath) })
+		}
+		return
+	}
+
+	if pending {
+		log.Printf("[Missing] %s vanished with an edit not yet synced; not propagating the deletion", oldPath)
+		return
+	}
+	w.async(func() { w.dropMissing(oldPath) })
+}
+
+// dropMissing hands a deleted file to OnMissing, then untracks it if it
+// belongs to a directory rule. The event loop picks the change up from
+// state.json.
+func (w *Watcher) dropMissing(oldPath string) {
+	if w.OnMissing != nil {
+		w.OnMissing(oldPath)
+	}
+
+	// A directory rule's files follow the directory: drop the entry rather
+	// than keep it around as missing. OnMissing may already have.
+	kept := false
+	err := w.stateManager.Update(func(files map[string]state.FileState) error {
+		cur, ok := files[oldPath]
+		if !ok || cur.Dir == "" {
+			kept = ok
+			return errNoChange
+		}
+		delete(files, oldPath)
+		return nil
+	})
+	switch {
+	case err == nil:
+		log.Printf("[Dir] %s was deleted; no longer tracking it", oldPath)
+	case !errors.Is(err, errNoChange):
+		log.Printf("Warning: failed to untrack %s: %v", oldPath, err)
+	case kept:
+		log.Printf("[Missing] %s was deleted; its Gist link is kept until it is restored or removed", oldPath)
+	}
+}
+
+// findRenameTarget returns the most recently created untracked file that is
+// old itself or whose content hashes to one of shas, or "" if there is none.
+// Only files created within twice VanishGrace count; older entries are
+// dropped.
+func (w *Watcher) findRenameTarget(old os.FileInfo, shas ...string) string {
+	cutoff := time.Now().Add(-2 * w.VanishGrace)
+	var best string
+	var bestAt time.Time
+	for p, at := range w.created {
+		if at.Before(cutoff) {
+			delete(w.created, p)
+			continue
+		}
+		if w.tracked[p] || !at.After(bestAt) {
+			continue
+		}
+		if info, err := os.Stat(p); err == nil && old != nil && os.SameFile(info, old) {
+			best, bestAt = p, at
+			continue
+		}
+		sha, err := fileSHA(p)
+		if err != nil {
+			continue
+		}
+		for _, want := range shas {
+			if want != "" && want == sha {
+				best, bestAt = p, at
+				break
+			}
+		}
+	}
+	if best != "" {
+		delete(w.created, best)
+	}
+	return best
+}
+
+// see records absPath in w.seen, hashing its content too when withSHA is set.
+// A file already gone keeps what was seen before.
+func (w *Watcher) see(absPath string, withSHA bool) {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return
+	}
+	seen := seenFile{info: info}
+	if withSHA {
+		if seen.sha, err = fileSHA(absPath); err != nil {
+			return
+		}
+	}
+	w.seen[absPath] = seen
+}
+
+// fileSHA is the hex SHA-256 of absPath's content.
+func fileSHA(absPath string) (string, error) {
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// reconcile brings the fsnotify watch list and pending debounce timers in
+// line with the tracked set in the event loop's snapshot. Called at
+// startup and whenever state.json changes on disk; the diff is empty for the
+// watcher's own UpdatedAt saves, so those are effectively no-ops.
+func (w *Watcher) reconcile() {
+	w.reconcileRules()
+
+	tracked := make(map[string]bool, len(w.snap.Files))
+	for absPath := range w.snap.Files {
+		tracked[absPath] = true
+		if !w.tracked[absPath] {
+			w.see(absPath, false)
+		}
+		if w.tracked != nil && !w.tracked[absPath] { // nil on the initial pass
+			log.Printf("[Reload] Now tracking %s", absPath)
+		}
+	}
+	for absPath := range w.tracked {
+		if !tracked[absPath] {
+			log.Printf("[Reload] No longer tracking %s", absPath)
+			w.cancelSync(absPath)
+			delete(w.seen, absPath)
 		}
+	}
+	w.tracked = tracked
+	w.reconcileHeld()
+
+	// fsnotify works best by watching the parent directory to catch vim/editor "save by replace" events.
+	dirsToWatch := make(map[string]bool)
+	for absPath := range tracked {
+		dirsToWatch[filepath.Dir(absPath)] = true
+	}
+	for dir := range w.snap.Dirs {
+		dirsToWatch[dir] = true
+	}
+
+	for dir := range dirsToWatch {
+		if w.watchedDirs[dir] {
+			continue
+		}
+		// When using fsnotify.Add(), macOS FSEvents might attempt to scan the directory.
+		// If the directory contains broken symlinks (e.g., dangling dotfiles), it can throw an error like:
+		// "no such file or directory". We should catch this but not let it crash the whole monitor.
+		// With go's fsnotify, if we add a path ending in `/...`, it watches recursively, but we are just adding `dir`.
+		err := w.watcher.Add(dir)
+		if err != nil {
+			log.Printf("Warning: failed to watch directory cleanly %s: %v", dir, err)
+			log.Printf("  -> This is often caused by broken symlinks in the directory. Continuing anyway.")
+			// We intentionally do not 'continue' or 'return' here, because fsnotify often still succeeds
+			// in watching the valid files in the directory despite throwing an error on the broken symlink.
+		} else {
+			log.Printf("[gh-automagist] Watching directory: %s", dir)
+		}
+		w.watchedDirs[dir] = true
+	}
+
+	for dir := range w.watchedDirs {
+		if dirsToWatch[dir] {
+			continue
+		}
+		delete(w.watchedDirs, dir)
+		if dir == w.stateDir {
+			continue // still needed for state.json itself
+		}
+		if err := w.watcher.Remove(dir); err != nil {
+			log.Printf("Warning: failed to unwatch directory %s: %v", dir, err)
+		} else {
+			log.Printf("[gh-automagist] Stopped watching directory: %s", dir)
+		}
+	}
+}
+
+// reconcileHeld drops pending syncs of files that stopped pushing, and
+// schedules one for a file that pushes again (resumed, or no longer
+// pull-only) if it was edited since its last sync.
+func (w *Watcher) reconcileHeld() {
+	held := make(map[string]bool)
+	for absPath, fs := range w.snap.Files {
+		if !fs.Pushes() {
+			held[absPath] = true
+			if w.held != nil && !w.held[absPath] {
+				w.cancelSync(absPath)
+			}
+			continue
+		}
+		if !w.held[absPath] {
+			continue
+		}
+		info, err := os.Stat(absPath)
+		if err != nil || info.ModTime().Unix() <= fs.UpdatedAt {
+			continue
+		}
+		log.Printf("[Resume] %s changed while held back; syncing", absPath)
+		w.scheduleSync(absPath, fs.GistID)
+	}
+	w.held = held
+}
+
+// reconcileRules scans directories whose rule is new since the last pass
+// (every rule, at startup) and tracks the matching files not tracked yet:
+// files created while the monitor was down, or before `add --dir` recorded
+// the rule. Those are synced like any other change.
+func (w *Watcher) reconcileRules() {
+	rules := make(map[string]bool, len(w.snap.Dirs))
+	for dir, rule := range w.snap.Dirs {
+		rules[dir] = true
+		if w.rules[dir] {
+			continue
+		}
+		if w.rules != nil {
+			log.Printf("[Reload] Now tracking directory %s", dir)
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			log.Printf("Warning: failed to scan %s: %v", dir, err)
+			continue
+		}
+		for _, e := range entries {
+			absPath := filepath.Join(dir, e.Name())
+			if _, ok := w.snap.Files[absPath]; ok || !rule.Matches(absPath) {
+				continue
+			}
+			if w.adopt(absPath, rule) {
+				w.scheduleSync(absPath, rule.GistID)
+			}
+		}
+	}
+	for dir := range w.rules {
+		if !rules[dir] {
+			log.Printf("[Reload] No longer tracking directory %s", dir)
+		}
+	}
+	w.rules = rules
+}
+
+// adopt starts tracking absPath under rule, reporting whether it is now
+// tracked. Non-regular files, and names the rule's Gist already uses for
+// another tracked file, are left alone.
+func (w *Watcher) adopt(absPath string, rule state.DirRule) bool {
+	info, err := os.Stat(absPath)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+	name := filepath.Base(absPath)
+	alreadyTracked := false
+	err = w.stateManager.Update(func(files map[string]state.FileState) error {
+		if _, ok := files[absPath]; ok {
+			alreadyTracked = true
+			return errNoChange
+		}
+		if other, taken := (state.Snapshot{Files: files}).FindRemote(rule.GistID, name); taken {
+			log.Printf("[Dir] Not tracking %s: Gist %s already syncs %s as %s", absPath, rule.GistID, other, name)
+			return errNoChange
+		}
+		fs := state.NewFileState(rule.GistID, time.Now().Unix())
+		fs.Dir = rule.Dir
+		fs.Host, fs.Account = rule.Host, rule.Account
+		files[absPath] = fs
+		return nil
+	})
+	if errors.Is(err, errNoChange) {
+		return alreadyTracked
+	}
+	if err != nil {
+		log.Printf("Warning: failed to track %s: %v", absPath, err)
+		return false
+	}
+	if w.tracked != nil {
+		w.tracked[absPath] = true
+	}
+	w.snap = w.stateManager.Snapshot()
+	log.Printf("[Dir] Now tracking %s", absPath)
+	return true
+}
+
+// HasPendingSync reports whether absPath has a debounced sync armed, i.e. a
+// local edit the watcher has seen but not yet handed to OnChange.
+func (w *Watcher) HasPendingSync(absPath string) bool {
+	w.timersMu.Lock()
+	defer w.timersMu.Unlock()
+	for _, entry := range w.timers {
+		if entry.paths[absPath] {
+			return true
+		}
+	}
+	return false
+}
+
+// cancelSync drops a pending debounced sync for a file that is no longer
+// tracked, leaving the rest of its window armed.
+func (w *Watcher) cancelSync(absPath string) {
+	w.timersMu.Lock()
+	defer w.timersMu.Unlock()
+	for key, entry := range w.timers {
+		if !entry.paths[absPath] {
+			continue
+		}
+		delete(entry.paths, absPath)
+		if len(entry.paths) == 0 {
+			entry.timer.Stop()
+			delete(w.timers, key)
+		}
+	}
+}
+
+// debounceFor is absPath's own debounce interval, or DebounceInterval.
+func (w *Watcher) debounceFor(absPath string) time.Duration {
+	if d, ok := w.snap.Files[absPath].DebounceInterval(); ok {
+		return d
+	}
+	return w.DebounceInterval
+}
+
+// scheduleSync adds absPath to gistID's debounce window and (re)starts its
+// timer, so a burst of edits across several files of one Gist becomes a
+// single upload. The window lasts the longest debounce of the files in it.
+// gistID is captured in the timer's closure so the AfterFunc callback never
+// reads the event loop's snapshot. Called on the event loop only.
+func (w *Watcher) scheduleSync(absPath, gistID string) {
+	interval := w.debounceFor(absPath)
+	if interval <= 0 {
+		w.cancelSync(absPath)
+		w.fire(gistID, []string{absPath})
 		return
 	}
 
 	w.timersMu.Lock()
 	defer w.timersMu.Unlock()
 
-	if entry, ok := w.timers[absPath]; ok {
+	// A file re-linked to another Gist leaves its old window.
+	for key, entry := range w.timers {
+		if key == gistID || !entry.paths[absPath] {
+			continue
+		}
+		delete(entry.paths, absPath)
+		if len(entry.paths) == 0 {
+			entry.timer.Stop()
+			delete(w.timers, key)
+		}
+	}
+
+	key := gistID
+	paths := map[string]bool{absPath: true}
+	if entry, ok := w.timers[key]; ok {
 		entry.timer.Stop()
+		for p := range entry.paths {
+			paths[p] = true
+		}
+		interval = max(interval, entry.interval)
 	}
-	w.timers[absPath] = &debounceEntry{
-		gistID: gistID,
-		timer: time.AfterFunc(w.DebounceInterval, func() {
-			w.timersMu.Lock()
-			delete(w.timers, absPath)
+	entry := &debounceEntry{gistID: gistID, paths: paths, interval: interval}
+	entry.timer = time.AfterFunc(interval, func() {
+		// A timer that fired while being replaced or flushed no longer owns
+		// the map slot; whoever took it over is responsible for OnChange.
+		w.timersMu.Lock()
+		if w.timers[key] != entry {
 			w.timersMu.Unlock()
+			return
+		}
+		delete(w.timers, key)
+		w.inflight.Add(1)
+		batch := sortedPaths(entry.paths)
+		w.timersMu.Unlock()
+		defer w.inflight.Done()
 
-			if w.OnChange != nil {
-				w.OnChange(absPath, gistID)
-			}
-		}),
+		w.fire(gistID, batch)
+	})
+	w.timers[key] = entry
+}
+
+// async runs fn on a goroutine of its own that Stop waits for, the way a
+// debounce window's upload runs. Once Stop has begun it runs fn inline
+// instead, so nothing is started after Stop's wait.
+func (w *Watcher) async(fn func()) {
+	w.timersMu.Lock()
+	select {
+	case <-w.done:
+		w.timersMu.Unlock()
+		fn()
+		return
+	default:
 	}
+	w.inflight.Add(1)
+	w.timersMu.Unlock()
+	go func() {
+		defer w.inflight.Done()
+		fn()
+	}()
+}
+
+// fire hands a closed window to OnBatch, or file by file to OnChange.
+func (w *Watcher) fire(gistID string, absPaths []string) {
+	if w.OnBatch != nil {
+		w.OnBatch(gistID, absPaths)
+		return
+	}
+	if w.OnChange != nil {
+		for _, p := range absPaths {
+			w.OnChange(p, gistID)
+		}
+	}
+}
+
+func sortedPaths(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for p := range set {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
 }
 
 // Stop gracefully shuts down the file watcher. Pending debounced syncs are
-// flushed synchronously so the final edit is not lost on shutdown.
+// flushed synchronously, and syncs already in flight are waited for, so the
+// final edit is not lost on shutdown.
 func (w *Watcher) Stop() {
 	close(w.done)
 	w.watcher.Close()
 	w.flushPendingSyncs()
+	w.inflight.Wait()
 }
 
-// flushPendingSyncs cancels armed debounce timers and fires OnChange for each.
-// Timers whose AfterFunc is already running or enqueued are left alone — the
-// callback will invoke OnChange itself.
+// flushPendingSyncs claims every armed debounce entry and fires it. Callbacks
+// that already claimed their entry are tracked by inflight; callbacks that
+// fire after this point find their slot gone and bail out.
 func (w *Watcher) flushPendingSyncs() {
 	w.timersMu.Lock()
-	pending := make(map[string]string, len(w.timers))
-	for absPath, entry := range w.timers {
-		if !entry.timer.Stop() {
-			continue // already fired or firing; the AfterFunc callback handles it
-		}
-		pending[absPath] = entry.gistID
+	pending := make([]*debounceEntry, 0, len(w.timers))
+	for _, entry := range w.timers {
+		entry.timer.Stop()
+		pending = append(pending, entry)
 	}
 	w.timers = make(map[string]*debounceEntry)
 	w.timersMu.Unlock()
 
-	for absPath, gistID := range pending {
-		if w.OnChange != nil {
-			w.OnChange(absPath, gistID)
-		}
+	for _, entry := range pending {
+		w.fire(entry.gistID, sortedPaths(entry.paths))
 	}
 }
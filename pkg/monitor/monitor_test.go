@@ -1,9 +1,12 @@
 package monitor
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -17,6 +20,7 @@ func TestWatcher_DetectsFileChange(t *testing.T) {
 	// 1. Setup mock environment
 	tempDir := t.TempDir()
 	t.Setenv("HOME", tempDir) // Hijack home so state manager writes cleanly
+	t.Setenv("XDG_CONFIG_HOME", "")
 
 	sm, err := state.NewManager()
 	require.NoError(t, err)
@@ -67,6 +71,7 @@ func TestWatcher_DetectsFileChange(t *testing.T) {
 func TestWatcher_ScheduleSync_DebouncesRapidCalls(t *testing.T) {
 	tempDir := t.TempDir()
 	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
 
 	sm, err := state.NewManager()
 	require.NoError(t, err)
@@ -103,6 +108,7 @@ func TestWatcher_ScheduleSync_DebouncesRapidCalls(t *testing.T) {
 func TestWatcher_StopFlushesPendingSyncs(t *testing.T) {
 	tempDir := t.TempDir()
 	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
 
 	sm, err := state.NewManager()
 	require.NoError(t, err)
@@ -139,3 +145,695 @@ func TestWatcher_StopFlushesPendingSyncs(t *testing.T) {
 		t.Fatal("Stop() did not flush the pending debounced sync")
 	}
 }
+
+func TestWatcher_StopWaitsForInFlightSync(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 10 * time.Millisecond
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	w.OnChange = func(absPath, gistID string) {
+		close(started)
+		time.Sleep(200 * time.Millisecond) // simulate a slow PATCH
+		finished.Store(true)
+	}
+
+	go func() { _ = w.Start() }()
+	time.Sleep(50 * time.Millisecond)
+
+	w.scheduleSync("/fake/slow.txt", "gist_slow")
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("debounced sync never started")
+	}
+
+	w.Stop()
+	assert.True(t, finished.Load(), "Stop() must not return while a sync is still uploading")
+}
+
+func TestWatcher_PicksUpFileAddedToStateWhileRunning(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	existing := filepath.Join(tempDir, "existing.txt")
+	require.NoError(t, os.WriteFile(existing, []byte("x"), 0644))
+	sm.AddTrackedFile(existing, "gist_existing", time.Now().Unix())
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 50 * time.Millisecond
+
+	fired := make(chan string, 4)
+	w.OnChange = func(absPath, gistID string) { fired <- gistID }
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// A separate directory the watcher is not yet watching, registered the
+	// way `gh automagist add` would: a fresh Manager saving state.json.
+	newDir := filepath.Join(tempDir, "later")
+	require.NoError(t, os.MkdirAll(newDir, 0755))
+	added := filepath.Join(newDir, "added.txt")
+	require.NoError(t, os.WriteFile(added, []byte("v1"), 0644))
+
+	other, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, other.Load())
+	other.AddTrackedFile(added, "gist_added", time.Now().Unix())
+	require.NoError(t, other.Save())
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(added, []byte("v2"), 0644))
+
+	select {
+	case got := <-fired:
+		assert.Equal(t, "gist_added", got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("write to a file added after Start() was never synced")
+	}
+}
+
+func TestWatcher_StartsWithNothingTracked(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(sm.ConfigDir(), 0755)) // as WritePID leaves it
+	require.NoError(t, sm.Load())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 50 * time.Millisecond
+	fired := make(chan string, 4)
+	w.OnChange = func(absPath, gistID string) { fired <- gistID }
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// The first `gh automagist add` creates state.json.
+	added := filepath.Join(tempDir, "first.txt")
+	require.NoError(t, os.WriteFile(added, []byte("v1"), 0644))
+	other, err := state.NewManager()
+	require.NoError(t, err)
+	other.AddTrackedFile(added, "gist_first", time.Now().Unix())
+	require.NoError(t, other.Save())
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(added, []byte("v2"), 0644))
+
+	select {
+	case got := <-fired:
+		assert.Equal(t, "gist_first", got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("a file added to an empty state was never synced")
+	}
+}
+
+func TestWatcher_RemovedFileCancelsPendingSync(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	target := filepath.Join(tempDir, "doomed.txt")
+	require.NoError(t, os.WriteFile(target, []byte("x"), 0644))
+	sm.AddTrackedFile(target, "gist_doomed", time.Now().Unix())
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 300 * time.Millisecond
+
+	var count atomic.Int32
+	w.OnChange = func(absPath, gistID string) { count.Add(1) }
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(target, []byte("y"), 0644))
+	time.Sleep(50 * time.Millisecond)
+
+	other, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, other.Load())
+	other.RemoveTrackedFile(target)
+	require.NoError(t, other.Save())
+
+	time.Sleep(600 * time.Millisecond)
+	assert.Equal(t, int32(0), count.Load(), "sync for a file removed from state.json must be cancelled")
+}
+
+func TestWatcher_HasPendingSync(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 50 * time.Millisecond
+
+	fired := make(chan struct{}, 1)
+	w.OnChange = func(absPath, gistID string) { fired <- struct{}{} }
+
+	assert.False(t, w.HasPendingSync("/fake/a.txt"))
+	w.scheduleSync("/fake/a.txt", "gist_a")
+	assert.True(t, w.HasPendingSync("/fake/a.txt"))
+
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("debounced sync never fired")
+	}
+	assert.False(t, w.HasPendingSync("/fake/a.txt"), "fired syncs are no longer pending")
+}
+
+func TestWatcher_RenameReKeysStateEntry(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	oldPath := filepath.Join(tempDir, "notes.md")
+	content := []byte("synced\n")
+	require.NoError(t, os.WriteFile(oldPath, content, 0644))
+	sum := sha256.Sum256(content)
+	sm.Files[oldPath] = state.FileState{GistID: "gist_notes", UpdatedAt: 1, Status: state.StatusActive,
+		ContentSHA: hex.EncodeToString(sum[:])}
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.VanishGrace = 50 * time.Millisecond
+	renamed := make(chan [2]string, 1)
+	w.OnRename = func(oldPath, newPath string) { renamed <- [2]string{oldPath, newPath} }
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	newPath := filepath.Join(tempDir, "journal.md")
+	require.NoError(t, os.Rename(oldPath, newPath))
+
+	select {
+	case got := <-renamed:
+		assert.Equal(t, [2]string{oldPath, newPath}, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("rename was not detected")
+	}
+
+	check, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, check.Load())
+	assert.NotContains(t, check.Files, oldPath)
+	require.Contains(t, check.Files, newPath)
+	fs := check.Files[newPath]
+	assert.Equal(t, "gist_notes", fs.GistID)
+	assert.Equal(t, "notes.md", fs.RemoteName(newPath), "the Gist file keeps its name")
+}
+
+func TestWatcher_RenameRightAfterEditIsARename(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	oldPath := filepath.Join(tempDir, "notes.md")
+	content := []byte("synced\n")
+	require.NoError(t, os.WriteFile(oldPath, content, 0644))
+	sum := sha256.Sum256(content)
+	sm.Files[oldPath] = state.FileState{GistID: "gist_notes", UpdatedAt: 1, Status: state.StatusActive,
+		ContentSHA: hex.EncodeToString(sum[:])}
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.VanishGrace = 50 * time.Millisecond
+	w.DebounceInterval = time.Hour // the edit is still pending when the file moves
+	renamed := make(chan [2]string, 1)
+	missing := make(chan string, 1)
+	w.OnRename = func(oldPath, newPath string) { renamed <- [2]string{oldPath, newPath} }
+	w.OnMissing = func(absPath string) { missing <- absPath }
+	w.OnBatch = func(string, []string) {}
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(oldPath, []byte("edited\n"), 0644))
+	require.Eventually(t, func() bool { return w.HasPendingSync(oldPath) }, time.Second, 10*time.Millisecond)
+	newPath := filepath.Join(tempDir, "journal.md")
+	require.NoError(t, os.Rename(oldPath, newPath))
+
+	select {
+	case got := <-renamed:
+		assert.Equal(t, [2]string{oldPath, newPath}, got)
+	case got := <-missing:
+		t.Fatalf("rename after an edit was treated as deleting %s", got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("rename was not detected")
+	}
+	assert.Eventually(t, func() bool { return w.HasPendingSync(newPath) }, time.Second, 10*time.Millisecond,
+		"the pending edit follows the file")
+}
+
+func TestWatcher_DeletionWithPendingSyncIsNotPropagated(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	target := filepath.Join(tempDir, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("x"), 0644))
+	sm.AddTrackedFile(target, "gist_notes", 1)
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.VanishGrace = 50 * time.Millisecond
+	w.DebounceInterval = time.Hour
+	missing := make(chan string, 1)
+	w.OnMissing = func(absPath string) { missing <- absPath }
+	w.OnBatch = func(string, []string) {}
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(target, []byte("edited"), 0644))
+	require.Eventually(t, func() bool { return w.HasPendingSync(target) }, time.Second, 10*time.Millisecond)
+	require.NoError(t, os.Remove(target))
+
+	check, err := state.NewManager()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return check.Load() == nil && check.Files[target].Status == state.StatusMissing
+	}, 2*time.Second, 20*time.Millisecond, "the deletion is still recorded")
+	select {
+	case got := <-missing:
+		t.Fatalf("deletion of %s with an unsynced edit was propagated", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestWatcher_DeletionMarksMissingAndRestoreReactivates(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	target := filepath.Join(tempDir, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("x"), 0644))
+	sm.AddTrackedFile(target, "gist_notes", 1)
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.VanishGrace = 50 * time.Millisecond
+	w.DebounceInterval = 0
+	missing := make(chan string, 1)
+	w.OnMissing = func(absPath string) { missing <- absPath }
+	w.OnChange = func(string, string) {}
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.Remove(target))
+	select {
+	case got := <-missing:
+		assert.Equal(t, target, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("deletion was not detected")
+	}
+
+	check, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, check.Load())
+	assert.Equal(t, state.StatusMissing, check.Files[target].Status)
+
+	require.NoError(t, os.WriteFile(target, []byte("back"), 0644))
+	assert.Eventually(t, func() bool {
+		return check.Load() == nil && check.Files[target].Status == state.StatusActive
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestWatcher_ReplaceStyleSaveIsNotADeletion(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	target := filepath.Join(tempDir, "notes.md")
+	require.NoError(t, os.WriteFile(target, []byte("v1"), 0644))
+	sm.AddTrackedFile(target, "gist_notes", 1)
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.VanishGrace = 50 * time.Millisecond
+	w.DebounceInterval = 0
+	missing := make(chan string, 1)
+	w.OnMissing = func(absPath string) { missing <- absPath }
+	w.OnChange = func(string, string) {}
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	// vim-style save: move the original aside, write a fresh file.
+	require.NoError(t, os.Rename(target, target+"~"))
+	require.NoError(t, os.WriteFile(target, []byte("v2"), 0644))
+
+	select {
+	case <-missing:
+		t.Fatal("replace-style save was treated as a deletion")
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatcher_DirRuleAdoptsNewFilesAndBatchesThem(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	dir := filepath.Join(tempDir, "prompts")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	existing := filepath.Join(dir, "before.md")
+	require.NoError(t, os.WriteFile(existing, []byte("x"), 0644))
+	sm.AddDirRule(state.DirRule{Dir: dir, GistID: "gist_dir", Include: []string{"*.md"}})
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 200 * time.Millisecond
+
+	batches := make(chan []string, 8)
+	w.OnBatch = func(gistID string, absPaths []string) {
+		assert.Equal(t, "gist_dir", gistID)
+		batches <- absPaths
+	}
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+
+	// The startup scan adopts the file that was already there.
+	select {
+	case got := <-batches:
+		assert.Equal(t, []string{existing}, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("existing matching file was not picked up at startup")
+	}
+
+	a := filepath.Join(dir, "a.md")
+	b := filepath.Join(dir, "b.md")
+	require.NoError(t, os.WriteFile(a, []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(b, []byte("b"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "skip.txt"), []byte("no"), 0644))
+
+	select {
+	case got := <-batches:
+		assert.Equal(t, []string{a, b}, got, "both new files go up in one window")
+	case <-time.After(2 * time.Second):
+		t.Fatal("new matching files were not synced")
+	}
+
+	check, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, check.Load())
+	assert.Equal(t, dir, check.Files[a].Dir)
+	assert.Equal(t, "gist_dir", check.Files[b].GistID)
+	assert.NotContains(t, check.Files, filepath.Join(dir, "skip.txt"))
+}
+
+func TestWatcher_DirRuleFileDeletionUntracks(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	dir := filepath.Join(tempDir, "prompts")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	target := filepath.Join(dir, "a.md")
+	require.NoError(t, os.WriteFile(target, []byte("x"), 0644))
+	sm.AddDirRule(state.DirRule{Dir: dir, GistID: "gist_dir"})
+	sm.Files[target] = state.FileState{GistID: "gist_dir", Status: state.StatusActive, Dir: dir}
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.VanishGrace = 50 * time.Millisecond
+	w.OnBatch = func(string, []string) {}
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.Remove(target))
+
+	check, err := state.NewManager()
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		if check.Load() != nil {
+			return false
+		}
+		_, tracked := check.Files[target]
+		return !tracked
+	}, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestWatcher_ScheduleSync_BatchesPerGist(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 100 * time.Millisecond
+
+	var mu sync.Mutex
+	got := map[string][][]string{}
+	w.OnBatch = func(gistID string, absPaths []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[gistID] = append(got[gistID], absPaths)
+	}
+
+	w.scheduleSync("/fake/a.txt", "gist_shared")
+	w.scheduleSync("/fake/other.txt", "gist_other")
+	time.Sleep(30 * time.Millisecond)
+	w.scheduleSync("/fake/b.txt", "gist_shared")
+	w.scheduleSync("/fake/a.txt", "gist_shared")
+
+	assert.True(t, w.HasPendingSync("/fake/b.txt"))
+	time.Sleep(300 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, [][]string{{"/fake/a.txt", "/fake/b.txt"}}, got["gist_shared"],
+		"one window, one batch for every file of the Gist")
+	assert.Equal(t, [][]string{{"/fake/other.txt"}}, got["gist_other"])
+}
+
+func TestWatcher_CancelSyncKeepsRestOfWindow(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 50 * time.Millisecond
+
+	fired := make(chan []string, 2)
+	w.OnBatch = func(_ string, absPaths []string) { fired <- absPaths }
+
+	w.scheduleSync("/fake/a.txt", "gist_shared")
+	w.scheduleSync("/fake/b.txt", "gist_shared")
+	w.cancelSync("/fake/a.txt")
+
+	select {
+	case paths := <-fired:
+		assert.Equal(t, []string{"/fake/b.txt"}, paths)
+	case <-time.After(time.Second):
+		t.Fatal("remaining file in the window was not synced")
+	}
+}
+
+func TestWatcher_ScheduleSync_HonoursPerFileDebounce(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	sm.Files["/fake/snippet.txt"] = state.FileState{GistID: "gist_a", Debounce: "0s"}
+	sm.Files["/fake/log.txt"] = state.FileState{GistID: "gist_b", Debounce: "300ms"}
+	sm.Files["/fake/notes.txt"] = state.FileState{GistID: "gist_b"}
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 50 * time.Millisecond
+
+	var mu sync.Mutex
+	got := map[string][]string{}
+	w.OnBatch = func(gistID string, absPaths []string) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[gistID] = append(got[gistID], absPaths...)
+	}
+	fired := func(gistID string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return got[gistID]
+	}
+
+	w.scheduleSync("/fake/snippet.txt", "gist_a")
+	assert.Equal(t, []string{"/fake/snippet.txt"}, fired("gist_a"), "a zero override syncs at once")
+
+	w.scheduleSync("/fake/log.txt", "gist_b")
+	w.scheduleSync("/fake/notes.txt", "gist_b")
+	time.Sleep(150 * time.Millisecond)
+	assert.Empty(t, fired("gist_b"), "the window lasts the longest debounce in it")
+	assert.Eventually(t, func() bool { return len(fired("gist_b")) == 2 }, time.Second, 20*time.Millisecond)
+}
+
+func TestWatcher_PausedFileIsHeldUntilResumed(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	target := filepath.Join(tempDir, "notes.txt")
+	require.NoError(t, os.WriteFile(target, []byte("v1"), 0644))
+	sm.AddTrackedFile(target, "gist_p", time.Now().Unix()-10)
+	fs := sm.Files[target]
+	fs.Paused = true
+	sm.Files[target] = fs
+	require.NoError(t, sm.Save())
+	syncedAt := fs.UpdatedAt
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 50 * time.Millisecond
+	fired := make(chan string, 4)
+	w.OnChange = func(absPath, _ string) { fired <- absPath }
+
+	go func() { _ = w.Start() }()
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	require.NoError(t, os.WriteFile(target, []byte("v2"), 0644))
+	select {
+	case <-fired:
+		t.Fatal("a paused file was synced")
+	case <-time.After(300 * time.Millisecond):
+	}
+	check, err := state.NewManager()
+	require.NoError(t, err)
+	require.NoError(t, check.Load())
+	assert.Equal(t, syncedAt, check.Files[target].UpdatedAt, "the held edit still counts as unsynced")
+
+	// `gh automagist resume`, from another process.
+	_, err = check.UpdateFile(target, func(fs *state.FileState) { fs.Paused = false })
+	require.NoError(t, err)
+	select {
+	case got := <-fired:
+		assert.Equal(t, target, got)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the edit made while paused was not synced on resume")
+	}
+}
+
+// Callbacks Update the shared Manager from timer goroutines while the event
+// loop reloads it on every state.json write; run with -race.
+func TestWatcher_ConcurrentCallbacksAndEventLoop(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("HOME", tempDir)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	sm, err := state.NewManager()
+	require.NoError(t, err)
+	var paths []string
+	for i := 0; i < 5; i++ {
+		p := filepath.Join(tempDir, fmt.Sprintf("f%d.txt", i))
+		require.NoError(t, os.WriteFile(p, []byte("0"), 0644))
+		sm.AddTrackedFile(p, fmt.Sprintf("gist_%d", i), time.Now().Unix())
+		paths = append(paths, p)
+	}
+	require.NoError(t, sm.Save())
+
+	w, err := NewWatcher(sm)
+	require.NoError(t, err)
+	w.DebounceInterval = 5 * time.Millisecond
+
+	var synced atomic.Int32
+	w.OnBatch = func(gistID string, absPaths []string) {
+		for _, p := range absPaths {
+			// What the pusher does after an upload.
+			if _, ok := sm.File(p); !ok {
+				continue
+			}
+			_, err := sm.UpdateFile(p, func(fs *state.FileState) { fs.RemoteUpdatedAt = time.Now().UnixNano() })
+			assert.NoError(t, err)
+			_ = sm.Snapshot()
+		}
+		synced.Add(1)
+	}
+
+	go func() { _ = w.Start() }()
+	time.Sleep(100 * time.Millisecond)
+
+	deadline := time.Now().Add(500 * time.Millisecond)
+	for i := 0; time.Now().Before(deadline); i++ {
+		for _, p := range paths {
+			require.NoError(t, os.WriteFile(p, []byte(fmt.Sprint(i)), 0644))
+		}
+		time.Sleep(12 * time.Millisecond)
+	}
+	w.Stop()
+	assert.NotZero(t, synced.Load())
+}
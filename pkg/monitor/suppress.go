@@ -9,8 +9,12 @@ func ShouldSuppress(fs state.FileState, currentSHA string, nowUnix int64) bool {
 	if fs.PullSuppressUntil == 0 || nowUnix >= fs.PullSuppressUntil {
 		return false
 	}
-	if fs.ContentSHA == "" {
+	sha := fs.ContentSHA
+	if fs.PullSuppressSHA != "" {
+		sha = fs.PullSuppressSHA
+	}
+	if sha == "" {
 		return false
 	}
-	return currentSHA == fs.ContentSHA
+	return currentSHA == sha
 }
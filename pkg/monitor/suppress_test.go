@@ -24,6 +24,20 @@ func TestShouldSuppress(t *testing.T) {
 			nowUnix: 150,
 			want:    true,
 		},
+		{
+			name:    "window active, suppress sha matches → suppress",
+			fs:      state.FileState{PullSuppressUntil: 200, ContentSHA: otherSHA, PullSuppressSHA: sha},
+			curSHA:  sha,
+			nowUnix: 150,
+			want:    true,
+		},
+		{
+			name:    "window active, only content sha matches → do not suppress",
+			fs:      state.FileState{PullSuppressUntil: 200, ContentSHA: sha, PullSuppressSHA: otherSHA},
+			curSHA:  sha,
+			nowUnix: 150,
+			want:    false,
+		},
 		{
 			name:    "window active, sha differs → do not suppress",
 			fs:      state.FileState{PullSuppressUntil: 200, ContentSHA: sha},
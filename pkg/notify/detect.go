@@ -1,7 +1,10 @@
 package notify
 
 import (
+	"context"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 )
@@ -17,43 +20,127 @@ type Fetcher interface {
 type FileStatus struct {
 	Path            string
 	GistID          string
+	Host            string // FileState.Host; empty is gh's default host
 	RemoteNewer     bool
 	RemoteUpdatedAt int64 // Gist's most recent commit timestamp, unix epoch; 0 on Err
+	// Conflict mirrors FileState.ConflictAt != 0: the monitor declined to
+	// overwrite (or backed up) a remote edit and the user has not resolved it.
+	Conflict bool
+	// Missing mirrors FileState.Status == StatusMissing: the local file was
+	// deleted and the monitor kept the entry instead of dropping it.
+	Missing bool
 	// Err is set when Detect could not fetch metadata for this file's Gist.
 	// All files sharing that Gist carry the same error; other Gists are
 	// unaffected.
 	Err error
 }
 
-// Detect returns one FileStatus per tracked file. API calls are deduped by
-// Gist ID: files sharing a Gist cost one FetchGistMeta call between them.
-// A fetch error for one Gist marks every file in that Gist with the same
-// Err but does not affect files in other Gists.
+// Defaults for Options fields left zero.
+const (
+	DefaultWorkers = 8
+	DefaultTimeout = 20 * time.Second
+)
+
+// Options tunes DetectHosts. Zero values pick the defaults.
+type Options struct {
+	// Workers caps how many Gists are checked at once.
+	Workers int
+	// Timeout bounds each Gist's check; a Gist that takes longer reports
+	// context.DeadlineExceeded as its Err.
+	Timeout time.Duration
+}
+
+// ContextFetcher is a Fetcher whose calls can be cancelled. *gist.Client
+// implements it; DetectHosts uses it when available.
+type ContextFetcher interface {
+	FetchGistMetaContext(ctx context.Context, gistID string) (updatedAt int64, err error)
+}
+
+// Detect is DetectHosts for trees where every file uses the same client.
+func Detect(sm *state.Manager, client Fetcher) []FileStatus {
+	return DetectHosts(context.Background(), sm, func(host, account string) Fetcher { return client }, Options{})
+}
+
+// DetectHosts returns one FileStatus per tracked file, asking each file's
+// Gist through fetcherFor(FileState.Host, FileState.Account). API calls are
+// deduped by host and Gist ID: files sharing a Gist cost one FetchGistMeta
+// call between them. Gists are checked in parallel by opts.Workers workers.
+// A fetch error — including a timeout, or ctx being cancelled before the
+// Gist's turn — marks every file in that Gist with the same Err but does not
+// affect files in other Gists.
 //
 // Results are sorted by Path for stable CLI output.
-func Detect(sm *state.Manager, client Fetcher) []FileStatus {
-	gistToPaths := make(map[string][]string)
+func DetectHosts(ctx context.Context, sm *state.Manager, fetcherFor func(host, account string) Fetcher, opts Options) []FileStatus {
+	if opts.Workers <= 0 {
+		opts.Workers = DefaultWorkers
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+
+	type gistKey struct{ host, gistID string }
+	gistToPaths := make(map[gistKey][]string)
 	for absPath, fs := range sm.Files {
-		gistToPaths[fs.GistID] = append(gistToPaths[fs.GistID], absPath)
+		key := gistKey{fs.Host, fs.GistID}
+		gistToPaths[key] = append(gistToPaths[key], absPath)
 	}
 
+	type check struct {
+		key             gistKey
+		paths           []string
+		remoteUpdatedAt int64
+		err             error
+	}
+	checks := make([]*check, 0, len(gistToPaths))
+	for key, paths := range gistToPaths {
+		sort.Strings(paths)
+		checks = append(checks, &check{key: key, paths: paths})
+	}
+
+	jobs := make(chan *check)
+	var wg sync.WaitGroup
+	for i := 0; i < min(opts.Workers, len(checks)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				if err := ctx.Err(); err != nil {
+					c.err = err
+					continue
+				}
+				fetcher := fetcherFor(c.key.host, sm.Files[c.paths[0]].Account)
+				c.remoteUpdatedAt, c.err = fetchMeta(ctx, fetcher, c.key.gistID, opts.Timeout)
+			}
+		}()
+	}
+	for _, c := range checks {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+
 	result := make([]FileStatus, 0, len(sm.Files))
-	for gistID, paths := range gistToPaths {
-		remoteUpdatedAt, err := client.FetchGistMeta(gistID)
-		for _, path := range paths {
-			if err != nil {
+	for _, c := range checks {
+		for _, path := range c.paths {
+			if c.err != nil {
 				result = append(result, FileStatus{
-					Path:   path,
-					GistID: gistID,
-					Err:    err,
+					Path:     path,
+					GistID:   c.key.gistID,
+					Host:     c.key.host,
+					Conflict: sm.Files[path].ConflictAt != 0,
+					Missing:  sm.Files[path].Status == state.StatusMissing,
+					Err:      c.err,
 				})
 				continue
 			}
 			result = append(result, FileStatus{
 				Path:            path,
-				GistID:          gistID,
-				RemoteNewer:     remoteUpdatedAt > sm.Files[path].RemoteUpdatedAt,
-				RemoteUpdatedAt: remoteUpdatedAt,
+				GistID:          c.key.gistID,
+				Host:            c.key.host,
+				RemoteNewer:     c.remoteUpdatedAt > sm.Files[path].RemoteUpdatedAt,
+				RemoteUpdatedAt: c.remoteUpdatedAt,
+				Conflict:        sm.Files[path].ConflictAt != 0,
+				Missing:         sm.Files[path].Status == state.StatusMissing,
 			})
 		}
 	}
@@ -63,3 +150,30 @@ func Detect(sm *state.Manager, client Fetcher) []FileStatus {
 	})
 	return result
 }
+
+// fetchMeta runs one check under its own timeout. A Fetcher that cannot be
+// cancelled is abandoned rather than waited for: its goroutine finishes in
+// the background and the result is dropped.
+func fetchMeta(ctx context.Context, f Fetcher, gistID string, timeout time.Duration) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if cf, ok := f.(ContextFetcher); ok {
+		return cf.FetchGistMetaContext(ctx, gistID)
+	}
+
+	type reply struct {
+		updatedAt int64
+		err       error
+	}
+	done := make(chan reply, 1)
+	go func() {
+		updatedAt, err := f.FetchGistMeta(gistID)
+		done <- reply{updatedAt, err}
+	}()
+	select {
+	case r := <-done:
+		return r.updatedAt, r.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
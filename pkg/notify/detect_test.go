@@ -1,8 +1,12 @@
 package notify
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/noriyo_tcp/gh-automagist/pkg/state"
 	"github.com/stretchr/testify/assert"
@@ -15,9 +19,12 @@ type fakeFetcher struct {
 	metaByGist map[string]int64
 	errByGist  map[string]error
 	calls      map[string]int
+	mu         sync.Mutex // Detect calls concurrently
 }
 
 func (f *fakeFetcher) FetchGistMeta(gistID string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	if f.calls == nil {
 		f.calls = make(map[string]int)
 	}
@@ -32,6 +39,7 @@ func newManager(t *testing.T, files map[string]state.FileState) *state.Manager {
 	t.Helper()
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
 	sm, err := state.NewManager()
 	require.NoError(t, err)
 	sm.Files = files
@@ -156,3 +164,149 @@ func indexByPath(s []FileStatus) map[string]FileStatus {
 	}
 	return m
 }
+
+func TestDetect_CarriesConflictFromState(t *testing.T) {
+	sm := newManager(t, map[string]state.FileState{
+		"/a.txt": {GistID: "g1", RemoteUpdatedAt: 100, ConflictAt: 150},
+		"/b.txt": {GistID: "g1", RemoteUpdatedAt: 100},
+	})
+	f := &fakeFetcher{metaByGist: map[string]int64{"g1": 200}}
+
+	result := Detect(sm, f)
+
+	require.Len(t, result, 2)
+	assert.True(t, result[0].Conflict, "/a.txt has a recorded conflict")
+	assert.False(t, result[1].Conflict)
+}
+
+func TestDetect_CarriesMissingFromState(t *testing.T) {
+	sm := newManager(t, map[string]state.FileState{
+		"/a.txt": {GistID: "g1", Status: state.StatusMissing},
+		"/b.txt": {GistID: "g1", Status: state.StatusActive},
+	})
+	f := &fakeFetcher{metaByGist: map[string]int64{"g1": 200}}
+
+	result := Detect(sm, f)
+
+	require.Len(t, result, 2)
+	assert.True(t, result[0].Missing)
+	assert.False(t, result[1].Missing)
+}
+
+// blockingFetcher holds every FetchGistMetaContext call until release is
+// closed (or the call's context ends), recording peak concurrency.
+type blockingFetcher struct {
+	release  chan struct{}
+	slow     map[string]bool // only these gists block; the rest answer at once
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (f *blockingFetcher) FetchGistMeta(gistID string) (int64, error) {
+	return f.FetchGistMetaContext(context.Background(), gistID)
+}
+
+func (f *blockingFetcher) FetchGistMetaContext(ctx context.Context, gistID string) (int64, error) {
+	f.mu.Lock()
+	f.inFlight++
+	f.peak = max(f.peak, f.inFlight)
+	f.mu.Unlock()
+	defer func() {
+		f.mu.Lock()
+		f.inFlight--
+		f.mu.Unlock()
+	}()
+	if f.slow != nil && !f.slow[gistID] {
+		return 100, nil
+	}
+	select {
+	case <-f.release:
+		return 100, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func manyGists(t *testing.T, n int) *state.Manager {
+	files := make(map[string]state.FileState, n)
+	for i := 0; i < n; i++ {
+		files[fmt.Sprintf("/f%02d.txt", i)] = state.FileState{GistID: fmt.Sprintf("g%02d", i), RemoteUpdatedAt: 50}
+	}
+	return newManager(t, files)
+}
+
+func TestDetectHosts_BoundedParallelism(t *testing.T) {
+	sm := manyGists(t, 12)
+	f := &blockingFetcher{release: make(chan struct{})}
+	go func() {
+		// Let the pool fill up before releasing it.
+		for {
+			f.mu.Lock()
+			full := f.inFlight == 4
+			f.mu.Unlock()
+			if full {
+				close(f.release)
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	result := DetectHosts(context.Background(), sm, func(string, string) Fetcher { return f }, Options{Workers: 4})
+
+	require.Len(t, result, 12)
+	assert.Equal(t, 4, f.peak)
+	for i, s := range result {
+		assert.Equal(t, fmt.Sprintf("/f%02d.txt", i), s.Path, "output stays path-sorted")
+		assert.NoError(t, s.Err)
+		assert.True(t, s.RemoteNewer)
+	}
+}
+
+func TestDetectHosts_TimeoutIsolatedPerGist(t *testing.T) {
+	sm := manyGists(t, 3)
+	f := &blockingFetcher{release: make(chan struct{}), slow: map[string]bool{"g01": true}}
+
+	result := DetectHosts(context.Background(), sm, func(string, string) Fetcher { return f },
+		Options{Timeout: 20 * time.Millisecond})
+
+	require.Len(t, result, 3)
+	assert.NoError(t, result[0].Err)
+	assert.ErrorIs(t, result[1].Err, context.DeadlineExceeded)
+	assert.NoError(t, result[2].Err)
+}
+
+func TestDetectHosts_CancelAbortsPendingChecks(t *testing.T) {
+	sm := manyGists(t, 6)
+	f := &blockingFetcher{release: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	result := DetectHosts(ctx, sm, func(string, string) Fetcher { return f }, Options{Workers: 2})
+
+	require.Len(t, result, 6)
+	for _, s := range result {
+		assert.ErrorIs(t, s.Err, context.Canceled, s.Path)
+	}
+}
+
+// plainFetcher cannot be cancelled; Detect must still return on timeout.
+type plainFetcher struct{ release chan struct{} }
+
+func (f plainFetcher) FetchGistMeta(string) (int64, error) {
+	<-f.release
+	return 100, nil
+}
+
+func TestDetectHosts_AbandonsUncancellableFetcher(t *testing.T) {
+	sm := manyGists(t, 1)
+	f := plainFetcher{release: make(chan struct{})}
+	defer close(f.release)
+
+	result := DetectHosts(context.Background(), sm, func(string, string) Fetcher { return f },
+		Options{Timeout: 10 * time.Millisecond})
+
+	require.Len(t, result, 1)
+	assert.ErrorIs(t, result[0].Err, context.DeadlineExceeded)
+}
@@ -0,0 +1,118 @@
+package objstore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Get when no object with the given SHA exists.
+var ErrNotFound = errors.New("object not found")
+
+// Store is a content-addressed blob store on disk: each object lives at
+// <dir>/<sha[:2]>/<sha[2:]>, keyed by the same hex SHA-256 that
+// FileState.ContentSHA records, so the last-synced content of any tracked
+// file can be looked up from state.json alone.
+type Store struct {
+	dir string
+}
+
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// SHA returns the key Put would store content under.
+func SHA(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+func (s *Store) path(sha string) (string, error) {
+	if len(sha) < 3 || strings.ContainsAny(sha, `/\.`) {
+		return "", fmt.Errorf("invalid object id %q", sha)
+	}
+	return filepath.Join(s.dir, sha[:2], sha[2:]), nil
+}
+
+// Put stores content and returns its SHA. Storing an existing object only
+// refreshes its mtime, which Prune uses as "recently referenced".
+func (s *Store) Put(content []byte) (string, error) {
+	sha := SHA(content)
+	p, err := s.path(sha)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(p); err == nil {
+		now := time.Now()
+		_ = os.Chtimes(p, now, now)
+		return sha, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return "", fmt.Errorf("failed to create object directory: %w", err)
+	}
+	tmpPath := p + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmpPath, p); err != nil {
+		_ = os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to rename object into place: %w", err)
+	}
+	return sha, nil
+}
+
+// Get returns the object stored under sha, or ErrNotFound.
+func (s *Store) Get(sha string) ([]byte, error) {
+	p, err := s.path(sha)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read object %s: %w", sha, err)
+	}
+	return data, nil
+}
+
+// Prune deletes objects not in keep whose mtime is older than minAge. The
+// age floor protects an object another process has just Put but not yet
+// recorded in state.json. Returns the number of objects removed.
+func (s *Store) Prune(keep map[string]bool, minAge time.Duration) (int, error) {
+	cutoff := time.Now().Add(-minAge)
+	removed := 0
+	err := filepath.WalkDir(s.dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return nil
+		}
+		sha := filepath.Base(filepath.Dir(p)) + d.Name()
+		if keep[sha] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if err := os.Remove(p); err == nil {
+			removed++
+		}
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("failed to prune object store: %w", err)
+	}
+	return removed, nil
+}
@@ -0,0 +1,68 @@
+package objstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_PutGetRoundTrip(t *testing.T) {
+	s := New(t.TempDir())
+	sha, err := s.Put([]byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, SHA([]byte("hello")), sha)
+
+	got, err := s.Get(sha)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(got))
+}
+
+func TestStore_GetMissing(t *testing.T) {
+	s := New(t.TempDir())
+	_, err := s.Get(SHA([]byte("nope")))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_RejectsPathLikeIDs(t *testing.T) {
+	s := New(t.TempDir())
+	_, err := s.Get("../../etc/passwd")
+	assert.Error(t, err)
+}
+
+func TestStore_PruneKeepsReferencedAndRecent(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+	keep, err := s.Put([]byte("keep"))
+	require.NoError(t, err)
+	old, err := s.Put([]byte("old"))
+	require.NoError(t, err)
+	recent, err := s.Put([]byte("recent"))
+	require.NoError(t, err)
+
+	past := time.Now().Add(-2 * time.Hour)
+	for _, sha := range []string{keep, old} {
+		require.NoError(t, os.Chtimes(filepath.Join(dir, sha[:2], sha[2:]), past, past))
+	}
+
+	removed, err := s.Prune(map[string]bool{keep: true}, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	_, err = s.Get(keep)
+	assert.NoError(t, err)
+	_, err = s.Get(recent)
+	assert.NoError(t, err)
+	_, err = s.Get(old)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestStore_PruneMissingDirIsNoop(t *testing.T) {
+	s := New(filepath.Join(t.TempDir(), "objects"))
+	removed, err := s.Prune(nil, 0)
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+}
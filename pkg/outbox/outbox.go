@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Backoff bounds. The first retry comes quickly (a blip while switching
+// networks), then doubles up to a ceiling that still recovers within a
+// quarter hour of the laptop coming back online.
+const (
+	BaseBackoff = 15 * time.Second
+	MaxBackoff  = 15 * time.Minute
+)
+
+// Entry is one tracked file whose most recent upload failed. Content is not
+// stored: a retry re-reads the file, so later edits coalesce into the newest
+// version automatically and ContentSHA just records what was last attempted.
+type Entry struct {
+	GistID        string `json:"gist_id"`
+	ContentSHA    string `json:"content_sha,omitempty"`
+	Attempts      int    `json:"attempts"`
+	FirstFailedAt int64  `json:"first_failed_at"`
+	NextAttemptAt int64  `json:"next_attempt_at"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// Outbox is the persistent set of failed uploads, keyed by absolute path.
+// Every mutation is written through to disk so pending work survives a
+// daemon restart.
+type Outbox struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]Entry
+}
+
+// Open loads the outbox at path; a missing file yields an empty outbox.
+func Open(path string) (*Outbox, error) {
+	o := &Outbox{path: path, entries: make(map[string]Entry)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return o, nil
+		}
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	if err := json.Unmarshal(data, &o.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse outbox: %w", err)
+	}
+	return o, nil
+}
+
+// RecordFailure upserts absPath's entry after a failed upload of the content
+// with contentSHA and schedules the next attempt. The attempt counter keeps
+// growing across newer versions of the file: a new edit does not make the
+// network any more reachable.
+func (o *Outbox) RecordFailure(absPath, gistID, contentSHA string, uploadErr error, now time.Time, jitter float64) (Entry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	e, ok := o.entries[absPath]
+	if !ok {
+		e.FirstFailedAt = now.Unix()
+	}
+	e.GistID = gistID
+	if contentSHA != "" {
+		e.ContentSHA = contentSHA
+	}
+	e.Attempts++
+	e.NextAttemptAt = now.Add(Backoff(e.Attempts, jitter)).Unix()
+	if uploadErr != nil {
+		e.LastError = uploadErr.Error()
+	}
+	o.entries[absPath] = e
+	return e, o.saveLocked()
+}
+
+// Remove drops absPath's entry, e.g. after a successful upload or when the
+// file is no longer tracked. Removing an absent entry is a no-op.
+func (o *Outbox) Remove(absPath string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.entries[absPath]; !ok {
+		return nil
+	}
+	delete(o.entries, absPath)
+	return o.saveLocked()
+}
+
+// Entries returns a snapshot of all pending entries.
+func (o *Outbox) Entries() map[string]Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make(map[string]Entry, len(o.entries))
+	for k, v := range o.entries {
+		out[k] = v
+	}
+	return out
+}
+
+// Due returns the entries whose next attempt is at or before now.
+func (o *Outbox) Due(now time.Time) map[string]Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	out := make(map[string]Entry)
+	for k, v := range o.entries {
+		if v.NextAttemptAt <= now.Unix() {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// saveLocked writes the outbox atomically (tmp + rename), like state.json.
+// An empty outbox removes the file so a healthy setup leaves nothing behind.
+func (o *Outbox) saveLocked() error {
+	if len(o.entries) == 0 {
+		if err := os.Remove(o.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove empty outbox: %w", err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(o.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(o.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode outbox: %w", err)
+	}
+	tmpPath := o.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write outbox: %w", err)
+	}
+	if err := os.Rename(tmpPath, o.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename outbox into place: %w", err)
+	}
+	return nil
+}
+
+// Backoff returns the delay before retry number attempts (1-based):
+// BaseBackoff doubled per attempt, capped at MaxBackoff, then scaled by
+// ±20% according to jitter in [0, 1) so many files failing together do not
+// retry in lockstep.
+func Backoff(attempts int, jitter float64) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := float64(BaseBackoff) * math.Pow(2, float64(attempts-1))
+	if d > float64(MaxBackoff) {
+		d = float64(MaxBackoff)
+	}
+	return time.Duration(d * (0.8 + 0.4*jitter))
+}
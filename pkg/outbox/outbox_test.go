@@ -0,0 +1,93 @@
+package outbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutbox_RecordFailurePersistsAcrossOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	o, err := Open(path)
+	require.NoError(t, err)
+
+	now := time.Unix(1_000_000, 0)
+	e, err := o.RecordFailure("/a.txt", "gist_a", "sha1", errors.New("offline"), now, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, 1, e.Attempts)
+	assert.Equal(t, now.Unix(), e.FirstFailedAt)
+	assert.Equal(t, now.Add(BaseBackoff).Unix(), e.NextAttemptAt)
+
+	reopened, err := Open(path)
+	require.NoError(t, err)
+	got := reopened.Entries()["/a.txt"]
+	assert.Equal(t, e, got)
+	assert.Equal(t, "offline", got.LastError)
+}
+
+func TestOutbox_NewerVersionCoalescesIntoOneEntry(t *testing.T) {
+	o, err := Open(filepath.Join(t.TempDir(), "outbox.json"))
+	require.NoError(t, err)
+
+	first := time.Unix(1_000_000, 0)
+	_, err = o.RecordFailure("/a.txt", "gist_a", "sha_v1", errors.New("offline"), first, 0.5)
+	require.NoError(t, err)
+	e, err := o.RecordFailure("/a.txt", "gist_a", "sha_v2", errors.New("still offline"), first.Add(time.Minute), 0.5)
+	require.NoError(t, err)
+
+	require.Len(t, o.Entries(), 1)
+	assert.Equal(t, "sha_v2", e.ContentSHA, "entry must track the newest version")
+	assert.Equal(t, 2, e.Attempts)
+	assert.Equal(t, first.Unix(), e.FirstFailedAt, "first failure time survives coalescing")
+}
+
+func TestOutbox_Due(t *testing.T) {
+	o, err := Open(filepath.Join(t.TempDir(), "outbox.json"))
+	require.NoError(t, err)
+
+	now := time.Unix(1_000_000, 0)
+	_, err = o.RecordFailure("/a.txt", "gist_a", "sha", errors.New("x"), now, 0.5)
+	require.NoError(t, err)
+
+	assert.Empty(t, o.Due(now))
+	assert.Contains(t, o.Due(now.Add(BaseBackoff)), "/a.txt")
+}
+
+func TestOutbox_RemoveLastEntryDeletesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+	o, err := Open(path)
+	require.NoError(t, err)
+
+	_, err = o.RecordFailure("/a.txt", "gist_a", "sha", errors.New("x"), time.Now(), 0.5)
+	require.NoError(t, err)
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	require.NoError(t, o.Remove("/a.txt"))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "empty outbox should leave no file behind")
+
+	assert.NoError(t, o.Remove("/never-queued.txt"), "removing an absent entry is a no-op")
+}
+
+func TestOpen_MissingFileIsEmpty(t *testing.T) {
+	o, err := Open(filepath.Join(t.TempDir(), "outbox.json"))
+	require.NoError(t, err)
+	assert.Empty(t, o.Entries())
+}
+
+func TestBackoff(t *testing.T) {
+	// jitter 0.5 → factor 1.0, so the raw schedule is visible.
+	assert.Equal(t, BaseBackoff, Backoff(1, 0.5))
+	assert.Equal(t, 2*BaseBackoff, Backoff(2, 0.5))
+	assert.Equal(t, 4*BaseBackoff, Backoff(3, 0.5))
+	assert.Equal(t, MaxBackoff, Backoff(30, 0.5), "backoff must cap at MaxBackoff")
+
+	assert.Equal(t, time.Duration(float64(BaseBackoff)*0.8), Backoff(1, 0))
+	assert.Less(t, Backoff(1, 0.999), time.Duration(float64(BaseBackoff)*1.2))
+}
@@ -0,0 +1,69 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultProfile names the config directory used when no profile is chosen.
+const DefaultProfile = "default"
+
+// DefaultConfigDir, when set, is the directory NewManager uses instead of
+// ConfigRoot. The CLI sets it from --config-dir or the chosen profile.
+var DefaultConfigDir string
+
+// ConfigRoot is gh-automagist's directory under $XDG_CONFIG_HOME, or under
+// ~/.config when that is unset or relative (the XDG spec says to ignore a
+// relative value).
+func ConfigRoot() (string, error) {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, "gh-automagist"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "gh-automagist"), nil
+}
+
+// ProfileDir is the config directory of the named profile under root:
+// root itself for DefaultProfile (or ""), root/profiles/<name> otherwise.
+func ProfileDir(root, name string) (string, error) {
+	if name == "" || name == DefaultProfile {
+		return root, nil
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("invalid profile name %q", name)
+	}
+	return filepath.Join(root, "profiles", name), nil
+}
+
+// Profile is one config directory with its own state and monitor.
+type Profile struct {
+	Name string
+	Dir  string
+}
+
+// Profiles lists DefaultProfile and every profile created under root,
+// sorted by name with the default first.
+func Profiles(root string) ([]Profile, error) {
+	profiles := []Profile{{Name: DefaultProfile, Dir: root}}
+	entries, err := os.ReadDir(filepath.Join(root, "profiles"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list profiles: %w", err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		profiles = append(profiles, Profile{Name: name, Dir: filepath.Join(root, "profiles", name)})
+	}
+	return profiles, nil
+}
@@ -0,0 +1,74 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigRoot_HonorsXDGConfigHome(t *testing.T) {
+	tempHome := setupTestEnv(t)
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	root, err := ConfigRoot()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(xdg, "gh-automagist"), root)
+
+	m, err := NewManager()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(xdg, "gh-automagist", "state.json"), m.StatePath())
+
+	t.Setenv("XDG_CONFIG_HOME", "relative/dir")
+	root, err = ConfigRoot()
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(tempHome, ".config", "gh-automagist"), root,
+		"a relative XDG_CONFIG_HOME is ignored")
+}
+
+func TestNewManager_UsesDefaultConfigDir(t *testing.T) {
+	_ = setupTestEnv(t)
+	dir := t.TempDir()
+	DefaultConfigDir = dir
+	t.Cleanup(func() { DefaultConfigDir = "" })
+
+	m, err := NewManager()
+	require.NoError(t, err)
+	assert.Equal(t, dir, m.ConfigDir())
+	assert.Equal(t, filepath.Join(dir, "monitor.pid"), m.pidPath)
+}
+
+func TestProfileDir(t *testing.T) {
+	dir, err := ProfileDir("/cfg", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/cfg", dir)
+	dir, err = ProfileDir("/cfg", DefaultProfile)
+	require.NoError(t, err)
+	assert.Equal(t, "/cfg", dir)
+	dir, err = ProfileDir("/cfg", "work")
+	require.NoError(t, err)
+	assert.Equal(t, "/cfg/profiles/work", dir)
+
+	for _, bad := range []string{"..", ".", "a/b"} {
+		_, err := ProfileDir("/cfg", bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestProfiles_ListsDefaultFirst(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"work", "personal"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "profiles", name), 0755))
+	}
+
+	profiles, err := Profiles(root)
+	require.NoError(t, err)
+	assert.Equal(t, []Profile{
+		{Name: DefaultProfile, Dir: root},
+		{Name: "personal", Dir: filepath.Join(root, "profiles", "personal")},
+		{Name: "work", Dir: filepath.Join(root, "profiles", "work")},
+	}, profiles)
+}
@@ -0,0 +1,162 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DirRule tracks every matching file directly inside Dir (not recursively)
+// in one multi-file Gist. Files it matches get ordinary FileState entries
+// with FileState.Dir pointing back at the rule. Rules live in state.json's
+// envelope, or with FormatRubyCompat in dirs.json, since the Ruby
+// implementation expects state.json to be a bare path→FileState map.
+type DirRule struct {
+	Dir     string   `json:"dir"`
+	GistID  string   `json:"gist_id"`
+	Include []string `json:"include,omitempty"` // basename globs; empty matches everything
+	Exclude []string `json:"exclude,omitempty"`
+	AddedAt int64    `json:"added_at"`
+
+	// Host and Account are copied onto every file the rule adopts; see
+	// FileState.Host.
+	Host    string `json:"host,omitempty"`
+	Account string `json:"account,omitempty"`
+}
+
+// BuiltinExcludes are never tracked by a DirRule: editor swap and probe
+// files, and the backups, conflict copies and temp files this tool writes
+// next to tracked files.
+var BuiltinExcludes = []string{
+	"*~", "*.swp", "*.swx", "4913", ".DS_Store",
+	"*.tmp", "*.bak.*", "*.remote.*",
+}
+
+// ValidatePatterns reports the first malformed glob, so bad --include or
+// --exclude values fail at add time instead of silently matching nothing.
+func ValidatePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether absPath is a file this rule tracks. It only looks
+// at the name; callers check that the path is a regular file.
+func (r DirRule) Matches(absPath string) bool {
+	if filepath.Dir(absPath) != r.Dir {
+		return false
+	}
+	name := filepath.Base(absPath)
+	if matchAny(BuiltinExcludes, name) || matchAny(r.Exclude, name) {
+		return false
+	}
+	return len(r.Include) == 0 || matchAny(r.Include, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// DirsPath is the location of dirs.json, used with FormatRubyCompat; the
+// monitor watches it alongside state.json.
+func (m *Manager) DirsPath() string {
+	return m.dirsPath
+}
+
+// RuleFor returns the directory rule that would track absPath, if any.
+func (m *Manager) RuleFor(absPath string) (DirRule, bool) {
+	return Snapshot{Dirs: m.Dirs}.RuleFor(absPath)
+}
+
+// AddDirRule upserts r, keyed by its directory.
+func (m *Manager) AddDirRule(r DirRule) {
+	m.Dirs[r.Dir] = r
+}
+
+// ExcludeFromRule adds absPath's name to the Exclude list of the rule that
+// matches it, so the rule stops adopting the file, and returns the rule's
+// directory; "" if no rule matches. Like RemoveDirRule it only changes
+// m.Dirs, so call it from an Update callback to have it saved.
+func (m *Manager) ExcludeFromRule(absPath string) string {
+	r, ok := m.RuleFor(absPath)
+	if !ok {
+		return ""
+	}
+	r.Exclude = append(slices.Clone(r.Exclude), escapeGlob(filepath.Base(absPath)))
+	m.Dirs[r.Dir] = r
+	return r.Dir
+}
+
+// escapeGlob quotes the filepath.Match metacharacters in name, so the
+// pattern matches only that name.
+func escapeGlob(name string) string {
+	var b strings.Builder
+	for _, c := range name {
+		if strings.ContainsRune(`*?[\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// RemoveDirRule drops the rule for dir along with every file it tracks, and
+// returns the paths that were untracked.
+func (m *Manager) RemoveDirRule(dir string) []string {
+	delete(m.Dirs, dir)
+	return UntrackDir(m.Files, dir)
+}
+
+// UntrackDir deletes every file the rule for dir tracks from files, and
+// returns their paths. It is RemoveDirRule's file half, for Update callbacks.
+func UntrackDir(files map[string]FileState, dir string) []string {
+	var removed []string
+	for path, fs := range files {
+		if fs.Dir == dir {
+			delete(files, path)
+			removed = append(removed, path)
+		}
+	}
+	return removed
+}
+
+// saveDirs writes dirs.json for FormatRubyCompat like Save writes state.json; with no rules the
+// file is removed so setups without directory tracking are unchanged.
+func (m *Manager) saveDirs() error {
+	if len(m.Dirs) == 0 {
+		if err := os.Remove(m.dirsPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove dirs file: %w", err)
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(m.Dirs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dirs json: %w", err)
+	}
+	// Skip unchanged writes: the monitor reloads on every dirs.json event,
+	// and most Saves only touch state.json.
+	if current, err := os.ReadFile(m.dirsPath); err == nil && bytes.Equal(current, data) {
+		return nil
+	}
+	tmpPath := m.dirsPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write dirs file: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.dirsPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename dirs file into place: %w", err)
+	}
+	return nil
+}
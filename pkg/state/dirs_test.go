@@ -0,0 +1,80 @@
+package state
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirRule_Matches(t *testing.T) {
+	r := DirRule{Dir: "/p", Include: []string{"*.md", "*.txt"}, Exclude: []string{"draft-*"}}
+
+	assert.True(t, r.Matches("/p/a.md"))
+	assert.True(t, r.Matches("/p/b.txt"))
+	assert.False(t, r.Matches("/p/c.go"), "not included")
+	assert.False(t, r.Matches("/p/draft-x.md"), "excluded")
+	assert.False(t, r.Matches("/p/sub/a.md"), "rules are not recursive")
+	assert.False(t, r.Matches("/q/a.md"))
+}
+
+func TestDirRule_BuiltinExcludes(t *testing.T) {
+	r := DirRule{Dir: "/p"}
+	assert.True(t, r.Matches("/p/.zshrc"), "no include list matches everything")
+	for _, name := range []string{".a.md.swp", "a.md~", "a.md.bak.20260101-120000",
+		"a.md.remote.20260101-120000", "a.md.pull.tmp", "4913"} {
+		assert.False(t, r.Matches("/p/"+name), name)
+	}
+}
+
+func TestValidatePatterns(t *testing.T) {
+	assert.NoError(t, ValidatePatterns([]string{"*.md", "a?c"}))
+	assert.Error(t, ValidatePatterns([]string{"[unclosed"}))
+}
+
+func TestManager_ExcludeFromRule(t *testing.T) {
+	m := &Manager{Dirs: map[string]DirRule{"/p": {Dir: "/p", Include: []string{"*.md"}}}}
+
+	assert.Equal(t, "/p", m.ExcludeFromRule("/p/a[1].md"))
+	assert.False(t, m.Dirs["/p"].Matches("/p/a[1].md"))
+	assert.True(t, m.Dirs["/p"].Matches("/p/a1.md"), "only that name is excluded")
+	assert.Equal(t, "", m.ExcludeFromRule("/p/a[1].md"), "already excluded")
+	assert.Equal(t, "", m.ExcludeFromRule("/q/b.md"))
+}
+
+func TestManager_DirsRoundTripInSidecar(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	m, err := NewManager()
+	require.NoError(t, err)
+	m.Format = FormatRubyCompat
+
+	require.NoError(t, m.Save())
+	_, err = os.Stat(m.DirsPath())
+	assert.True(t, os.IsNotExist(err), "no rules, no dirs.json")
+
+	m.AddDirRule(DirRule{Dir: "/p", GistID: "g1", Include: []string{"*.md"}, AddedAt: 5})
+	m.Files["/p/a.md"] = FileState{GistID: "g1", Status: StatusActive, Dir: "/p"}
+	m.Files["/other.txt"] = FileState{GistID: "g2", Status: StatusActive}
+	require.NoError(t, m.Save())
+
+	back, err := NewManager()
+	require.NoError(t, err)
+	back.Format = FormatRubyCompat
+	require.NoError(t, back.Load())
+	assert.Equal(t, m.Dirs, back.Dirs)
+
+	rule, ok := back.RuleFor("/p/b.md")
+	assert.True(t, ok)
+	assert.Equal(t, "g1", rule.GistID)
+	_, ok = back.RuleFor("/p/b.txt")
+	assert.False(t, ok)
+
+	removed := back.RemoveDirRule("/p")
+	assert.Equal(t, []string{"/p/a.md"}, removed)
+	assert.Contains(t, back.Files, "/other.txt")
+	require.NoError(t, back.Save())
+	_, err = os.Stat(back.DirsPath())
+	assert.True(t, os.IsNotExist(err), "removing the last rule removes dirs.json")
+}
@@ -0,0 +1,88 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// lock takes the advisory lock on state.lock that serializes every write of
+// state.json and dirs.json, across processes and across goroutines sharing
+// a Manager (flock locks belong to the open file, not the process). The
+// lockfile itself is never removed: unlinking it would let a later caller
+// lock a fresh inode while an earlier one still holds the old.
+func (m *Manager) lock() (unlock func(), err error) {
+	if err := os.MkdirAll(m.configDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	f, err := os.OpenFile(m.lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock: %w", err)
+	}
+	for {
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock state: %w", err)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// Update is the read-modify-write for tracked state: under the state lock
+// it reloads state.json and dirs.json, hands the fresh Files map to fn, and
+// saves what fn left behind. fn may also change m.Dirs. If fn returns an
+// error nothing is written and the error is returned as is. fn runs with mu
+// held, so it must not call Snapshot, File or Update itself.
+//
+// Mutate entries inside fn starting from the map's current values rather
+// than copies taken before Update: anything another process wrote in the
+// meantime is only in the map.
+func (m *Manager) Update(fn func(files map[string]FileState) error) error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.load(); err != nil {
+		return err
+	}
+	if m.needsUpgrade() {
+		if err := m.backupForUpgrade(); err != nil {
+			return err
+		}
+	}
+	if err := fn(m.Files); err != nil {
+		return err
+	}
+	return m.save()
+}
+
+// UpdateFile is Update for a single tracked entry: fn edits a copy of
+// absPath's current state, which is stored back. Reports false without
+// writing when absPath is no longer tracked, so a late write can never
+// resurrect an entry another process removed.
+func (m *Manager) UpdateFile(absPath string, fn func(fs *FileState)) (bool, error) {
+	err := m.Update(func(files map[string]FileState) error {
+		fs, ok := files[absPath]
+		if !ok {
+			return errNotTracked
+		}
+		fn(&fs)
+		files[absPath] = fs
+		return nil
+	})
+	if err == errNotTracked {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,161 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// addEntries has one writer add n entries named prefix0..prefixN-1, one
+// Update each, the way separate commands would.
+func addEntries(t testing.TB, prefix string, n int) {
+	m, err := NewManager()
+	require.NoError(t, err)
+	for i := 0; i < n; i++ {
+		path := fmt.Sprintf("/%s%d", prefix, i)
+		require.NoError(t, m.Update(func(files map[string]FileState) error {
+			files[path] = FileState{GistID: prefix, Status: StatusActive}
+			return nil
+		}))
+	}
+}
+
+func TestManager_Update_ConcurrentWritersLoseNothing(t *testing.T) {
+	_ = setupTestEnv(t)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			addEntries(t, fmt.Sprintf("w%d-", w), 25)
+		}(w)
+	}
+	wg.Wait()
+
+	m, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, m.Load())
+	assert.Len(t, m.Files, 100)
+}
+
+// TestLockHelperProcess is not a real test: TestManager_Update_AcrossProcesses
+// runs the test binary again with this as the only test, to write from a
+// second process.
+func TestLockHelperProcess(t *testing.T) {
+	if os.Getenv("GH_AUTOMAGIST_LOCK_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+	addEntries(t, "child-", 50)
+}
+
+func TestManager_Update_AcrossProcesses(t *testing.T) {
+	home := setupTestEnv(t)
+
+	child := exec.Command(os.Args[0], "-test.run=^TestLockHelperProcess$")
+	child.Env = append(os.Environ(), "HOME="+home, "GH_AUTOMAGIST_LOCK_HELPER=1")
+	require.NoError(t, child.Start())
+	addEntries(t, "parent-", 50)
+	require.NoError(t, child.Wait())
+
+	m, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, m.Load())
+	assert.Len(t, m.Files, 100)
+}
+
+func TestManager_Update_ErrorWritesNothing(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	m.AddTrackedFile("/a", "g", 1)
+	require.NoError(t, m.Save())
+
+	boom := errors.New("boom")
+	err = m.Update(func(files map[string]FileState) error {
+		delete(files, "/a")
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	onDisk, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, onDisk.Load())
+	assert.Contains(t, onDisk.Files, "/a")
+}
+
+func TestManager_UpdateFile_DoesNotResurrect(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	m.AddTrackedFile("/a", "g", 1)
+	require.NoError(t, m.Save())
+
+	ok, err := m.UpdateFile("/a", func(fs *FileState) { fs.ContentSHA = "sha" })
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Another process removes the file...
+	other, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, other.Update(func(files map[string]FileState) error {
+		delete(files, "/a")
+		return nil
+	}))
+
+	// ...so a late write from the first one is dropped.
+	ok, err = m.UpdateFile("/a", func(fs *FileState) { fs.ContentSHA = "late" })
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.NotContains(t, m.Files, "/a")
+}
+
+func TestManager_SharedAcrossGoroutines(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	m.AddTrackedFile("/a", "g", 1)
+	require.NoError(t, m.Save())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				_, err := m.UpdateFile("/a", func(fs *FileState) { fs.UpdatedAt++ })
+				assert.NoError(t, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				assert.NoError(t, m.Load())
+				_, ok := m.Snapshot().Files["/a"]
+				assert.True(t, ok)
+			}
+		}()
+	}
+	wg.Wait()
+	fs, ok := m.File("/a")
+	require.True(t, ok)
+	assert.Equal(t, int64(81), fs.UpdatedAt)
+
+	// ReadSnapshot sees another process's write without replacing m's view.
+	other, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, other.Update(func(files map[string]FileState) error {
+		files["/b"] = FileState{GistID: "g2"}
+		return nil
+	}))
+	snap, err := m.ReadSnapshot()
+	require.NoError(t, err)
+	assert.Contains(t, snap.Files, "/b")
+	assert.NotContains(t, m.Files, "/b")
+}
@@ -0,0 +1,192 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// CurrentVersion is the state.json schema this build writes. Version 0 is
+// the Ruby implementation's bare path→FileState map, with directory rules in
+// the dirs.json sidecar; version 1 wraps both in an envelope.
+const CurrentVersion = 1
+
+// envelope is the versioned state.json.
+type envelope struct {
+	Version int                  `json:"version"`
+	Files   map[string]FileState `json:"files"`
+	Dirs    map[string]DirRule   `json:"dirs,omitempty"`
+}
+
+// Format selects the shape Save writes. Load reads either.
+type Format string
+
+const (
+	// FormatVersioned writes the versioned envelope, upgrading older files
+	// on Load.
+	FormatVersioned Format = "versioned"
+	// FormatRubyCompat keeps writing the bare map plus dirs.json so the Ruby
+	// implementation can share the config directory. Nothing is migrated.
+	FormatRubyCompat Format = "ruby-compat"
+)
+
+// DefaultFormat is the Format NewManager gives new Managers.
+var DefaultFormat = FormatVersioned
+
+// ParseFormat validates a --state-format value; empty means FormatVersioned.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case "":
+		return FormatVersioned, nil
+	case FormatVersioned, FormatRubyCompat:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid state format %q (want %s or %s)", s, FormatVersioned, FormatRubyCompat)
+	}
+}
+
+// migrations[v] turns a version-v state.json into version v+1. They work on
+// raw JSON so each step keeps handling the shape it was written for however
+// FileState changes later.
+var migrations = []func(m *Manager, data []byte) ([]byte, error){
+	0: migrateBareMap,
+}
+
+// migrateBareMap wraps the Ruby-compatible map in the envelope and folds
+// dirs.json into it.
+func migrateBareMap(m *Manager, data []byte) ([]byte, error) {
+	var doc struct {
+		Version int                        `json:"version"`
+		Files   json.RawMessage            `json:"files"`
+		Dirs    map[string]json.RawMessage `json:"dirs,omitempty"`
+	}
+	doc.Version = 1
+	doc.Files = data
+	dirs, err := os.ReadFile(m.dirsPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read dirs file: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(dirs, &doc.Dirs); err != nil {
+			return nil, fmt.Errorf("failed to parse dirs json: %w", err)
+		}
+	}
+	return json.Marshal(doc)
+}
+
+// schemaVersion reports which version data is. The bare map's keys are
+// absolute paths, so a top-level "version" key only appears in an envelope.
+func schemaVersion(data []byte) (int, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, err
+	}
+	raw, ok := doc["version"]
+	if !ok {
+		return 0, nil
+	}
+	var v int
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return 0, fmt.Errorf("bad version: %w", err)
+	}
+	return v, nil
+}
+
+// load reads state.json into Files and Dirs and records the version found
+// on disk for needsUpgrade. Callers hold mu.
+func (m *Manager) load() error {
+	files, dirs, version, err := m.read()
+	if err != nil {
+		return err
+	}
+	m.Files, m.Dirs, m.diskVersion = files, dirs, version
+	return nil
+}
+
+// read parses state.json, migrating it in memory, without touching m's
+// maps.
+func (m *Manager) read() (files map[string]FileState, dirs map[string]DirRule, version int, err error) {
+	data, err := os.ReadFile(m.statePath)
+	switch {
+	case os.IsNotExist(err):
+		if _, statErr := os.Stat(m.dirsPath); os.IsNotExist(statErr) {
+			return make(map[string]FileState), make(map[string]DirRule), CurrentVersion, nil
+		}
+		// Rules but no files yet: still the version 0 layout.
+		data = []byte("{}")
+	case err != nil:
+		return nil, nil, 0, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	version, err = schemaVersion(data)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to parse state json: %w", err)
+	}
+	if version > CurrentVersion {
+		return nil, nil, 0, fmt.Errorf("state.json is schema version %d, newer than this gh-automagist understands (%d); upgrade gh-automagist", version, CurrentVersion)
+	}
+	for v := version; v < CurrentVersion; v++ {
+		if data, err = migrations[v](m, data); err != nil {
+			return nil, nil, 0, fmt.Errorf("failed to migrate state json from version %d: %w", v, err)
+		}
+	}
+
+	// Decode into fresh maps: unmarshalling into the existing ones would keep
+	// entries that another process has since removed from disk.
+	var env envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to parse state json: %w", err)
+	}
+	if env.Files == nil {
+		env.Files = make(map[string]FileState)
+	}
+	if env.Dirs == nil {
+		env.Dirs = make(map[string]DirRule)
+	}
+	return env.Files, env.Dirs, version, nil
+}
+
+// needsUpgrade reports whether the last load migrated an older file that
+// this Manager's Format wants rewritten.
+func (m *Manager) needsUpgrade() bool {
+	return m.Format != FormatRubyCompat && m.diskVersion < CurrentVersion
+}
+
+// backupForUpgrade copies state.json and dirs.json to <name>.v<N>.bak
+// before a migrated state is first written, so the old files can be put
+// back for an older build. Callers hold the state lock.
+func (m *Manager) backupForUpgrade() error {
+	for _, path := range []string{m.statePath, m.dirsPath} {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		if err := os.WriteFile(backupPath(path, m.diskVersion), data, 0644); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// backupPath is where the pre-migration copy of path (state.json or
+// dirs.json) at schema version is kept.
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+// encode renders Files (and, for FormatVersioned, Dirs) as state.json.
+// Indented to match Ruby's JSON.pretty_generate output.
+func (m *Manager) encode() ([]byte, error) {
+	var v any = m.Files
+	if m.Format != FormatRubyCompat {
+		v = envelope{Version: CurrentVersion, Files: m.Files, Dirs: m.Dirs}
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode state json: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,160 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLegacy lays out a version 0 config directory: the Ruby-compatible
+// bare map plus a dirs.json sidecar.
+func writeLegacy(t *testing.T, m *Manager) (stateJSON, dirsJSON string) {
+	t.Helper()
+	stateJSON = `{
+  "/p/a.md": {"gist_id": "g1", "updated_at": 100, "status": "active", "dir": "/p"},
+  "/other.txt": {"gist_id": "g2", "updated_at": 200, "status": "active"}
+}`
+	dirsJSON = `{"/p": {"dir": "/p", "gist_id": "g1", "include": ["*.md"], "added_at": 5}}`
+	require.NoError(t, os.MkdirAll(m.configDir, 0755))
+	require.NoError(t, os.WriteFile(m.statePath, []byte(stateJSON), 0644))
+	require.NoError(t, os.WriteFile(m.dirsPath, []byte(dirsJSON), 0644))
+	return stateJSON, dirsJSON
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatVersioned, f)
+	f, err = ParseFormat("ruby-compat")
+	require.NoError(t, err)
+	assert.Equal(t, FormatRubyCompat, f)
+	_, err = ParseFormat("yaml")
+	assert.Error(t, err)
+}
+
+func TestManager_Load_MigratesLegacyAndKeepsBackup(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	stateJSON, dirsJSON := writeLegacy(t, m)
+
+	require.NoError(t, m.Load())
+	assert.Equal(t, "g2", m.Files["/other.txt"].GistID)
+	assert.Equal(t, []string{"*.md"}, m.Dirs["/p"].Include)
+
+	var env struct {
+		Version int                        `json:"version"`
+		Files   map[string]json.RawMessage `json:"files"`
+		Dirs    map[string]json.RawMessage `json:"dirs"`
+	}
+	data, err := os.ReadFile(m.statePath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &env))
+	assert.Equal(t, CurrentVersion, env.Version)
+	assert.Len(t, env.Files, 2)
+	assert.Contains(t, env.Dirs, "/p", "dirs.json is folded into the envelope")
+	_, err = os.Stat(m.dirsPath)
+	assert.True(t, os.IsNotExist(err))
+
+	backup, err := os.ReadFile(m.statePath + ".v0.bak")
+	require.NoError(t, err)
+	assert.Equal(t, stateJSON, string(backup))
+	backup, err = os.ReadFile(m.dirsPath + ".v0.bak")
+	require.NoError(t, err)
+	assert.Equal(t, dirsJSON, string(backup))
+
+	// A second Load finds nothing left to migrate.
+	require.NoError(t, os.Remove(m.statePath+".v0.bak"))
+	require.NoError(t, m.Load())
+	_, err = os.Stat(m.statePath + ".v0.bak")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestManager_Update_MigratesLegacy(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	writeLegacy(t, m)
+
+	require.NoError(t, m.Update(func(files map[string]FileState) error {
+		delete(files, "/other.txt")
+		return nil
+	}))
+
+	back, err := NewManager()
+	require.NoError(t, err)
+	back.Format = FormatRubyCompat // read without upgrading again
+	require.NoError(t, back.Load())
+	assert.Equal(t, CurrentVersion, back.diskVersion)
+	assert.NotContains(t, back.Files, "/other.txt")
+	assert.Contains(t, back.Dirs, "/p")
+	_, err = os.Stat(m.statePath + ".v0.bak")
+	assert.NoError(t, err)
+}
+
+func TestManager_RubyCompat_NeitherMigratesNorWritesEnvelope(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	m.Format = FormatRubyCompat
+	stateJSON, _ := writeLegacy(t, m)
+
+	require.NoError(t, m.Load())
+	assert.Len(t, m.Files, 2)
+	data, err := os.ReadFile(m.statePath)
+	require.NoError(t, err)
+	assert.Equal(t, stateJSON, string(data), "Load leaves a legacy file alone")
+	_, err = os.Stat(m.statePath + ".v0.bak")
+	assert.True(t, os.IsNotExist(err))
+
+	m.AddTrackedFile("/new.txt", "g3", 300)
+	require.NoError(t, m.Save())
+	var files map[string]FileState
+	data, err = os.ReadFile(m.statePath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &files))
+	assert.Len(t, files, 3, "still a bare path→FileState map")
+	_, err = os.Stat(m.dirsPath)
+	assert.NoError(t, err, "rules stay in dirs.json")
+}
+
+func TestManager_RubyCompat_DowngradesEnvelope(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	m.AddDirRule(DirRule{Dir: "/p", GistID: "g1"})
+	m.AddTrackedFile("/p/a.md", "g1", 100)
+	require.NoError(t, m.Save())
+
+	compat, err := NewManager()
+	require.NoError(t, err)
+	compat.Format = FormatRubyCompat
+	require.NoError(t, compat.Load())
+	require.NoError(t, compat.Save())
+
+	var files map[string]FileState
+	data, err := os.ReadFile(m.statePath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &files))
+	assert.Contains(t, files, "/p/a.md")
+	var dirs map[string]DirRule
+	data, err = os.ReadFile(m.dirsPath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &dirs))
+	assert.Contains(t, dirs, "/p")
+}
+
+func TestManager_Load_RejectsNewerSchema(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(m.configDir, 0755))
+	require.NoError(t, os.WriteFile(m.statePath, []byte(`{"version": 99, "files": {}}`), 0644))
+
+	err = m.Load()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upgrade gh-automagist")
+}
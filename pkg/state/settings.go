@@ -0,0 +1,48 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// FileState.Direction values. Empty means DirectionBoth.
+const (
+	DirectionBoth = "both"
+	DirectionPush = "push" // local edits go up; pull and the monitor's poll leave it alone
+	DirectionPull = "pull" // Gist edits come down; local edits are not uploaded
+)
+
+// ParseDirection validates a --direction value; empty means DirectionBoth.
+func ParseDirection(s string) (string, error) {
+	switch s {
+	case "", DirectionBoth:
+		return DirectionBoth, nil
+	case DirectionPush, DirectionPull:
+		return s, nil
+	default:
+		return "", fmt.Errorf("invalid direction %q (want %s, %s or %s)", s, DirectionPush, DirectionPull, DirectionBoth)
+	}
+}
+
+// Pushes reports whether the monitor uploads local edits of the file.
+func (fs FileState) Pushes() bool {
+	return !fs.Paused && fs.Direction != DirectionPull
+}
+
+// Pulls reports whether pull and the monitor's poll may apply Gist edits.
+func (fs FileState) Pulls() bool {
+	return !fs.Paused && fs.Direction != DirectionPush
+}
+
+// DebounceInterval returns the file's own debounce window, if it has a valid
+// one. A zero interval is an override too: sync on every write.
+func (fs FileState) DebounceInterval() (time.Duration, bool) {
+	if fs.Debounce == "" {
+		return 0, false
+	}
+	d, err := time.ParseDuration(fs.Debounce)
+	if err != nil {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,44 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDirection(t *testing.T) {
+	d, err := ParseDirection("")
+	require.NoError(t, err)
+	assert.Equal(t, DirectionBoth, d)
+	d, err = ParseDirection("pull")
+	require.NoError(t, err)
+	assert.Equal(t, DirectionPull, d)
+	_, err = ParseDirection("sideways")
+	assert.Error(t, err)
+}
+
+func TestFileState_PushesAndPulls(t *testing.T) {
+	assert.True(t, FileState{}.Pushes())
+	assert.True(t, FileState{}.Pulls())
+	assert.False(t, FileState{Direction: DirectionPull}.Pushes())
+	assert.True(t, FileState{Direction: DirectionPull}.Pulls())
+	assert.True(t, FileState{Direction: DirectionPush}.Pushes())
+	assert.False(t, FileState{Direction: DirectionPush}.Pulls())
+	assert.False(t, FileState{Paused: true}.Pushes())
+	assert.False(t, FileState{Paused: true}.Pulls())
+}
+
+func TestFileState_DebounceInterval(t *testing.T) {
+	_, ok := FileState{}.DebounceInterval()
+	assert.False(t, ok)
+	d, ok := FileState{Debounce: "2m"}.DebounceInterval()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+	d, ok = FileState{Debounce: "0s"}.DebounceInterval()
+	assert.True(t, ok, "zero is an override, not unset")
+	assert.Zero(t, d)
+	_, ok = FileState{Debounce: "soon"}.DebounceInterval()
+	assert.False(t, ok)
+}
@@ -0,0 +1,64 @@
+package state
+
+import "path/filepath"
+
+// Snapshot is a copy of the tracked state, safe to read while other
+// goroutines Load or Update the Manager it came from.
+type Snapshot struct {
+	Files map[string]FileState
+	Dirs  map[string]DirRule
+}
+
+// Snapshot copies the state this Manager last loaded or wrote.
+func (m *Manager) Snapshot() Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := Snapshot{
+		Files: make(map[string]FileState, len(m.Files)),
+		Dirs:  make(map[string]DirRule, len(m.Dirs)),
+	}
+	for path, fs := range m.Files {
+		s.Files[path] = fs
+	}
+	for dir, r := range m.Dirs {
+		s.Dirs[dir] = r
+	}
+	return s
+}
+
+// ReadSnapshot reads the state on disk now, e.g. a marker another process
+// just wrote, without replacing what this Manager holds.
+func (m *Manager) ReadSnapshot() (Snapshot, error) {
+	files, dirs, _, err := m.read()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	return Snapshot{Files: files, Dirs: dirs}, nil
+}
+
+// File returns absPath's entry as this Manager last loaded or wrote it.
+func (m *Manager) File(absPath string) (FileState, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fs, ok := m.Files[absPath]
+	return fs, ok
+}
+
+// RuleFor returns the directory rule that would track absPath, if any.
+func (s Snapshot) RuleFor(absPath string) (DirRule, bool) {
+	r, ok := s.Dirs[filepath.Dir(absPath)]
+	if !ok || !r.Matches(absPath) {
+		return DirRule{}, false
+	}
+	return r, true
+}
+
+// FindRemote is Manager.FindRemote on the snapshot.
+func (s Snapshot) FindRemote(gistID, filename string) (string, bool) {
+	for path, fs := range s.Files {
+		if fs.GistID == gistID && fs.RemoteName(path) == filename {
+			return path, true
+		}
+	}
+	return "", false
+}
@@ -6,21 +6,79 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"syscall"
+	"time"
 )
 
 // FileState is one entry in state.json; field names mirror the Ruby implementation for cross-tool interop.
 type FileState struct {
 	GistID    string `json:"gist_id"`
 	UpdatedAt int64  `json:"updated_at"`
-	Status    string `json:"status"` // e.g., "active"
+	Status    string `json:"status"` // StatusActive or StatusMissing
 
 	RemoteUpdatedAt int64  `json:"remote_updated_at,omitempty"`
 	ContentSHA      string `json:"content_sha,omitempty"`
 
 	// PullSuppressUntil is a unix-second deadline; paired with ContentSHA it
 	// gates the daemon's post-pull PATCH via pkg/monitor.ShouldSuppress.
-	PullSuppressUntil int64 `json:"pull_suppress_until,omitempty"`
+	// PullSuppressSHA, when set, is matched instead: a restore that was not
+	// pushed writes content the Gist does not have, so ContentSHA stays at
+	// the last synced remote.
+	PullSuppressUntil int64  `json:"pull_suppress_until,omitempty"`
+	PullSuppressSHA   string `json:"pull_suppress_sha,omitempty"`
+
+	// ConflictAt is a unix-second timestamp set when the monitor found the
+	// Gist edited remotely since the last sync; ConflictCopy is where the
+	// remote version was saved, if it was. Both clear on the next clean sync.
+	ConflictAt   int64  `json:"conflict_at,omitempty"`
+	ConflictCopy string `json:"conflict_copy,omitempty"`
+
+	// RemoteFilename is the file's name inside the Gist. Empty means the
+	// local basename, which is all the Ruby implementation knows about.
+	RemoteFilename string `json:"remote_filename,omitempty"`
+
+	// Dir is set on files tracked through a DirRule, naming the rule's
+	// directory; empty for files added one by one.
+	Dir string `json:"dir,omitempty"`
+
+	// Host is the GitHub host the Gist lives on, e.g. a GitHub Enterprise
+	// Server hostname; empty means gh's default host (github.com for the
+	// Ruby implementation). Account picks one of several users logged in to
+	// that host; empty means the host's active account.
+	Host    string `json:"host,omitempty"`
+	Account string `json:"account,omitempty"`
+
+	// Per-file sync settings; zero values follow the monitor's. Debounce is
+	// a time.Duration string replacing the monitor's --debounce for this
+	// file. Direction is one of the Direction constants. Paused stops both
+	// directions until `resume`.
+	Debounce  string `json:"debounce,omitempty"`
+	Direction string `json:"direction,omitempty"`
+	Paused    bool   `json:"paused,omitempty"`
+}
+
+// RemoteName returns the Gist filename absPath syncs to.
+func (fs FileState) RemoteName(absPath string) string {
+	if fs.RemoteFilename != "" {
+		return fs.RemoteFilename
+	}
+	return filepath.Base(absPath)
+}
+
+// FileState.Status values. The Ruby implementation only ever writes
+// StatusActive; StatusMissing marks a tracked file the monitor saw deleted
+// locally, kept so its Gist link survives until it is restored or removed.
+const (
+	StatusActive  = "active"
+	StatusMissing = "missing"
+)
+
+// ClearConflict drops the conflict marker after a sync that reconciled local
+// and remote.
+func (fs *FileState) ClearConflict() {
+	fs.ConflictAt = 0
+	fs.ConflictCopy = ""
 }
 
 // MonitorInfo is the daemon's self-report, written when the monitor comes up
@@ -31,68 +89,168 @@ type MonitorInfo struct {
 	Version   string `json:"version"`
 	Commit    string `json:"commit,omitempty"`
 	StartedAt int64  `json:"started_at"`
+	// Debounce is the monitor's effective --debounce, as a time.Duration
+	// string, so commands that write tracked files can outlast it.
+	Debounce string `json:"debounce,omitempty"`
 }
 
 type Manager struct {
-	configDir       string
-	statePath       string
-	pidPath         string
-	monitorInfoPath string
-	Files           map[string]FileState
+	configDir        string
+	statePath        string
+	pidPath          string
+	monitorInfoPath  string
+	logPath          string
+	outboxPath       string
+	objectsDir       string
+	backupsDir       string
+	dirsPath         string
+	etagCachePath    string
+	manifestGistPath string
+	lockPath         string
+
+	// Files and Dirs may be read directly by the goroutine that owns the
+	// Manager. Goroutines sharing one, as in the monitor, read through
+	// Snapshot or File and write through Update; mu guards the maps (and
+	// diskVersion) for them and is always taken after the state lock.
+	mu    sync.Mutex
+	Files map[string]FileState
+	Dirs  map[string]DirRule // keyed by DirRule.Dir
+
+	// Format is the shape Save writes; see FormatRubyCompat.
+	Format Format
+	// diskVersion is the schema version the last load found on disk.
+	diskVersion int
 }
 
+// NewManager returns a Manager for DefaultConfigDir, or ConfigRoot when it
+// is unset.
 func NewManager() (*Manager, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("could not determine home directory: %w", err)
+	configDir := DefaultConfigDir
+	if configDir == "" {
+		root, err := ConfigRoot()
+		if err != nil {
+			return nil, err
+		}
+		configDir = root
 	}
+	return NewManagerAt(configDir), nil
+}
 
-	configDir := filepath.Join(homeDir, ".config", "gh-automagist")
+// NewManagerAt returns a Manager for the given config directory, e.g. another
+// profile's.
+func NewManagerAt(configDir string) *Manager {
 	statePath := filepath.Join(configDir, "state.json")
 	pidPath := filepath.Join(configDir, "monitor.pid")
 	monitorInfoPath := filepath.Join(configDir, "monitor.json")
+	logPath := filepath.Join(configDir, "monitor.log")
+	outboxPath := filepath.Join(configDir, "outbox.json")
+	objectsDir := filepath.Join(configDir, "objects")
+	backupsDir := filepath.Join(configDir, "backups")
+	dirsPath := filepath.Join(configDir, "dirs.json")
+	etagCachePath := filepath.Join(configDir, "etags.json")
+	manifestGistPath := filepath.Join(configDir, "manifest-gist.json")
+	lockPath := filepath.Join(configDir, "state.lock")
 
 	return &Manager{
-		configDir:       configDir,
-		statePath:       statePath,
-		pidPath:         pidPath,
-		monitorInfoPath: monitorInfoPath,
-		Files:           make(map[string]FileState),
-	}, nil
+		configDir:        configDir,
+		statePath:        statePath,
+		pidPath:          pidPath,
+		monitorInfoPath:  monitorInfoPath,
+		logPath:          logPath,
+		outboxPath:       outboxPath,
+		objectsDir:       objectsDir,
+		backupsDir:       backupsDir,
+		dirsPath:         dirsPath,
+		etagCachePath:    etagCachePath,
+		manifestGistPath: manifestGistPath,
+		lockPath:         lockPath,
+		Files:            make(map[string]FileState),
+		Dirs:             make(map[string]DirRule),
+		Format:           DefaultFormat,
+		diskVersion:      CurrentVersion,
+	}
+}
+
+// ConfigDir is the directory holding this Manager's state, PID file and log.
+func (m *Manager) ConfigDir() string {
+	return m.configDir
 }
 
-// Load parses state.json; a missing file yields an empty state without error.
+// Load parses state.json (and, before the versioned schema, dirs.json);
+// missing files yield an empty state without error. An older schema is
+// migrated and, unless Format is FormatRubyCompat, written back under the
+// state lock after the original is copied to state.json.v<N>.bak.
 func (m *Manager) Load() error {
-	data, err := os.ReadFile(m.statePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			m.Files = make(map[string]FileState)
-			return nil
-		}
-		return fmt.Errorf("failed to read state file: %w", err)
+	m.mu.Lock()
+	err := m.load()
+	upgrade := err == nil && m.needsUpgrade()
+	m.mu.Unlock()
+	if !upgrade {
+		return err
 	}
-
-	err = json.Unmarshal(data, &m.Files)
+	unlock, err := m.lock()
 	if err != nil {
-		return fmt.Errorf("failed to parse state json: %w", err)
+		return err
+	}
+	defer unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	// Another process may have upgraded it while we waited.
+	if err := m.load(); err != nil || !m.needsUpgrade() {
+		return err
 	}
+	if err := m.backupForUpgrade(); err != nil {
+		return err
+	}
+	return m.save()
+}
 
-	return nil
+// StatePath is the location of state.json. The monitor watches it to pick up
+// files added or removed by other commands without a restart.
+func (m *Manager) StatePath() string {
+	return m.statePath
 }
 
-// Save persists Files to state.json atomically (tmp + rename), creating the
-// config directory if needed. A partial write during shutdown leaves the
+// errNotTracked aborts an UpdateFile whose entry has gone.
+var errNotTracked = errors.New("not tracked")
+
+// Save persists Files and Dirs to state.json (FormatRubyCompat: Dirs to
+// dirs.json) atomically (tmp + rename), creating the config directory if
+// needed. A partial write during shutdown leaves the
 // previous file intact rather than truncated.
+//
+// Save overwrites whatever another process wrote since this Manager's last
+// Load; read-modify-write sequences should use Update instead.
 func (m *Manager) Save() error {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.save()
+}
+
+// save is Save for callers already holding the state lock and mu.
+func (m *Manager) save() error {
 	err := os.MkdirAll(m.configDir, 0755)
 	if err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Indented to match Ruby's JSON.pretty_generate output.
-	data, err := json.MarshalIndent(m.Files, "", "  ")
+	// Rules first: a monitor reacting to the state.json write below must
+	// already see the rule its new entries point at. The versioned format
+	// carries them in state.json itself.
+	if m.Format == FormatRubyCompat {
+		if err := m.saveDirs(); err != nil {
+			return err
+		}
+	}
+
+	data, err := m.encode()
 	if err != nil {
-		return fmt.Errorf("failed to encode state json: %w", err)
+		return err
 	}
 
 	tmpPath := m.statePath + ".tmp"
@@ -104,22 +262,79 @@ func (m *Manager) Save() error {
 		return fmt.Errorf("failed to rename state file into place: %w", err)
 	}
 
+	if m.Format != FormatRubyCompat {
+		if err := os.Remove(m.dirsPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove dirs file: %w", err)
+		}
+	}
+	m.diskVersion = CurrentVersion
+	if m.Format == FormatRubyCompat {
+		m.diskVersion = 0
+	}
 	return nil
 }
 
-// AddTrackedFile upserts the file's tracked state.
-func (m *Manager) AddTrackedFile(absPath, gistID string, updatedAt int64) {
-	m.Files[absPath] = FileState{
+// NewFileState is the state of a file that just started syncing to gistID.
+func NewFileState(gistID string, updatedAt int64) FileState {
+	return FileState{
 		GistID:    gistID,
 		UpdatedAt: updatedAt,
-		Status:    "active",
+		Status:    StatusActive,
 	}
 }
 
+// AddTrackedFile upserts the file's tracked state. Inside Update, set the
+// callback's map to NewFileState instead.
+func (m *Manager) AddTrackedFile(absPath, gistID string, updatedAt int64) {
+	m.Files[absPath] = NewFileState(gistID, updatedAt)
+}
+
 func (m *Manager) RemoveTrackedFile(absPath string) {
 	delete(m.Files, absPath)
 }
 
+// FindRemote returns the tracked path that syncs to filename in gistID, if
+// any. Two tracked files mapping to the same Gist file would overwrite each
+// other on every sync.
+func (m *Manager) FindRemote(gistID, filename string) (string, bool) {
+	return Snapshot{Files: m.Files}.FindRemote(gistID, filename)
+}
+
+// LogPath is where the monitor appends its log (rotated as monitor.log.N).
+func (m *Manager) LogPath() string {
+	return m.logPath
+}
+
+// OutboxPath is where the monitor persists uploads that failed and are
+// waiting to be retried (see pkg/outbox).
+func (m *Manager) OutboxPath() string {
+	return m.outboxPath
+}
+
+// ObjectsDir holds the last-synced content of tracked files, keyed by
+// ContentSHA (see pkg/objstore); it is the base for `pull --merge`.
+func (m *Manager) ObjectsDir() string {
+	return m.objectsDir
+}
+
+// BackupsDir holds the copies taken before pull, merge and restore overwrite
+// a tracked file (see pkg/backup).
+func (m *Manager) BackupsDir() string {
+	return m.backupsDir
+}
+
+// ETagCachePath is where the Gist client remembers response ETags so polling
+// an unchanged Gist costs a 304 (see gist.ETagCache).
+func (m *Manager) ETagCachePath() string {
+	return m.etagCachePath
+}
+
+// ManifestGistPath is where `export --gist` and `import --gist` remember the
+// Gist the manifest is synced through (see manifest.Remote).
+func (m *Manager) ManifestGistPath() string {
+	return m.manifestGistPath
+}
+
 // WritePID writes the current process's PID to monitor.pid.
 func (m *Manager) WritePID() error {
 	pid := os.Getpid()
@@ -201,6 +416,67 @@ func (m *Manager) KillMonitor(pid int) (killed bool, err error) {
 	}
 }
 
+// StopResult reports how StopMonitor brought the daemon down.
+type StopResult int
+
+const (
+	StopNotRunning StopResult = iota // stale PID file; nothing to signal, cleaned up
+	StopGraceful                     // exited on SIGTERM within the timeout
+	StopKilled                       // ignored SIGTERM past the timeout and was SIGKILLed
+)
+
+// stopPollInterval is how often StopMonitor checks whether the PID has exited.
+const stopPollInterval = 100 * time.Millisecond
+
+// StopMonitor sends SIGTERM so the daemon can flush pending debounced syncs
+// and remove its own PID/info files, then waits up to timeout for the PID to
+// exit before escalating to KillMonitor. PID and info files are cleared on
+// every path except a failed signal (e.g. EPERM), mirroring KillMonitor.
+//
+// Callers should check GetPID() != 0 before calling.
+func (m *Manager) StopMonitor(pid int, timeout time.Duration) (StopResult, error) {
+	process, _ := os.FindProcess(pid) // Unix: always succeeds regardless of process existence
+	termErr := process.Signal(syscall.SIGTERM)
+	switch {
+	case termErr == nil:
+	case errors.Is(termErr, os.ErrProcessDone) || errors.Is(termErr, syscall.ESRCH):
+		m.DeletePID()
+		m.DeleteMonitorInfo()
+		return StopNotRunning, nil
+	default:
+		return StopNotRunning, fmt.Errorf("failed to signal monitor (PID %d): %w", pid, termErr)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !processAlive(pid) {
+			// The daemon deletes these itself on a clean shutdown; this only
+			// matters if it exited through some other path.
+			m.DeletePID()
+			m.DeleteMonitorInfo()
+			return StopGraceful, nil
+		}
+		time.Sleep(stopPollInterval)
+	}
+
+	killed, err := m.KillMonitor(pid)
+	if err != nil {
+		return StopNotRunning, err
+	}
+	if !killed {
+		// Exited between the last poll and the SIGKILL.
+		return StopGraceful, nil
+	}
+	return StopKilled, nil
+}
+
+// processAlive probes pid with signal 0. EPERM means the process exists but
+// belongs to someone else, which still counts as alive.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
 // GetPID reads the monitor.pid file; returns 0 if missing.
 func (m *Manager) GetPID() int {
 	data, err := os.ReadFile(m.pidPath)
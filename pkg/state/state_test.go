@@ -18,6 +18,7 @@ import (
 func setupTestEnv(t *testing.T) string {
 	tempHome := t.TempDir()
 	t.Setenv("HOME", tempHome)
+	t.Setenv("XDG_CONFIG_HOME", "")
 	return tempHome
 }
 
@@ -31,6 +32,10 @@ func TestManager_NewManager(t *testing.T) {
 	assert.Equal(t, filepath.Join(expectedConfigDir, "state.json"), m.statePath)
 	assert.Equal(t, filepath.Join(expectedConfigDir, "monitor.pid"), m.pidPath)
 	assert.Equal(t, filepath.Join(expectedConfigDir, "monitor.json"), m.monitorInfoPath)
+	assert.Equal(t, filepath.Join(expectedConfigDir, "monitor.log"), m.logPath)
+	assert.Equal(t, filepath.Join(expectedConfigDir, "outbox.json"), m.outboxPath)
+	assert.Equal(t, filepath.Join(expectedConfigDir, "objects"), m.objectsDir)
+	assert.Equal(t, filepath.Join(expectedConfigDir, "backups"), m.backupsDir)
 	assert.NotNil(t, m.Files)
 }
 
@@ -164,6 +169,9 @@ func TestFileState_JSONRoundTrip_NewFields(t *testing.T) {
 		RemoteUpdatedAt:   200,
 		ContentSHA:        "a1b2c3d4",
 		PullSuppressUntil: 300,
+		ConflictAt:        250,
+		ConflictCopy:      "/x/a.txt.remote.20260710-100000",
+		RemoteFilename:    "work.yaml",
 	}
 	data, err := json.Marshal(orig)
 	require.NoError(t, err)
@@ -195,6 +203,52 @@ func TestFileState_JSONOmitEmpty_PullSuppress(t *testing.T) {
 	assert.NotContains(t, string(data), "pull_suppress_until")
 }
 
+func TestFileState_RemoteName_DefaultsToBasename(t *testing.T) {
+	// Entries written by the Ruby tool have no remote_filename.
+	var s FileState
+	require.NoError(t, json.Unmarshal([]byte(`{"gist_id":"abc","updated_at":1,"status":"active"}`), &s))
+	assert.Equal(t, "config.yaml", s.RemoteName("/home/u/work/config.yaml"))
+
+	s.RemoteFilename = "work-config.yaml"
+	assert.Equal(t, "work-config.yaml", s.RemoteName("/home/u/work/config.yaml"))
+
+	data, err := json.Marshal(FileState{GistID: "abc"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "remote_filename")
+}
+
+func TestFileState_HostOmittedForDefaultHost(t *testing.T) {
+	// Entries on gh's default host must still read as plain Ruby entries.
+	data, err := json.Marshal(FileState{GistID: "abc"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "host")
+	assert.NotContains(t, string(data), "account")
+
+	data, err = json.Marshal(FileState{GistID: "abc", Host: "ghe.example.com", Account: "me"})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"host":"ghe.example.com"`)
+	assert.Contains(t, string(data), `"account":"me"`)
+}
+
+func TestManager_FindRemote(t *testing.T) {
+	m := &Manager{Files: map[string]FileState{
+		"/a/config.yaml": {GistID: "g1"},
+		"/b/config.yaml": {GistID: "g1", RemoteFilename: "b-config.yaml"},
+		"/c/config.yaml": {GistID: "g2"},
+	}}
+
+	path, ok := m.FindRemote("g1", "config.yaml")
+	assert.True(t, ok)
+	assert.Equal(t, "/a/config.yaml", path)
+
+	path, ok = m.FindRemote("g1", "b-config.yaml")
+	assert.True(t, ok)
+	assert.Equal(t, "/b/config.yaml", path)
+
+	_, ok = m.FindRemote("g2", "b-config.yaml")
+	assert.False(t, ok)
+}
+
 func TestMonitorInfo_RoundTrip(t *testing.T) {
 	_ = setupTestEnv(t)
 	m, err := NewManager()
@@ -245,3 +299,84 @@ func TestKillMonitor_CleansMonitorInfoAlongsidePID(t *testing.T) {
 	require.NoError(t, err)
 	assert.Nil(t, back, "KillMonitor should clean up monitor.json even on stale PID")
 }
+
+func TestStopMonitor_GracefulOnSIGTERM(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(m.configDir, 0755))
+
+	cmd := exec.Command("sleep", "30")
+	require.NoError(t, cmd.Start())
+	// Reap in the background so the exited child does not linger as a zombie
+	// (a zombie still answers signal 0 and would look alive).
+	go func() { _ = cmd.Wait() }()
+
+	pid := cmd.Process.Pid
+	require.NoError(t, os.WriteFile(m.pidPath, []byte(fmt.Sprintf("%d", pid)), 0644))
+	require.NoError(t, m.WriteMonitorInfo(MonitorInfo{PID: pid, Version: "v1.8.0"}))
+
+	result, err := m.StopMonitor(pid, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, StopGraceful, result)
+	assert.Equal(t, 0, m.GetPID(), "PID file should be cleaned up after graceful stop")
+	info, err := m.ReadMonitorInfo()
+	require.NoError(t, err)
+	assert.Nil(t, info)
+}
+
+func TestStopMonitor_EscalatesToSIGKILLAfterTimeout(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(m.configDir, 0755))
+
+	// An ignored disposition survives exec, so sleep itself ignores SIGTERM.
+	cmd := exec.Command("sh", "-c", `trap "" TERM; exec sleep 30`)
+	require.NoError(t, cmd.Start())
+	go func() { _ = cmd.Wait() }()
+	// Let sh install the trap before we signal.
+	time.Sleep(100 * time.Millisecond)
+
+	pid := cmd.Process.Pid
+	require.NoError(t, os.WriteFile(m.pidPath, []byte(fmt.Sprintf("%d", pid)), 0644))
+
+	result, err := m.StopMonitor(pid, 300*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, StopKilled, result)
+	assert.Equal(t, 0, m.GetPID())
+}
+
+func TestStopMonitor_StalePID(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(m.configDir, 0755))
+
+	stalePID := 99999999
+	require.NoError(t, os.WriteFile(m.pidPath, []byte(fmt.Sprintf("%d", stalePID)), 0644))
+
+	result, err := m.StopMonitor(stalePID, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, StopNotRunning, result)
+	assert.Equal(t, 0, m.GetPID())
+}
+
+func TestManager_Load_DropsEntriesRemovedOnDisk(t *testing.T) {
+	_ = setupTestEnv(t)
+	m, err := NewManager()
+	require.NoError(t, err)
+	m.AddTrackedFile("/a", "gist_a", 100)
+	m.AddTrackedFile("/b", "gist_b", 100)
+	require.NoError(t, m.Save())
+
+	other, err := NewManager()
+	require.NoError(t, err)
+	require.NoError(t, other.Load())
+	other.RemoveTrackedFile("/b")
+	require.NoError(t, other.Save())
+
+	require.NoError(t, m.Load())
+	assert.Contains(t, m.Files, "/a")
+	assert.NotContains(t, m.Files, "/b", "reload must not keep entries deleted by another process")
+}